@@ -0,0 +1,336 @@
+package alerting
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// notifyTimeout bounds a single notifier call.
+	notifyTimeout = 10 * time.Second
+
+	// alertQueueSize is the number of alerts waiting for delivery before new
+	// ones are dropped.
+	alertQueueSize = 100
+
+	// maxRecentAlerts is the number of alerts kept for the alerts API.
+	maxRecentAlerts = 100
+)
+
+// Kind identifies what triggered an alert.
+type Kind string
+
+const (
+	// KindNewIssue fires the first time a fingerprint is seen.
+	KindNewIssue Kind = "new_issue"
+	// KindSpike fires when a fingerprint's rate exceeds its rolling baseline.
+	KindSpike Kind = "spike"
+)
+
+// Alert describes a new or spiking violation.
+type Alert struct {
+	Kind        Kind                    `json:"kind"`
+	Fingerprint string                  `json:"fingerprint"`
+	Input       models.FingerprintInput `json:"input"`
+	Project     string                  `json:"project,omitempty"`
+	Count       int64                   `json:"count"`
+	Baseline    float64                 `json:"baseline,omitempty"`
+	Window      string                  `json:"window,omitempty"`
+	FiredAt     time.Time               `json:"fired_at"`
+}
+
+// Silence mutes alerts for a fingerprint until the given time.
+type Silence struct {
+	Fingerprint string    `json:"fingerprint"`
+	Until       time.Time `json:"until"`
+}
+
+// tracked holds the per-window counts of a fingerprint. history is a ring of
+// the last BaselineWindows closed windows.
+type tracked struct {
+	input   models.FingerprintInput
+	project string
+	current int64
+	history []int64
+	next    int
+	filled  int
+}
+
+// Alerter detects first-seen fingerprints and rate spikes and delivers alerts
+// to the configured notifiers.
+type Alerter struct {
+	cfg       config.AlertingConfig
+	window    time.Duration
+	notifiers []Notifier
+	logger    *logrus.Logger
+	startedAt time.Time
+	queue     chan Alert
+
+	mu       sync.Mutex
+	tracked  map[string]*tracked
+	silences map[string]time.Time
+	lastSent map[string]time.Time
+	recent   []Alert
+
+	fired        int64
+	suppressed   int64
+	notifyErrors int64
+}
+
+func NewAlerter(cfg config.AlertingConfig, notifiers []Notifier, logger *logrus.Logger) *Alerter {
+	return &Alerter{
+		cfg:       cfg,
+		window:    time.Duration(cfg.Window) * time.Second,
+		notifiers: notifiers,
+		logger:    logger,
+		startedAt: time.Now(),
+		queue:     make(chan Alert, alertQueueSize),
+		tracked:   make(map[string]*tracked),
+		silences:  make(map[string]time.Time),
+		lastSent:  make(map[string]time.Time),
+	}
+}
+
+// Observe counts a report towards its fingerprint's rate in the current
+// window. isNew tells whether the report created a new issue; new issues
+// alert immediately unless the alerter is still warming up after startup,
+// when every fingerprint looks new.
+func (a *Alerter) Observe(report *models.CSPReport, isNew bool) {
+	if report.Fingerprint == "" || report.ParsedReport == nil {
+		return
+	}
+
+	a.mu.Lock()
+	t, ok := a.tracked[report.Fingerprint]
+	if !ok && (a.cfg.MaxTracked <= 0 || len(a.tracked) < a.cfg.MaxTracked) {
+		t = &tracked{
+			input:   report.FingerprintInput(),
+			project: report.Project,
+			history: make([]int64, a.cfg.BaselineWindows),
+		}
+		a.tracked[report.Fingerprint] = t
+	}
+	if t != nil {
+		t.current++
+	}
+	a.mu.Unlock()
+
+	warmingUp := time.Since(a.startedAt) < time.Duration(a.cfg.Warmup)*time.Second
+	if isNew && a.cfg.NewIssues && !warmingUp {
+		a.fire(Alert{
+			Kind:        KindNewIssue,
+			Fingerprint: report.Fingerprint,
+			Input:       report.FingerprintInput(),
+			Project:     report.Project,
+			Count:       1,
+			FiredAt:     time.Now().UTC(),
+		})
+	}
+}
+
+// CloseWindow ends the current window. A fingerprint spikes when its count
+// reaches SpikeMinCount and SpikeFactor times its average over the previous
+// windows; fingerprints without any history are left to the new issue alert.
+func (a *Alerter) CloseWindow(now time.Time) {
+	var spikes []Alert
+
+	a.mu.Lock()
+	for fingerprint, t := range a.tracked {
+		if a.cfg.Spikes && t.filled > 0 {
+			var sum int64
+			for _, count := range t.history {
+				sum += count
+			}
+			baseline := float64(sum) / float64(t.filled)
+
+			// A baseline below one report per window would make any
+			// trickle look like a spike
+			threshold := a.cfg.SpikeFactor * baseline
+			if threshold < a.cfg.SpikeFactor {
+				threshold = a.cfg.SpikeFactor
+			}
+
+			if t.current >= int64(a.cfg.SpikeMinCount) && float64(t.current) >= threshold {
+				spikes = append(spikes, Alert{
+					Kind:        KindSpike,
+					Fingerprint: fingerprint,
+					Input:       t.input,
+					Project:     t.project,
+					Count:       t.current,
+					Baseline:    baseline,
+					Window:      a.window.String(),
+					FiredAt:     now.UTC(),
+				})
+			}
+		}
+
+		if len(t.history) > 0 {
+			t.history[t.next] = t.current
+			t.next = (t.next + 1) % len(t.history)
+			if t.filled < len(t.history) {
+				t.filled++
+			}
+		}
+		t.current = 0
+
+		// Forget fingerprints that were idle for the whole baseline
+		idle := true
+		for _, count := range t.history {
+			if count > 0 {
+				idle = false
+				break
+			}
+		}
+		if idle {
+			delete(a.tracked, fingerprint)
+		}
+	}
+	a.mu.Unlock()
+
+	for _, alert := range spikes {
+		a.fire(alert)
+	}
+}
+
+// Silence mutes alerts for a fingerprint until the given time.
+func (a *Alerter) Silence(fingerprint string, until time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.silences[fingerprint] = until
+}
+
+// Unsilence removes a silence, returning false if none existed.
+func (a *Alerter) Unsilence(fingerprint string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	_, ok := a.silences[fingerprint]
+	delete(a.silences, fingerprint)
+	return ok
+}
+
+// Silences returns the active silences ordered by expiry.
+func (a *Alerter) Silences() []Silence {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	silences := make([]Silence, 0, len(a.silences))
+	for fingerprint, until := range a.silences {
+		if !until.After(now) {
+			delete(a.silences, fingerprint)
+			continue
+		}
+		silences = append(silences, Silence{Fingerprint: fingerprint, Until: until})
+	}
+
+	sort.Slice(silences, func(i, j int) bool {
+		return silences[i].Until.Before(silences[j].Until)
+	})
+	return silences
+}
+
+// Recent returns the most recently fired alerts, newest first.
+func (a *Alerter) Recent() []Alert {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	alerts := make([]Alert, len(a.recent))
+	for i, alert := range a.recent {
+		alerts[len(a.recent)-1-i] = alert
+	}
+	return alerts
+}
+
+// Stats returns the number of alerts fired, alerts suppressed by silences or
+// dedup, and failed deliveries.
+func (a *Alerter) Stats() (fired, suppressed, notifyErrors int64) {
+	return atomic.LoadInt64(&a.fired), atomic.LoadInt64(&a.suppressed), atomic.LoadInt64(&a.notifyErrors)
+}
+
+// Run closes windows and delivers queued alerts until ctx is cancelled.
+func (a *Alerter) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.CloseWindow(now)
+		case alert := <-a.queue:
+			a.notify(ctx, alert)
+		}
+	}
+}
+
+// fire records an alert and queues it for delivery unless the fingerprint is
+// silenced or the same alert was already sent within the dedup window.
+func (a *Alerter) fire(alert Alert) {
+	key := string(alert.Kind) + "|" + alert.Fingerprint
+
+	a.mu.Lock()
+	if until, ok := a.silences[alert.Fingerprint]; ok && until.After(alert.FiredAt) {
+		a.mu.Unlock()
+		atomic.AddInt64(&a.suppressed, 1)
+		return
+	}
+	if last, ok := a.lastSent[key]; ok && alert.FiredAt.Sub(last) < time.Duration(a.cfg.DedupWindow)*time.Second {
+		a.mu.Unlock()
+		atomic.AddInt64(&a.suppressed, 1)
+		return
+	}
+	a.pruneLastSent(alert.FiredAt)
+	a.lastSent[key] = alert.FiredAt
+
+	a.recent = append(a.recent, alert)
+	if len(a.recent) > maxRecentAlerts {
+		a.recent = a.recent[len(a.recent)-maxRecentAlerts:]
+	}
+	a.mu.Unlock()
+
+	atomic.AddInt64(&a.fired, 1)
+
+	select {
+	case a.queue <- alert:
+	default:
+		atomic.AddInt64(&a.notifyErrors, 1)
+		a.logger.WithField("fingerprint", alert.Fingerprint).Warn("Alert queue is full, dropping alert")
+	}
+}
+
+// pruneLastSent drops dedup entries older than the dedup window. The caller
+// must hold a.mu.
+func (a *Alerter) pruneLastSent(now time.Time) {
+	window := time.Duration(a.cfg.DedupWindow) * time.Second
+	for key, sent := range a.lastSent {
+		if now.Sub(sent) >= window {
+			delete(a.lastSent, key)
+		}
+	}
+}
+
+func (a *Alerter) notify(ctx context.Context, alert Alert) {
+	for _, notifier := range a.notifiers {
+		notifyCtx, cancel := context.WithTimeout(ctx, notifyTimeout)
+		err := notifier.Notify(notifyCtx, alert)
+		cancel()
+
+		if err != nil {
+			atomic.AddInt64(&a.notifyErrors, 1)
+			a.logger.WithError(err).WithFields(logrus.Fields{
+				"notifier":    notifier.Name(),
+				"fingerprint": alert.Fingerprint,
+			}).Error("Failed to send alert")
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package alerting
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+type recordingNotifier struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (n *recordingNotifier) Name() string {
+	return "recording"
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alert Alert) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.alerts = append(n.alerts, alert)
+	return nil
+}
+
+func (n *recordingNotifier) count() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.alerts)
+}
+
+func testAlertingConfig() config.AlertingConfig {
+	return config.AlertingConfig{
+		Enabled:         true,
+		NewIssues:       true,
+		Spikes:          true,
+		Window:          60,
+		BaselineWindows: 3,
+		SpikeFactor:     3,
+		SpikeMinCount:   10,
+		DedupWindow:     3600,
+		MaxTracked:      100,
+	}
+}
+
+func newAlertReport(blockedURI string) *models.CSPReport {
+	parsed := &models.ParsedCSPReport{
+		DocumentURI:       "https://example.com/checkout",
+		ViolatedDirective: "script-src",
+		BlockedURI:        blockedURI,
+	}
+	return &models.CSPReport{
+		Project:      "shop",
+		ParsedReport: parsed,
+		Fingerprint:  models.ComputeFingerprint(parsed),
+	}
+}
+
+func TestAlerter_NewIssue(t *testing.T) {
+	alerter := NewAlerter(testAlertingConfig(), nil, logrus.New())
+	report := newAlertReport("https://evil.com/x.js")
+
+	alerter.Observe(report, true)
+	// The same issue again must be deduplicated
+	alerter.Observe(report, true)
+	alerter.Observe(report, false)
+
+	alerts := alerter.Recent()
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %d", len(alerts))
+	}
+	if alerts[0].Kind != KindNewIssue {
+		t.Errorf("Expected kind %s, got %s", KindNewIssue, alerts[0].Kind)
+	}
+	if alerts[0].Input.BlockedOrigin != "https://evil.com" {
+		t.Errorf("Expected blocked origin https://evil.com, got %s", alerts[0].Input.BlockedOrigin)
+	}
+	if alerts[0].Project != "shop" {
+		t.Errorf("Expected project shop, got %s", alerts[0].Project)
+	}
+
+	fired, suppressed, _ := alerter.Stats()
+	if fired != 1 || suppressed != 1 {
+		t.Errorf("Expected 1 fired and 1 suppressed, got %d and %d", fired, suppressed)
+	}
+}
+
+func TestAlerter_Warmup(t *testing.T) {
+	cfg := testAlertingConfig()
+	cfg.Warmup = 3600
+	alerter := NewAlerter(cfg, nil, logrus.New())
+
+	alerter.Observe(newAlertReport("https://evil.com/x.js"), true)
+
+	if alerts := alerter.Recent(); len(alerts) != 0 {
+		t.Errorf("Expected no alerts during warmup, got %d", len(alerts))
+	}
+}
+
+func TestAlerter_Spike(t *testing.T) {
+	cfg := testAlertingConfig()
+	cfg.NewIssues = false
+	alerter := NewAlerter(cfg, nil, logrus.New())
+
+	steady := newAlertReport("https://evil.com/x.js")
+	spiking := newAlertReport("https://cdn.example.net/lib.js")
+
+	now := time.Now()
+	observe := func(report *models.CSPReport, count int) {
+		for i := 0; i < count; i++ {
+			alerter.Observe(report, false)
+		}
+	}
+
+	// Baseline of 5 per window for both
+	for i := 0; i < 3; i++ {
+		observe(steady, 5)
+		observe(spiking, 5)
+		now = now.Add(time.Minute)
+		alerter.CloseWindow(now)
+	}
+
+	observe(steady, 12)
+	observe(spiking, 40)
+	now = now.Add(time.Minute)
+	alerter.CloseWindow(now)
+
+	alerts := alerter.Recent()
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 spike alert, got %d", len(alerts))
+	}
+	alert := alerts[0]
+	if alert.Kind != KindSpike || alert.Fingerprint != spiking.Fingerprint {
+		t.Errorf("Expected spike for %s, got %s for %s", spiking.Fingerprint, alert.Kind, alert.Fingerprint)
+	}
+	if alert.Count != 40 {
+		t.Errorf("Expected count 40, got %d", alert.Count)
+	}
+	if alert.Baseline != 5 {
+		t.Errorf("Expected baseline 5, got %v", alert.Baseline)
+	}
+}
+
+func TestAlerter_SpikeNeedsMinCountAndHistory(t *testing.T) {
+	cfg := testAlertingConfig()
+	cfg.NewIssues = false
+	report := newAlertReport("https://evil.com/x.js")
+	now := time.Now()
+
+	// Without history a burst is left to the new issue alert
+	alerter := NewAlerter(cfg, nil, logrus.New())
+	for i := 0; i < 50; i++ {
+		alerter.Observe(report, false)
+	}
+	alerter.CloseWindow(now)
+
+	// Nine times the baseline but below the minimum count
+	quiet := NewAlerter(cfg, nil, logrus.New())
+	for i := 1; i <= 3; i++ {
+		quiet.Observe(report, false)
+		quiet.CloseWindow(now.Add(time.Duration(i) * time.Minute))
+	}
+	for i := 0; i < 9; i++ {
+		quiet.Observe(report, false)
+	}
+	quiet.CloseWindow(now.Add(4 * time.Minute))
+
+	if alerts := alerter.Recent(); len(alerts) != 0 {
+		t.Errorf("Expected no alert without history, got %+v", alerts)
+	}
+	if alerts := quiet.Recent(); len(alerts) != 0 {
+		t.Errorf("Expected no alert below the minimum count, got %+v", alerts)
+	}
+}
+
+func TestAlerter_ForgetsIdleFingerprints(t *testing.T) {
+	alerter := NewAlerter(testAlertingConfig(), nil, logrus.New())
+	alerter.Observe(newAlertReport("https://evil.com/x.js"), false)
+
+	now := time.Now()
+	for i := 1; i <= 4; i++ {
+		alerter.CloseWindow(now.Add(time.Duration(i) * time.Minute))
+	}
+
+	alerter.mu.Lock()
+	defer alerter.mu.Unlock()
+	if len(alerter.tracked) != 0 {
+		t.Errorf("Expected idle fingerprint to be forgotten, got %d tracked", len(alerter.tracked))
+	}
+}
+
+func TestAlerter_Silence(t *testing.T) {
+	alerter := NewAlerter(testAlertingConfig(), nil, logrus.New())
+	silenced := newAlertReport("https://evil.com/x.js")
+	other := newAlertReport("https://other.com/x.js")
+
+	alerter.Silence(silenced.Fingerprint, time.Now().Add(time.Hour))
+	alerter.Silence("expired", time.Now().Add(-time.Minute))
+
+	alerter.Observe(silenced, true)
+	alerter.Observe(other, true)
+
+	alerts := alerter.Recent()
+	if len(alerts) != 1 || alerts[0].Fingerprint != other.Fingerprint {
+		t.Errorf("Expected only the unsilenced alert, got %+v", alerts)
+	}
+
+	silences := alerter.Silences()
+	if len(silences) != 1 || silences[0].Fingerprint != silenced.Fingerprint {
+		t.Errorf("Expected 1 active silence, got %+v", silences)
+	}
+
+	if !alerter.Unsilence(silenced.Fingerprint) {
+		t.Error("Expected silence to be removed")
+	}
+	if alerter.Unsilence(silenced.Fingerprint) {
+		t.Error("Expected second removal to report a missing silence")
+	}
+}
+
+func TestAlerter_RunDeliversAlerts(t *testing.T) {
+	notifier := &recordingNotifier{}
+	alerter := NewAlerter(testAlertingConfig(), []Notifier{notifier}, logrus.New())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		alerter.Run(ctx)
+		close(done)
+	}()
+
+	alerter.Observe(newAlertReport("https://evil.com/x.js"), true)
+	alerter.Observe(newAlertReport("https://other.com/x.js"), true)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for notifier.count() < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := notifier.count(); got != 2 {
+		t.Errorf("Expected 2 delivered alerts, got %d", got)
+	}
+}
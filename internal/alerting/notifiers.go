@@ -0,0 +1,183 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"universal-csp-report/internal/config"
+)
+
+// Notifier delivers an alert to an external system.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// NewNotifiers returns a notifier for every destination set in cfg.
+func NewNotifiers(cfg config.AlertingConfig) []Notifier {
+	var notifiers []Notifier
+
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURL))
+	}
+	if cfg.SlackWebhookURL != "" {
+		notifiers = append(notifiers, NewSlackNotifier(cfg.SlackWebhookURL))
+	}
+	if cfg.SMTP.Addr != "" && len(cfg.SMTP.To) > 0 {
+		notifiers = append(notifiers, NewSMTPNotifier(cfg.SMTP))
+	}
+
+	return notifiers
+}
+
+// Summary returns a one-line human readable description of the alert.
+func (a Alert) Summary() string {
+	subject := a.Input.Directive + " blocked " + a.Input.BlockedOrigin
+	if a.Input.DocumentPath != "" {
+		subject += " on " + a.Input.DocumentPath
+	}
+	if a.Project != "" {
+		subject = "[" + a.Project + "] " + subject
+	}
+
+	switch a.Kind {
+	case KindSpike:
+		return fmt.Sprintf("CSP violation spike: %s (%d reports in %s, baseline %.1f)", subject, a.Count, a.Window, a.Baseline)
+	default:
+		return "New CSP violation: " + subject
+	}
+}
+
+// WebhookNotifier POSTs the alert as JSON.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{}}
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, alert Alert) error {
+	return postJSON(ctx, n.client, n.url, alert)
+}
+
+// SlackNotifier posts the alert summary to a Slack-compatible incoming
+// webhook.
+type SlackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewSlackNotifier(url string) *SlackNotifier {
+	return &SlackNotifier{url: url, client: &http.Client{}}
+}
+
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (n *SlackNotifier) Notify(ctx context.Context, alert Alert) error {
+	text := alert.Summary() + "\nFingerprint: `" + alert.Fingerprint + "`"
+	return postJSON(ctx, n.client, n.url, map[string]string{"text": text})
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("alert endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// SMTPNotifier emails the alert. Authentication uses PLAIN when a username
+// is configured, which net/smtp only allows over TLS or to localhost.
+type SMTPNotifier struct {
+	cfg config.SMTPConfig
+}
+
+func NewSMTPNotifier(cfg config.SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg}
+}
+
+func (n *SMTPNotifier) Name() string {
+	return "smtp"
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, alert Alert) error {
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		host, _, err := net.SplitHostPort(n.cfg.Addr)
+		if err != nil {
+			return fmt.Errorf("invalid SMTP address: %w", err)
+		}
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, host)
+	}
+
+	body, err := json.MarshalIndent(alert, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(n.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", headerValue(alert.Summary()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(alert.Summary() + "\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(string(body), "\n", "\r\n") + "\r\n")
+
+	// net/smtp has no context support, so the send runs in the background and
+	// is abandoned once ctx expires
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(n.cfg.Addr, auth, n.cfg.From, n.cfg.To, msg.Bytes())
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to send alert email: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to send alert email: %w", ctx.Err())
+	}
+}
+
+// headerValue strips line breaks so report content cannot inject headers.
+func headerValue(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,207 @@
+package alerting
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+func testAlert() Alert {
+	return Alert{
+		Kind:        KindSpike,
+		Fingerprint: "abc123",
+		Input: models.FingerprintInput{
+			Directive:     "script-src",
+			BlockedOrigin: "https://evil.com",
+			DocumentPath:  "/checkout",
+		},
+		Project:  "shop",
+		Count:    40,
+		Baseline: 5,
+		Window:   "5m0s",
+		FiredAt:  time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestAlertSummary(t *testing.T) {
+	alert := testAlert()
+	expected := "CSP violation spike: [shop] script-src blocked https://evil.com on /checkout (40 reports in 5m0s, baseline 5.0)"
+	if got := alert.Summary(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+
+	alert.Kind = KindNewIssue
+	alert.Project = ""
+	expected = "New CSP violation: script-src blocked https://evil.com on /checkout"
+	if got := alert.Summary(); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Alert
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected JSON content type, got %s", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode alert: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookNotifier(server.URL).Notify(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if received.Fingerprint != "abc123" || received.Count != 40 {
+		t.Errorf("Expected the alert to be posted, got %+v", received)
+	}
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := NewWebhookNotifier(server.URL).Notify(context.Background(), testAlert()); err == nil {
+		t.Error("Expected an error for a 500 response")
+	}
+}
+
+func TestSlackNotifier(t *testing.T) {
+	var payload map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	if err := NewSlackNotifier(server.URL).Notify(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !strings.HasPrefix(payload["text"], "CSP violation spike:") || !strings.Contains(payload["text"], "abc123") {
+		t.Errorf("Unexpected Slack text: %q", payload["text"])
+	}
+}
+
+// fakeSMTPServer accepts one message and sends it on the returned channel.
+func fakeSMTPServer(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		reply("220 localhost ESMTP")
+		var data strings.Builder
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(command, "AUTH"):
+				reply("235 Authenticated")
+			case strings.HasPrefix(command, "DATA"):
+				reply("354 Go ahead")
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(line)
+				}
+				messages <- data.String()
+				reply("250 Queued")
+			case strings.HasPrefix(command, "QUIT"):
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	return listener.Addr().String(), messages
+}
+
+func TestSMTPNotifier(t *testing.T) {
+	addr, messages := fakeSMTPServer(t)
+
+	notifier := NewSMTPNotifier(config.SMTPConfig{
+		Addr:     addr,
+		Username: "alerts",
+		Password: "secret",
+		From:     "csp@example.com",
+		To:       []string{"security@example.com", "oncall@example.com"},
+	})
+
+	if err := notifier.Notify(context.Background(), testAlert()); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	select {
+	case message := <-messages:
+		for _, expected := range []string{
+			"To: security@example.com, oncall@example.com",
+			"Subject: CSP violation spike: [shop] script-src blocked https://evil.com",
+			`"fingerprint": "abc123"`,
+		} {
+			if !strings.Contains(message, expected) {
+				t.Errorf("Expected message to contain %q, got:\n%s", expected, message)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the email")
+	}
+}
+
+func TestNewNotifiers(t *testing.T) {
+	cfg := config.AlertingConfig{
+		WebhookURL:      "http://localhost/hook",
+		SlackWebhookURL: "http://localhost/slack",
+		SMTP:            config.SMTPConfig{Addr: "localhost:25"},
+	}
+
+	notifiers := NewNotifiers(cfg)
+	if len(notifiers) != 2 {
+		t.Fatalf("Expected 2 notifiers without SMTP recipients, got %d", len(notifiers))
+	}
+
+	cfg.SMTP.To = []string{"security@example.com"}
+	if notifiers := NewNotifiers(cfg); len(notifiers) != 3 {
+		t.Errorf("Expected 3 notifiers, got %d", len(notifiers))
+	}
+}
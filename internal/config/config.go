@@ -8,76 +8,451 @@ import (
 
 // Default configuration values
 const (
-	DefaultServerPort      = 8080
-	DefaultReadTimeout     = 30
-	DefaultWriteTimeout    = 30
-	DefaultIdleTimeout     = 120
-	DefaultRateLimit       = 10000
-	DefaultRateBurst       = 20000
-	DefaultWorkerCount     = 10
-	DefaultBatchSize       = 100
-	DefaultQueueSize       = 10000
-	DefaultFlushInterval   = 5
-	DefaultLogLevel        = 4  // Info level
-	DefaultShutdownTimeout = 30 // seconds
-	BatchChannelMultiplier = 2  // Buffer multiplier for batch channel
+	DefaultServerPort           = 8080
+	DefaultReadTimeout          = 30
+	DefaultWriteTimeout         = 30
+	DefaultIdleTimeout          = 120
+	DefaultRateLimit            = 10000
+	DefaultRateBurst            = 20000
+	DefaultWorkerCount          = 10
+	DefaultBatchSize            = 100
+	DefaultQueueSize            = 10000
+	DefaultFlushInterval        = 5
+	DefaultLogLevel             = 4  // Info level
+	DefaultShutdownTimeout      = 30 // seconds
+	BatchChannelMultiplier      = 2  // Buffer multiplier for batch channel
+	DefaultMaxIssues            = 10000
+	DefaultMaxIssuePages        = 20
+	DefaultMaxInlineScripts     = 10000
+	DefaultMaxRolloutPolicies   = 100
+	DefaultDedupWindow          = 60 // seconds
+	DefaultDedupMaxEntries      = 100000
+	DefaultRollupMaxBuckets     = 50000
+	DefaultRollupMaxRetries     = 100000
+	DefaultSampleRate           = 1.0
+	DefaultSampleKeepFirst      = 10
+	DefaultStreamMaxSubscribers = 50
+	DefaultStreamBufferSize     = 100
+	DefaultAlertWindow          = 300 // seconds
+	DefaultAlertBaselineWindows = 12
+	DefaultAlertSpikeFactor     = 3.0
+	DefaultAlertSpikeMinCount   = 20
+	DefaultAlertWarmup          = 600  // seconds
+	DefaultAlertDedupWindow     = 3600 // seconds
+	DefaultAlertMaxTracked      = 10000
+)
+
+// DefaultDedupKey identifies duplicates as the same violation at the same
+// location reported by the same client.
+var DefaultDedupKey = []string{"fingerprint", "document_uri", "source_file", "line_number", "column_number", "remote_addr"}
+
+// Actions for reports whose document origin is not on the allowlist
+const (
+	OriginActionReject     = "reject"
+	OriginActionQuarantine = "quarantine"
+)
+
+// Default query parameters removed from URIs and scrubbers applied by
+// redaction
+var (
+	DefaultRedactQueryParams = []string{"token", "access_token", "id_token", "refresh_token", "code", "state", "session", "sessionid", "password", "email", "key", "api_key", "apikey", "auth", "signature", "sig"}
+	DefaultRedactScrubbers   = []string{"email", "jwt", "credit_card"}
+)
+
+// Default rollup buckets and the dimensions counted in each bucket
+var (
+	DefaultRollupIntervals  = []string{"1m", "1h"}
+	DefaultRollupDimensions = []string{"directive", "blocked_host", "document_host"}
+)
+
+// Default Elasticsearch index settings
+const (
+	DefaultESShards          = 1
+	DefaultESReplicas        = 0
+	DefaultESRefreshInterval = "30s"
+	DefaultESRolloverMaxSize = "50gb"
+	DefaultESRolloverMaxAge  = "1d"
+	DefaultESWarmAfter       = "7d"
+	DefaultESDeleteAfter     = "30d"
+)
+
+// Supported storage backends
+const (
+	StorageBackendElasticsearch = "elasticsearch"
+	StorageBackendClickHouse    = "clickhouse"
+	StorageBackendWebhook       = "webhook"
+)
+
+// Webhook body formats
+const (
+	WebhookFormatNDJSON    = "ndjson"
+	WebhookFormatJSONArray = "json_array"
+	WebhookFormatPerReport = "per_report"
+)
+
+// Default webhook values
+const (
+	DefaultWebhookTimeout      = 10  // seconds
+	DefaultWebhookMaxRetries   = 3   // attempts after the first
+	DefaultWebhookRetryBackoff = 500 // milliseconds, doubled per retry
 )
 
 type Config struct {
 	Server         ServerConfig         `json:"server"`
 	BatchProcessor BatchProcessorConfig `json:"batch_processor"`
+	StorageBackend string               `json:"storage_backend"`
 	Elasticsearch  ElasticsearchConfig  `json:"elasticsearch"`
+	ClickHouse     ClickHouseConfig     `json:"clickhouse"`
+	Webhook        WebhookConfig        `json:"webhook"`
 	LogLevel       int                  `json:"log_level"`
+	ProjectsFile   string               `json:"projects_file"`
 }
 
 type ServerConfig struct {
-	Port         int  `json:"port"`
-	Production   bool `json:"production"`
-	ReadTimeout  int  `json:"read_timeout"`
-	WriteTimeout int  `json:"write_timeout"`
-	IdleTimeout  int  `json:"idle_timeout"`
-	RateLimit    int  `json:"rate_limit"`
-	RateBurst    int  `json:"rate_burst"`
+	Port            int             `json:"port"`
+	Production      bool            `json:"production"`
+	ReadTimeout     int             `json:"read_timeout"`
+	WriteTimeout    int             `json:"write_timeout"`
+	IdleTimeout     int             `json:"idle_timeout"`
+	RateLimit       int             `json:"rate_limit"`
+	RateBurst       int             `json:"rate_burst"`
+	AllowedOrigins  []string        `json:"allowed_origins"`
+	OriginAction    string          `json:"origin_action"`
+	Projects        []ProjectConfig `json:"projects"`
+	APIKeys         []string        `json:"-"`
+	APIAuthDisabled bool            `json:"api_auth_disabled"`
+	Dashboard       bool            `json:"dashboard"`
+	PolicyRegistry  string          `json:"policy_registry"`
+	PublicURL       string          `json:"public_url"`
+}
+
+// ProjectConfig defines a tenant reporting to /report/:project/:key. Zero
+// limits mean unlimited.
+type ProjectConfig struct {
+	ID         string `json:"id"`
+	Key        string `json:"key"`
+	RateLimit  int    `json:"rate_limit"`
+	RateBurst  int    `json:"rate_burst"`
+	DailyQuota int    `json:"daily_quota"`
 }
 
 type BatchProcessorConfig struct {
-	WorkerCount   int `json:"worker_count"`
-	BatchSize     int `json:"batch_size"`
-	QueueSize     int `json:"queue_size"`
-	FlushInterval int `json:"flush_interval"`
+	WorkerCount      int             `json:"worker_count"`
+	BatchSize        int             `json:"batch_size"`
+	QueueSize        int             `json:"queue_size"`
+	FlushInterval    int             `json:"flush_interval"`
+	MaxIssues        int             `json:"max_issues"`
+	MaxIssuePages    int             `json:"max_issue_pages"`
+	MaxInlineScripts int             `json:"max_inline_scripts"`
+	MaxRollouts      int             `json:"max_rollout_policies"`
+	Dedup            DedupConfig     `json:"dedup"`
+	Rollup           RollupConfig    `json:"rollup"`
+	Sampling         SamplingConfig  `json:"sampling"`
+	Filter           FilterConfig    `json:"filter"`
+	Routes           RouteConfig     `json:"routes"`
+	Redaction        RedactionConfig `json:"redaction"`
+	Stream           StreamConfig    `json:"stream"`
+	Alerting         AlertingConfig  `json:"alerting"`
+}
+
+// AlertingConfig controls new issue and spike alerts. Window and Warmup are in
+// seconds; a spike needs SpikeMinCount reports in a window and SpikeFactor
+// times the average of the previous BaselineWindows windows.
+type AlertingConfig struct {
+	Enabled         bool       `json:"enabled"`
+	NewIssues       bool       `json:"new_issues"`
+	Spikes          bool       `json:"spikes"`
+	Window          int        `json:"window"`
+	BaselineWindows int        `json:"baseline_windows"`
+	SpikeFactor     float64    `json:"spike_factor"`
+	SpikeMinCount   int        `json:"spike_min_count"`
+	Warmup          int        `json:"warmup"`
+	DedupWindow     int        `json:"dedup_window"`
+	MaxTracked      int        `json:"max_tracked"`
+	WebhookURL      string     `json:"webhook_url"`
+	SlackWebhookURL string     `json:"-"`
+	SMTP            SMTPConfig `json:"smtp"`
+}
+
+// SMTPConfig configures the email notifier. Email alerts are disabled when
+// Addr or To is empty.
+type SMTPConfig struct {
+	Addr     string   `json:"addr"`
+	Username string   `json:"username"`
+	Password string   `json:"-"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// StreamConfig limits live tail subscribers and the reports buffered for each.
+type StreamConfig struct {
+	MaxSubscribers int `json:"max_subscribers"`
+	BufferSize     int `json:"buffer_size"`
+}
+
+// FilterConfig controls the noise filter. RulesFile points to a JSON array of
+// rules applied after the built-in defaults.
+type FilterConfig struct {
+	Enabled      bool   `json:"enabled"`
+	DefaultRules bool   `json:"default_rules"`
+	RulesFile    string `json:"rules_file"`
+}
+
+// RouteConfig lists the path templating rules producing document routes.
+// Patterns are route patterns such as /orders/:id; RulesFile points to a JSON
+// array of rules applied after them.
+type RouteConfig struct {
+	Patterns  []string `json:"patterns"`
+	RulesFile string   `json:"rules_file"`
+}
+
+// RedactionConfig controls the removal of personal data from the URIs and
+// script samples of reports, both parsed and raw, before they are counted,
+// streamed or stored. RulesFile points to a JSON array of regex scrubbers
+// applied after the built-in Scrubbers; SampleLength truncates script
+// samples to that many characters when positive.
+type RedactionConfig struct {
+	Enabled       bool     `json:"enabled"`
+	StripQuery    bool     `json:"strip_query"`
+	QueryParams   []string `json:"query_params"`
+	StripFragment bool     `json:"strip_fragment"`
+	Scrubbers     []string `json:"scrubbers"`
+	RulesFile     string   `json:"rules_file"`
+	SampleLength  int      `json:"sample_length"`
+}
+
+// SamplingConfig controls head sampling. The most specific rate applies:
+// fingerprint, then blocked or document host, then directive, then Rate.
+type SamplingConfig struct {
+	Enabled          bool               `json:"enabled"`
+	Rate             float64            `json:"rate"`
+	DirectiveRates   map[string]float64 `json:"directive_rates"`
+	HostRates        map[string]float64 `json:"host_rates"`
+	FingerprintRates map[string]float64 `json:"fingerprint_rates"`
+	KeepFirst        int                `json:"keep_first"`
+}
+
+type RollupConfig struct {
+	Enabled    bool     `json:"enabled"`
+	Intervals  []string `json:"intervals"`
+	Dimensions []string `json:"dimensions"`
+	MaxBuckets int      `json:"max_buckets"`
+	MaxRetries int      `json:"max_retries"`
+}
+
+type DedupConfig struct {
+	Enabled    bool     `json:"enabled"`
+	Window     int      `json:"window"`
+	MaxEntries int      `json:"max_entries"`
+	Key        []string `json:"key"`
 }
 
 type ElasticsearchConfig struct {
-	Addresses   []string `json:"addresses"`
-	Username    string   `json:"username"`
-	Password    string   `json:"password"`
-	IndexPrefix string   `json:"index_prefix"`
+	Addresses          []string  `json:"addresses"`
+	CloudID            string    `json:"cloud_id"`
+	Username           string    `json:"username"`
+	Password           string    `json:"password"`
+	PasswordFile       string    `json:"password_file"`
+	APIKey             string    `json:"api_key"`
+	APIKeyFile         string    `json:"api_key_file"`
+	ServiceToken       string    `json:"service_token"`
+	ServiceTokenFile   string    `json:"service_token_file"`
+	CAFile             string    `json:"ca_file"`
+	CertFile           string    `json:"cert_file"`
+	KeyFile            string    `json:"key_file"`
+	InsecureSkipVerify bool      `json:"insecure_skip_verify"`
+	IndexPrefix        string    `json:"index_prefix"`
+	DataStream         bool      `json:"data_stream"`
+	Shards             int       `json:"shards"`
+	Replicas           int       `json:"replicas"`
+	RefreshInterval    string    `json:"refresh_interval"`
+	ILM                ILMConfig `json:"ilm"`
+}
+
+// ILMConfig describes the index lifecycle policy managed by the service.
+// Phase ages set to "none" disable the corresponding phase.
+type ILMConfig struct {
+	Enabled         bool   `json:"enabled"`
+	PolicyName      string `json:"policy_name"`
+	RolloverMaxSize string `json:"rollover_max_size"`
+	RolloverMaxAge  string `json:"rollover_max_age"`
+	WarmAfter       string `json:"warm_after"`
+	DeleteAfter     string `json:"delete_after"`
+}
+
+type ClickHouseConfig struct {
+	URL      string `json:"url"`
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type WebhookConfig struct {
+	URL                string            `json:"url"`
+	Method             string            `json:"method"`
+	Headers            map[string]string `json:"headers"`
+	BearerToken        string            `json:"bearer_token"`
+	Username           string            `json:"username"`
+	Password           string            `json:"password"`
+	Format             string            `json:"format"`
+	BatchSize          int               `json:"batch_size"`
+	Timeout            int               `json:"timeout"`
+	MaxRetries         int               `json:"max_retries"`
+	RetryBackoff       int               `json:"retry_backoff"`
+	CAFile             string            `json:"ca_file"`
+	CertFile           string            `json:"cert_file"`
+	KeyFile            string            `json:"key_file"`
+	InsecureSkipVerify bool              `json:"insecure_skip_verify"`
 }
 
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnvInt("SERVER_PORT", DefaultServerPort),
-			Production:   getEnvBool("PRODUCTION", false),
-			ReadTimeout:  getEnvInt("SERVER_READ_TIMEOUT", DefaultReadTimeout),
-			WriteTimeout: getEnvInt("SERVER_WRITE_TIMEOUT", DefaultWriteTimeout),
-			IdleTimeout:  getEnvInt("SERVER_IDLE_TIMEOUT", DefaultIdleTimeout),
-			RateLimit:    getEnvInt("RATE_LIMIT", DefaultRateLimit),
-			RateBurst:    getEnvInt("RATE_BURST", DefaultRateBurst),
+			Port:            getEnvInt("SERVER_PORT", DefaultServerPort),
+			Production:      getEnvBool("PRODUCTION", false),
+			ReadTimeout:     getEnvInt("SERVER_READ_TIMEOUT", DefaultReadTimeout),
+			WriteTimeout:    getEnvInt("SERVER_WRITE_TIMEOUT", DefaultWriteTimeout),
+			IdleTimeout:     getEnvInt("SERVER_IDLE_TIMEOUT", DefaultIdleTimeout),
+			RateLimit:       getEnvInt("RATE_LIMIT", DefaultRateLimit),
+			RateBurst:       getEnvInt("RATE_BURST", DefaultRateBurst),
+			AllowedOrigins:  getEnvStringSlice("ALLOWED_ORIGINS", nil),
+			OriginAction:    getEnvString("ORIGIN_ACTION", OriginActionReject),
+			APIKeys:         getEnvStringSlice("API_KEYS", nil),
+			APIAuthDisabled: getEnvBool("API_AUTH_DISABLED", false),
+			Dashboard:       getEnvBool("DASHBOARD_ENABLED", true),
+			PolicyRegistry:  getEnvString("POLICY_REGISTRY_FILE", ""),
+			PublicURL:       getEnvString("PUBLIC_URL", ""),
 		},
 		BatchProcessor: BatchProcessorConfig{
-			WorkerCount:   getEnvInt("WORKER_COUNT", DefaultWorkerCount),
-			BatchSize:     getEnvInt("BATCH_SIZE", DefaultBatchSize),
-			QueueSize:     getEnvInt("QUEUE_SIZE", DefaultQueueSize),
-			FlushInterval: getEnvInt("FLUSH_INTERVAL", DefaultFlushInterval),
+			WorkerCount:      getEnvInt("WORKER_COUNT", DefaultWorkerCount),
+			BatchSize:        getEnvInt("BATCH_SIZE", DefaultBatchSize),
+			QueueSize:        getEnvInt("QUEUE_SIZE", DefaultQueueSize),
+			FlushInterval:    getEnvInt("FLUSH_INTERVAL", DefaultFlushInterval),
+			MaxIssues:        getEnvInt("MAX_ISSUES", DefaultMaxIssues),
+			MaxIssuePages:    getEnvInt("MAX_ISSUE_PAGES", DefaultMaxIssuePages),
+			MaxInlineScripts: getEnvInt("MAX_INLINE_SCRIPTS", DefaultMaxInlineScripts),
+			MaxRollouts:      getEnvInt("MAX_ROLLOUT_POLICIES", DefaultMaxRolloutPolicies),
+			Dedup: DedupConfig{
+				Enabled:    getEnvBool("DEDUP_ENABLED", false),
+				Window:     getEnvInt("DEDUP_WINDOW", DefaultDedupWindow),
+				MaxEntries: getEnvInt("DEDUP_MAX_ENTRIES", DefaultDedupMaxEntries),
+				Key:        getEnvStringSlice("DEDUP_KEY", DefaultDedupKey),
+			},
+			Rollup: RollupConfig{
+				Enabled:    getEnvBool("ROLLUP_ENABLED", false),
+				Intervals:  getEnvStringSlice("ROLLUP_INTERVALS", DefaultRollupIntervals),
+				Dimensions: getEnvStringSlice("ROLLUP_DIMENSIONS", DefaultRollupDimensions),
+				MaxBuckets: getEnvInt("ROLLUP_MAX_BUCKETS", DefaultRollupMaxBuckets),
+				MaxRetries: getEnvInt("ROLLUP_MAX_RETRIES", DefaultRollupMaxRetries),
+			},
+			Sampling: SamplingConfig{
+				Enabled:          getEnvBool("SAMPLING_ENABLED", false),
+				Rate:             getEnvFloat("SAMPLE_RATE", DefaultSampleRate),
+				DirectiveRates:   getEnvFloatMap("SAMPLE_RATE_DIRECTIVES"),
+				HostRates:        getEnvFloatMap("SAMPLE_RATE_HOSTS"),
+				FingerprintRates: getEnvFloatMap("SAMPLE_RATE_FINGERPRINTS"),
+				KeepFirst:        getEnvInt("SAMPLE_KEEP_FIRST", DefaultSampleKeepFirst),
+			},
+			Filter: FilterConfig{
+				Enabled:      getEnvBool("FILTER_ENABLED", false),
+				DefaultRules: getEnvBool("FILTER_DEFAULT_RULES", true),
+				RulesFile:    getEnvString("FILTER_RULES_FILE", ""),
+			},
+			Redaction: RedactionConfig{
+				Enabled:       getEnvBool("REDACTION_ENABLED", false),
+				StripQuery:    getEnvBool("REDACT_STRIP_QUERY", false),
+				QueryParams:   getEnvStringSlice("REDACT_QUERY_PARAMS", DefaultRedactQueryParams),
+				StripFragment: getEnvBool("REDACT_STRIP_FRAGMENT", true),
+				Scrubbers:     getEnvStringSlice("REDACT_SCRUBBERS", DefaultRedactScrubbers),
+				RulesFile:     getEnvString("REDACT_RULES_FILE", ""),
+				SampleLength:  getEnvInt("REDACT_SAMPLE_LENGTH", 0),
+			},
+			Routes: RouteConfig{
+				Patterns:  getEnvStringSlice("ROUTE_PATTERNS", nil),
+				RulesFile: getEnvString("ROUTE_RULES_FILE", ""),
+			},
+			Stream: StreamConfig{
+				MaxSubscribers: getEnvInt("STREAM_MAX_SUBSCRIBERS", DefaultStreamMaxSubscribers),
+				BufferSize:     getEnvInt("STREAM_BUFFER_SIZE", DefaultStreamBufferSize),
+			},
+			Alerting: AlertingConfig{
+				Enabled:         getEnvBool("ALERTING_ENABLED", false),
+				NewIssues:       getEnvBool("ALERT_NEW_ISSUES", true),
+				Spikes:          getEnvBool("ALERT_SPIKES", true),
+				Window:          getEnvInt("ALERT_WINDOW", DefaultAlertWindow),
+				BaselineWindows: getEnvInt("ALERT_BASELINE_WINDOWS", DefaultAlertBaselineWindows),
+				SpikeFactor:     getEnvFloat("ALERT_SPIKE_FACTOR", DefaultAlertSpikeFactor),
+				SpikeMinCount:   getEnvInt("ALERT_SPIKE_MIN_COUNT", DefaultAlertSpikeMinCount),
+				Warmup:          getEnvInt("ALERT_WARMUP", DefaultAlertWarmup),
+				DedupWindow:     getEnvInt("ALERT_DEDUP_WINDOW", DefaultAlertDedupWindow),
+				MaxTracked:      getEnvInt("ALERT_MAX_TRACKED", DefaultAlertMaxTracked),
+				WebhookURL:      getEnvString("ALERT_WEBHOOK_URL", ""),
+				SlackWebhookURL: getEnvString("ALERT_SLACK_WEBHOOK_URL", ""),
+				SMTP: SMTPConfig{
+					Addr:     getEnvString("ALERT_SMTP_ADDR", ""),
+					Username: getEnvString("ALERT_SMTP_USERNAME", ""),
+					Password: getEnvString("ALERT_SMTP_PASSWORD", ""),
+					From:     getEnvString("ALERT_SMTP_FROM", ""),
+					To:       getEnvStringSlice("ALERT_SMTP_TO", nil),
+				},
+			},
 		},
 		Elasticsearch: ElasticsearchConfig{
-			Addresses:   getEnvStringSlice("ELASTICSEARCH_ADDRESSES", []string{"http://localhost:9200"}),
-			Username:    getEnvString("ELASTICSEARCH_USERNAME", ""),
-			Password:    getEnvString("ELASTICSEARCH_PASSWORD", ""),
-			IndexPrefix: getEnvString("ELASTICSEARCH_INDEX_PREFIX", "csp-reports"),
+			Addresses:          getEnvStringSlice("ELASTICSEARCH_ADDRESSES", []string{"http://localhost:9200"}),
+			CloudID:            getEnvString("ELASTICSEARCH_CLOUD_ID", ""),
+			Username:           getEnvString("ELASTICSEARCH_USERNAME", ""),
+			Password:           getEnvString("ELASTICSEARCH_PASSWORD", ""),
+			PasswordFile:       getEnvString("ELASTICSEARCH_PASSWORD_FILE", ""),
+			APIKey:             getEnvString("ELASTICSEARCH_API_KEY", ""),
+			APIKeyFile:         getEnvString("ELASTICSEARCH_API_KEY_FILE", ""),
+			ServiceToken:       getEnvString("ELASTICSEARCH_SERVICE_TOKEN", ""),
+			ServiceTokenFile:   getEnvString("ELASTICSEARCH_SERVICE_TOKEN_FILE", ""),
+			CAFile:             getEnvString("ELASTICSEARCH_CA_FILE", ""),
+			CertFile:           getEnvString("ELASTICSEARCH_CERT_FILE", ""),
+			KeyFile:            getEnvString("ELASTICSEARCH_KEY_FILE", ""),
+			InsecureSkipVerify: getEnvBool("ELASTICSEARCH_INSECURE_SKIP_VERIFY", false),
+			IndexPrefix:        getEnvString("ELASTICSEARCH_INDEX_PREFIX", "csp-reports"),
+			DataStream:         getEnvBool("ELASTICSEARCH_DATA_STREAM", false),
+			Shards:             getEnvInt("ELASTICSEARCH_SHARDS", DefaultESShards),
+			Replicas:           getEnvInt("ELASTICSEARCH_REPLICAS", DefaultESReplicas),
+			RefreshInterval:    getEnvString("ELASTICSEARCH_REFRESH_INTERVAL", DefaultESRefreshInterval),
+			ILM: ILMConfig{
+				Enabled:         getEnvBool("ELASTICSEARCH_ILM_ENABLED", false),
+				PolicyName:      getEnvString("ELASTICSEARCH_ILM_POLICY", ""),
+				RolloverMaxSize: getEnvString("ELASTICSEARCH_ILM_ROLLOVER_MAX_SIZE", DefaultESRolloverMaxSize),
+				RolloverMaxAge:  getEnvString("ELASTICSEARCH_ILM_ROLLOVER_MAX_AGE", DefaultESRolloverMaxAge),
+				WarmAfter:       getEnvString("ELASTICSEARCH_ILM_WARM_AFTER", DefaultESWarmAfter),
+				DeleteAfter:     getEnvString("ELASTICSEARCH_ILM_DELETE_AFTER", DefaultESDeleteAfter),
+			},
+		},
+		StorageBackend: getEnvString("STORAGE_BACKEND", StorageBackendElasticsearch),
+		ClickHouse: ClickHouseConfig{
+			URL:      getEnvString("CLICKHOUSE_URL", "http://localhost:8123"),
+			Database: getEnvString("CLICKHOUSE_DATABASE", "default"),
+			Table:    getEnvString("CLICKHOUSE_TABLE", "csp_reports"),
+			Username: getEnvString("CLICKHOUSE_USERNAME", ""),
+			Password: getEnvString("CLICKHOUSE_PASSWORD", ""),
+		},
+		Webhook: WebhookConfig{
+			URL:                getEnvString("WEBHOOK_URL", ""),
+			Method:             getEnvString("WEBHOOK_METHOD", "POST"),
+			Headers:            getEnvStringMap("WEBHOOK_HEADERS", nil),
+			BearerToken:        getEnvString("WEBHOOK_BEARER_TOKEN", ""),
+			Username:           getEnvString("WEBHOOK_USERNAME", ""),
+			Password:           getEnvString("WEBHOOK_PASSWORD", ""),
+			Format:             getEnvString("WEBHOOK_FORMAT", WebhookFormatNDJSON),
+			BatchSize:          getEnvInt("WEBHOOK_BATCH_SIZE", 0),
+			Timeout:            getEnvInt("WEBHOOK_TIMEOUT", DefaultWebhookTimeout),
+			MaxRetries:         getEnvInt("WEBHOOK_MAX_RETRIES", DefaultWebhookMaxRetries),
+			RetryBackoff:       getEnvInt("WEBHOOK_RETRY_BACKOFF", DefaultWebhookRetryBackoff),
+			CAFile:             getEnvString("WEBHOOK_CA_FILE", ""),
+			CertFile:           getEnvString("WEBHOOK_CERT_FILE", ""),
+			KeyFile:            getEnvString("WEBHOOK_KEY_FILE", ""),
+			InsecureSkipVerify: getEnvBool("WEBHOOK_INSECURE_SKIP_VERIFY", false),
 		},
-		LogLevel: getEnvInt("LOG_LEVEL", DefaultLogLevel),
+		LogLevel:     getEnvInt("LOG_LEVEL", DefaultLogLevel),
+		ProjectsFile: getEnvString("PROJECTS_FILE", ""),
 	}
 }
 
@@ -97,6 +472,15 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -112,3 +496,34 @@ func getEnvStringSlice(key string, defaultValue []string) []string {
 	}
 	return defaultValue
 }
+
+// getEnvStringMap parses comma-separated key=value pairs, e.g.
+// "Authorization=Splunk abc,X-Source=csp".
+func getEnvStringMap(key string, defaultValue map[string]string) map[string]string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// getEnvFloatMap parses comma-separated key=rate pairs, e.g.
+// "img-src=0.01,script-src=0.5". Pairs with an invalid rate are skipped.
+func getEnvFloatMap(key string) map[string]float64 {
+	result := make(map[string]float64)
+	for k, v := range getEnvStringMap(key, nil) {
+		if rate, err := strconv.ParseFloat(v, 64); err == nil {
+			result[k] = rate
+		}
+	}
+	return result
+}
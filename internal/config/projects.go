@@ -0,0 +1,62 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// projectIDPattern keeps project IDs safe for use in index and URL names.
+var projectIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// reservedProjectPrefixes clash with index names used by the service
+// itself: project indices are named <prefix>-<project>-*, so a project
+// starting with rollups would match the rollup index pattern.
+var reservedProjectPrefixes = []string{"rollups"}
+
+func isReservedProjectID(id string) bool {
+	for _, prefix := range reservedProjectPrefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadProjects reads the tenants from ProjectsFile, a JSON array of
+// projects, into the server configuration. It does nothing when no file is
+// configured.
+func (c *Config) LoadProjects() error {
+	if c.ProjectsFile == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.ProjectsFile)
+	if err != nil {
+		return fmt.Errorf("failed to read projects file: %w", err)
+	}
+
+	var projects []ProjectConfig
+	if err := json.Unmarshal(data, &projects); err != nil {
+		return fmt.Errorf("failed to parse projects file: %w", err)
+	}
+
+	seen := make(map[string]bool, len(projects))
+	for _, project := range projects {
+		if !projectIDPattern.MatchString(project.ID) || isReservedProjectID(project.ID) {
+			return fmt.Errorf("invalid project ID %q", project.ID)
+		}
+		if seen[project.ID] {
+			return fmt.Errorf("duplicate project ID %q", project.ID)
+		}
+		if project.Key == "" {
+			return fmt.Errorf("project %q has no key", project.ID)
+		}
+		seen[project.ID] = true
+	}
+
+	c.Server.Projects = projects
+	return nil
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Categories a blocked URI is classified into. Network resources are either
+// first-party or third-party and may additionally be known-cdn.
+const (
+	BlockedInline           = "inline"
+	BlockedEval             = "eval"
+	BlockedWasmEval         = "wasm-eval"
+	BlockedData             = "data"
+	BlockedBlob             = "blob"
+	BlockedTrustedTypesSink = "trusted-types-sink"
+	// BlockedTrustedTypesPolicy is a policy creation blocked by the
+	// trusted-types directive
+	BlockedTrustedTypesPolicy = "trusted-types-policy"
+	BlockedExtension          = "extension"
+	BlockedFirstParty         = "first-party"
+	BlockedThirdParty         = "third-party"
+	BlockedKnownCDN           = "known-cdn"
+)
+
+// blockedKeywords maps the non-URL values browsers report as blocked URI to
+// their category.
+var blockedKeywords = map[string]string{
+	"":                     BlockedInline,
+	"inline":               BlockedInline,
+	"unsafe-inline":        BlockedInline,
+	"'unsafe-inline'":      BlockedInline,
+	"eval":                 BlockedEval,
+	"unsafe-eval":          BlockedEval,
+	"'unsafe-eval'":        BlockedEval,
+	"wasm-eval":            BlockedWasmEval,
+	"trusted-types-sink":   BlockedTrustedTypesSink,
+	"trusted-types-policy": BlockedTrustedTypesPolicy,
+	"data":                 BlockedData,
+	"blob":                 BlockedBlob,
+}
+
+// extensionSchemes are the URL schemes of browser extension resources.
+var extensionSchemes = map[string]bool{
+	"chrome-extension":     true,
+	"moz-extension":        true,
+	"safari-extension":     true,
+	"safari-web-extension": true,
+	"ms-browser-extension": true,
+	// Safari hides extension URLs behind this scheme
+	"webkit-masked-url": true,
+}
+
+// knownCDNDomains are public CDNs commonly allowlisted in policies. A host
+// matches when it is the domain or one of its subdomains.
+var knownCDNDomains = []string{
+	"ajax.aspnetcdn.com",
+	"ajax.googleapis.com",
+	"akamaihd.net",
+	"bootstrapcdn.com",
+	"cdn.skypack.dev",
+	"cdnjs.cloudflare.com",
+	"cloudfront.net",
+	"code.jquery.com",
+	"esm.sh",
+	"fastly.net",
+	"fonts.googleapis.com",
+	"gstatic.com",
+	"jsdelivr.net",
+	"unpkg.com",
+}
+
+// defaultPorts are used when a URL has no explicit port.
+var defaultPorts = map[string]int{
+	"http":  80,
+	"https": 443,
+	"ws":    80,
+	"wss":   443,
+	"ftp":   21,
+}
+
+// BlockedResource is a blocked URI decomposed for aggregation. Domain is the
+// registrable domain, e.g. example.co.uk for cdn.example.co.uk, and Port
+// falls back to the scheme's default.
+type BlockedResource struct {
+	Scheme     string   `json:"scheme,omitempty"`
+	Host       string   `json:"host,omitempty"`
+	Domain     string   `json:"domain,omitempty"`
+	Port       int      `json:"port,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+}
+
+// DecomposeBlockedURI splits a blocked URI into its parts and classifies it.
+// Network resources are first-party when they share the registrable domain
+// of the document.
+func DecomposeBlockedURI(blockedURI, documentURI string) BlockedResource {
+	raw := strings.TrimSpace(blockedURI)
+	if category, ok := blockedKeywords[strings.ToLower(raw)]; ok {
+		return BlockedResource{Categories: []string{category}}
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return BlockedResource{}
+	}
+
+	resource := BlockedResource{
+		Scheme: strings.ToLower(u.Scheme),
+		Host:   strings.ToLower(u.Hostname()),
+		Path:   u.EscapedPath(),
+	}
+
+	switch {
+	case resource.Scheme == "data":
+		resource.Path = ""
+		resource.Categories = []string{BlockedData}
+		return resource
+	case resource.Scheme == "blob":
+		resource.Path = ""
+		resource.Categories = []string{BlockedBlob}
+		return resource
+	case extensionSchemes[resource.Scheme]:
+		resource.Categories = []string{BlockedExtension}
+		return resource
+	}
+
+	if resource.Host == "" {
+		return resource
+	}
+
+	resource.Port = defaultPorts[resource.Scheme]
+	if port, err := strconv.Atoi(u.Port()); err == nil {
+		resource.Port = port
+	}
+	resource.Domain = RegistrableDomain(resource.Host)
+
+	if documentDomain := RegistrableDomain(URLHost(documentURI)); documentDomain != "" && documentDomain == resource.Domain {
+		resource.Categories = append(resource.Categories, BlockedFirstParty)
+	} else {
+		resource.Categories = append(resource.Categories, BlockedThirdParty)
+	}
+	if IsKnownCDN(resource.Host) {
+		resource.Categories = append(resource.Categories, BlockedKnownCDN)
+	}
+	return resource
+}
+
+// BlockedResource decomposes and classifies the report's blocked URI.
+func (p *ParsedCSPReport) BlockedResource() BlockedResource {
+	return DecomposeBlockedURI(p.BlockedURI, p.DocumentURI)
+}
+
+// RegistrableDomain returns the public suffix plus one label of a host, using
+// the public suffix list embedded in the binary. IP addresses and hosts that
+// are themselves a public suffix, such as localhost, are returned as is.
+func RegistrableDomain(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return host
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// IsKnownCDN reports whether a host belongs to a well-known public CDN.
+func IsKnownCDN(host string) bool {
+	host = strings.ToLower(host)
+	for _, domain := range knownCDNDomains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecomposeBlockedURI(t *testing.T) {
+	tests := []struct {
+		name        string
+		blockedURI  string
+		documentURI string
+		expected    BlockedResource
+	}{
+		{
+			name:        "third-party script",
+			blockedURI:  "https://cdn.evil.com/x.js?v=1",
+			documentURI: "https://shop.example.com/",
+			expected:    BlockedResource{Scheme: "https", Host: "cdn.evil.com", Domain: "evil.com", Port: 443, Path: "/x.js", Categories: []string{BlockedThirdParty}},
+		},
+		{
+			name:        "first-party subdomain with explicit port",
+			blockedURI:  "http://static.example.co.uk:8080/app.js",
+			documentURI: "https://www.example.co.uk/",
+			expected:    BlockedResource{Scheme: "http", Host: "static.example.co.uk", Domain: "example.co.uk", Port: 8080, Path: "/app.js", Categories: []string{BlockedFirstParty}},
+		},
+		{
+			name:        "sibling on a public suffix is third-party",
+			blockedURI:  "https://other.github.io/x.js",
+			documentURI: "https://mine.github.io/",
+			expected:    BlockedResource{Scheme: "https", Host: "other.github.io", Domain: "other.github.io", Port: 443, Path: "/x.js", Categories: []string{BlockedThirdParty}},
+		},
+		{
+			name:        "known CDN",
+			blockedURI:  "https://cdn.jsdelivr.net/npm/lib.js",
+			documentURI: "https://example.com/",
+			expected:    BlockedResource{Scheme: "https", Host: "cdn.jsdelivr.net", Domain: "jsdelivr.net", Port: 443, Path: "/npm/lib.js", Categories: []string{BlockedThirdParty, BlockedKnownCDN}},
+		},
+		{
+			name:       "IP address",
+			blockedURI: "wss://10.0.0.1/socket",
+			expected:   BlockedResource{Scheme: "wss", Host: "10.0.0.1", Domain: "10.0.0.1", Port: 443, Path: "/socket", Categories: []string{BlockedThirdParty}},
+		},
+		{
+			name:       "extension",
+			blockedURI: "chrome-extension://abcdef/content.js",
+			expected:   BlockedResource{Scheme: "chrome-extension", Host: "abcdef", Path: "/content.js", Categories: []string{BlockedExtension}},
+		},
+		{
+			name:       "data URL",
+			blockedURI: "data:image/png;base64,AAAA",
+			expected:   BlockedResource{Scheme: "data", Categories: []string{BlockedData}},
+		},
+		{"data keyword", "data", "", BlockedResource{Categories: []string{BlockedData}}},
+		{"blob keyword", "blob", "", BlockedResource{Categories: []string{BlockedBlob}}},
+		{"inline", "inline", "", BlockedResource{Categories: []string{BlockedInline}}},
+		{"eval", "eval", "", BlockedResource{Categories: []string{BlockedEval}}},
+		{"wasm-eval", "wasm-eval", "", BlockedResource{Categories: []string{BlockedWasmEval}}},
+		{"trusted types sink", "trusted-types-sink", "", BlockedResource{Categories: []string{BlockedTrustedTypesSink}}},
+		{"trusted types policy", "trusted-types-policy", "", BlockedResource{Categories: []string{BlockedTrustedTypesPolicy}}},
+		{"unknown value", "self", "", BlockedResource{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DecomposeBlockedURI(tt.blockedURI, tt.documentURI); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := map[string]string{
+		"www.example.com":   "example.com",
+		"a.b.example.co.uk": "example.co.uk",
+		"Example.COM.":      "example.com",
+		"localhost":         "localhost",
+		"::1":               "::1",
+		"":                  "",
+	}
+
+	for host, expected := range tests {
+		if got := RegistrableDomain(host); got != expected {
+			t.Errorf("RegistrableDomain(%q): expected %q, got %q", host, expected, got)
+		}
+	}
+}
@@ -9,15 +9,36 @@ import (
 )
 
 type CSPReport struct {
-	ID               string                 `json:"id"`
-	Timestamp        time.Time              `json:"timestamp"`
-	UserAgent        string                 `json:"user_agent"`
-	RemoteAddr       string                 `json:"remote_addr"`
-	BrowserType      string                 `json:"browser_type"`
-	ParsedReport     *ParsedCSPReport       `json:"parsed_report"`
-	RawReport        map[string]interface{} `json:"raw_report"`
-	HumanReadable    string                 `json:"human_readable"`
-	ProcessingErrors []string               `json:"processing_errors,omitempty"`
+	ID                string                 `json:"id"`
+	Project           string                 `json:"project,omitempty"`
+	Timestamp         time.Time              `json:"timestamp"`
+	UserAgent         string                 `json:"user_agent"`
+	RemoteAddr        string                 `json:"remote_addr"`
+	BrowserType       string                 `json:"browser_type"`
+	ParsedReport      *ParsedCSPReport       `json:"parsed_report"`
+	RawReport         map[string]interface{} `json:"raw_report"`
+	HumanReadable     string                 `json:"human_readable"`
+	Fingerprint       string                 `json:"fingerprint,omitempty"`
+	DocumentRoute     string                 `json:"document_route,omitempty"`
+	PolicyFingerprint string                 `json:"policy_fingerprint,omitempty"`
+	PolicyName        string                 `json:"policy_name,omitempty"`
+	PolicyVersion     int                    `json:"policy_version,omitempty"`
+	Occurrences       int                    `json:"occurrences,omitempty"`
+	FirstOccurrence   *time.Time             `json:"first_occurrence,omitempty"`
+	LastOccurrence    *time.Time             `json:"last_occurrence,omitempty"`
+	SampleRate        float64                `json:"sample_rate,omitempty"`
+	Tags              []string               `json:"tags,omitempty"`
+	Downgraded        bool                   `json:"downgraded,omitempty"`
+	Redactions        []Redaction            `json:"redactions,omitempty"`
+	ProcessingErrors  []string               `json:"processing_errors,omitempty"`
+}
+
+// Redaction records how often a redaction rule changed a report field. Fields
+// of the raw report are prefixed with raw_report and their path.
+type Redaction struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Count int    `json:"count"`
 }
 
 type ParsedCSPReport struct {
@@ -112,6 +133,7 @@ func parseIndividualReport(rawReport map[string]interface{}, userAgent, remoteAd
 		RemoteAddr:  remoteAddr,
 		BrowserType: detectBrowserType(userAgent),
 		RawReport:   rawReport,
+		SampleRate:  1,
 	}
 
 	// Check if this is a Report-To format
@@ -128,9 +150,41 @@ func parseIndividualReport(rawReport map[string]interface{}, userAgent, remoteAd
 	}
 
 	report.HumanReadable = generateHumanReadable(report.ParsedReport)
+	if report.ParsedReport != nil {
+		report.ApplyRoutes(nil)
+		report.PolicyFingerprint = PolicyFingerprint(report.ParsedReport.OriginalPolicy)
+	}
 	return report
 }
 
+// DirectiveName returns the lowercased directive name, preferring the
+// effective directive and dropping any source list appended to it.
+func (p *ParsedCSPReport) DirectiveName() string {
+	directive := p.EffectiveDirective
+	if directive == "" {
+		directive = p.ViolatedDirective
+	}
+	name, _ := SplitDirective(directive)
+	return name
+}
+
+// Dispositions of a violated policy: enforced, or only reported.
+const (
+	DispositionEnforce = "enforce"
+	DispositionReport  = "report"
+)
+
+// DispositionName returns the normalized disposition, or an empty string when
+// the browser did not send one (CSP Level 2 reports) or sent an unknown value.
+func (p *ParsedCSPReport) DispositionName() string {
+	switch disposition := strings.ToLower(strings.TrimSpace(p.Disposition)); disposition {
+	case DispositionEnforce, DispositionReport:
+		return disposition
+	default:
+		return ""
+	}
+}
+
 func extractReportToData(rawReport map[string]interface{}) (*ParsedCSPReport, []string) {
 	var errors []string
 	parsed := &ParsedCSPReport{}
@@ -279,20 +333,24 @@ func extractInt(data map[string]interface{}, keys ...string) *int {
 	return nil
 }
 
-// normalizeBlockedURI handles special blocked-uri values
+// normalizeBlockedURI handles special blocked-uri values: an empty value
+// means inline code and keywords are lowercased.
 func normalizeBlockedURI(uri string) string {
+	uri = strings.TrimSpace(uri)
 	if uri == "" {
 		return "inline"
 	}
 
 	// Normalize common special values
-	switch strings.ToLower(uri) {
+	switch lower := strings.ToLower(uri); lower {
 	case "self":
 		return "'self'"
 	case "unsafe-eval":
 		return "'unsafe-eval'"
 	case "unsafe-inline":
 		return "'unsafe-inline'"
+	case "inline", "eval", "wasm-eval", "trusted-types-sink", "trusted-types-policy", "data", "blob":
+		return lower
 	}
 
 	return uri
@@ -314,6 +372,25 @@ func detectBrowserType(userAgent string) string {
 	}
 }
 
+// Weight returns the number of reports a stored report stands for: its
+// occurrences when deduplicated, scaled up by the rate it was sampled at.
+func (r *CSPReport) Weight() float64 {
+	weight := 1.0
+	if r.Occurrences > 0 {
+		weight = float64(r.Occurrences)
+	}
+	if r.SampleRate > 0 {
+		weight /= r.SampleRate
+	}
+	return weight
+}
+
+// RefreshHumanReadable regenerates the description of a report after its
+// parsed fields changed.
+func (r *CSPReport) RefreshHumanReadable() {
+	r.HumanReadable = generateHumanReadable(r.ParsedReport)
+}
+
 func generateHumanReadable(parsed *ParsedCSPReport) string {
 	if parsed == nil {
 		return "Failed to parse CSP report"
@@ -205,6 +205,46 @@ func TestParseCSPReports_ReportToSingleFormat(t *testing.T) {
 	if parsed.Disposition != "report" {
 		t.Errorf("Disposition: expected report, got %s", parsed.Disposition)
 	}
+	if expected := PolicyFingerprint(parsed.OriginalPolicy); reports[0].PolicyFingerprint != expected || expected == "" {
+		t.Errorf("PolicyFingerprint: expected %s, got %s", expected, reports[0].PolicyFingerprint)
+	}
+}
+
+func TestDispositionName(t *testing.T) {
+	tests := map[string]string{
+		"enforce":   DispositionEnforce,
+		" Report ":  DispositionReport,
+		"":          "",
+		"something": "",
+	}
+
+	for disposition, expected := range tests {
+		parsed := &ParsedCSPReport{Disposition: disposition}
+		if got := parsed.DispositionName(); got != expected {
+			t.Errorf("Expected DispositionName(%q) = %q, got %q", disposition, expected, got)
+		}
+	}
+}
+
+func TestCSPReport_Weight(t *testing.T) {
+	tests := []struct {
+		name     string
+		report   CSPReport
+		expected float64
+	}{
+		{"plain", CSPReport{}, 1},
+		{"deduplicated", CSPReport{Occurrences: 3}, 3},
+		{"sampled", CSPReport{SampleRate: 0.25}, 4},
+		{"both", CSPReport{Occurrences: 3, SampleRate: 0.5}, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if weight := tt.report.Weight(); weight != tt.expected {
+				t.Errorf("Expected weight %v, got %v", tt.expected, weight)
+			}
+		})
+	}
 }
 
 func TestParseCSPReports_SpecialBlockedURIValues(t *testing.T) {
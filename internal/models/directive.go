@@ -0,0 +1,54 @@
+package models
+
+import "strings"
+
+// subDirectives maps the CSP Level 3 directives that refine a fetch directive
+// for elements or attributes onto the directive they refine.
+var subDirectives = map[string]string{
+	"script-src-elem": "script-src",
+	"script-src-attr": "script-src",
+	"style-src-elem":  "style-src",
+	"style-src-attr":  "style-src",
+}
+
+// SplitDirective splits a reported directive into its lowercased name and the
+// source list some browsers (Safari, older Chrome) append to it.
+func SplitDirective(raw string) (name, value string) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	return strings.ToLower(fields[0]), strings.Join(fields[1:], " ")
+}
+
+// CanonicalDirective maps a directive name onto the directive family it
+// belongs to, so script-src-elem and script-src-attr count as script-src.
+func CanonicalDirective(name string) string {
+	if base, ok := subDirectives[name]; ok {
+		return base
+	}
+	return name
+}
+
+// CanonicalDirective returns the directive family of the violation.
+func (p *ParsedCSPReport) CanonicalDirective() string {
+	return CanonicalDirective(p.DirectiveName())
+}
+
+// GoverningDirective returns the directive of the original policy that
+// actually applied to the resource, following the CSP Level 3 fallback list,
+// e.g. default-src for a script-src-elem violation of a policy without
+// script-src, or for a frame-src violation of a policy without child-src.
+// Without an original policy it falls back to the violated directive, which
+// CSP Level 2 browsers report as the governing one.
+func (p *ParsedCSPReport) GoverningDirective() string {
+	if p.OriginalPolicy != "" {
+		if governing, _, ok := ParsePolicy(p.OriginalPolicy).Governing(p.DirectiveName()); ok {
+			return governing
+		}
+	}
+	if name, _ := SplitDirective(p.ViolatedDirective); name != "" {
+		return name
+	}
+	return p.DirectiveName()
+}
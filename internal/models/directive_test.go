@@ -0,0 +1,97 @@
+package models
+
+import "testing"
+
+func TestSplitDirective(t *testing.T) {
+	tests := []struct {
+		raw, name, value string
+	}{
+		{"script-src-elem", "script-src-elem", ""},
+		{"Script-Src 'self' https://cdn.example.com", "script-src", "'self' https://cdn.example.com"},
+		{"  ", "", ""},
+	}
+
+	for _, tt := range tests {
+		name, value := SplitDirective(tt.raw)
+		if name != tt.name || value != tt.value {
+			t.Errorf("SplitDirective(%q): expected (%q, %q), got (%q, %q)", tt.raw, tt.name, tt.value, name, value)
+		}
+	}
+}
+
+func TestParsedCSPReport_Directives(t *testing.T) {
+	tests := []struct {
+		name      string
+		parsed    ParsedCSPReport
+		canonical string
+		governing string
+	}{
+		{
+			name:      "sub-directive falls back to default-src",
+			parsed:    ParsedCSPReport{EffectiveDirective: "script-src-elem", ViolatedDirective: "script-src-elem", OriginalPolicy: "default-src 'self'"},
+			canonical: "script-src",
+			governing: "default-src",
+		},
+		{
+			name:      "sub-directive governed by script-src",
+			parsed:    ParsedCSPReport{EffectiveDirective: "script-src-attr", OriginalPolicy: "default-src 'self'; script-src 'self'"},
+			canonical: "script-src",
+			governing: "script-src",
+		},
+		{
+			name:      "sub-directive present in the policy",
+			parsed:    ParsedCSPReport{EffectiveDirective: "style-src-elem", OriginalPolicy: "style-src 'self'; style-src-elem 'self'"},
+			canonical: "style-src",
+			governing: "style-src-elem",
+		},
+		{
+			name:      "legacy violated directive with sources",
+			parsed:    ParsedCSPReport{ViolatedDirective: "default-src 'self' https://cdn.example.com"},
+			canonical: "default-src",
+			governing: "default-src",
+		},
+		{
+			name:      "frame without child-src falls back to default-src",
+			parsed:    ParsedCSPReport{EffectiveDirective: "frame-src", OriginalPolicy: "default-src 'self'; script-src 'self'"},
+			canonical: "frame-src",
+			governing: "default-src",
+		},
+		{
+			name:      "child-src never falls back to script-src",
+			parsed:    ParsedCSPReport{EffectiveDirective: "child-src", OriginalPolicy: "default-src 'self'; script-src 'self'"},
+			canonical: "child-src",
+			governing: "default-src",
+		},
+		{
+			name:      "worker falls back through script-src",
+			parsed:    ParsedCSPReport{EffectiveDirective: "worker-src", OriginalPolicy: "default-src 'self'; script-src 'self'"},
+			canonical: "worker-src",
+			governing: "script-src",
+		},
+		{
+			name:      "effective directive without a policy",
+			parsed:    ParsedCSPReport{EffectiveDirective: "img-src", ViolatedDirective: "default-src 'none'"},
+			canonical: "img-src",
+			governing: "default-src",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.parsed.CanonicalDirective(); got != tt.canonical {
+				t.Errorf("Expected canonical directive %q, got %q", tt.canonical, got)
+			}
+			if got := tt.parsed.GoverningDirective(); got != tt.governing {
+				t.Errorf("Expected governing directive %q, got %q", tt.governing, got)
+			}
+		})
+	}
+}
+
+func TestComputeFingerprint_SubDirectives(t *testing.T) {
+	elem := ComputeFingerprint(&ParsedCSPReport{DocumentURI: "https://example.com/", EffectiveDirective: "script-src-elem", BlockedURI: "https://evil.com/x.js"})
+	legacy := ComputeFingerprint(&ParsedCSPReport{DocumentURI: "https://example.com/", ViolatedDirective: "script-src 'self'", BlockedURI: "https://evil.com/x.js"})
+	if elem != legacy {
+		t.Errorf("Expected script-src-elem and script-src violations to share a fingerprint, got %q and %q", elem, legacy)
+	}
+}
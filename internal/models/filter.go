@@ -0,0 +1,228 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FilterAction is what a matching filter rule does to a report.
+type FilterAction string
+
+const (
+	// FilterActionDrop discards the report before it is counted or stored.
+	FilterActionDrop FilterAction = "drop"
+	// FilterActionTag stores the report with the rule's tag added.
+	FilterActionTag FilterAction = "tag"
+	// FilterActionDowngrade stores the report flagged as downgraded, keeping
+	// it out of issue tracking.
+	FilterActionDowngrade FilterAction = "downgrade"
+)
+
+// extensionPattern matches URLs of browser extension resources, e.g.
+// chrome-extension://, moz-extension:// and safari-web-extension://.
+const extensionPattern = `(?i)^[a-z-]*extension://`
+
+// FilterRule matches a report field against a glob or a regular expression.
+// Globs support * and ? and match case-insensitively.
+type FilterRule struct {
+	Name   string       `json:"name"`
+	Field  string       `json:"field"`
+	Glob   string       `json:"glob,omitempty"`
+	Regex  string       `json:"regex,omitempty"`
+	Action FilterAction `json:"action"`
+	Tag    string       `json:"tag,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// injectedSchemePattern matches the URL schemes of resources injected by
+// browser toolbars, security suites and adware rather than by the page.
+const injectedSchemePattern = `(?i)^(mxaddon-pkg|chromeinvokeimmediate|chromenull|webviewprogressproxy|tmtbff|mbinit|symres|jar|resource)://`
+
+// injectedScriptHosts are hosts of ad injectors and malware that inject
+// scripts into visited pages. A host matches including its subdomains.
+var injectedScriptHosts = []string{
+	"020dfefc4ac745dab7594f2f771c1ded.com",
+	"73a5b0806e464be8bd4e694c744624f0.com",
+	"amiok.org",
+	"cdncache-a.akamaihd.net",
+	"connectionstrenth.com",
+	"datafastguru.info",
+	"devappstor.com",
+	"godlinkapp.com",
+	"hoholikik.club",
+	"icontent.us",
+	"image2play.com",
+	"injections.adguard.com",
+	"linkluster.com",
+	"metrext.com",
+	"middlerush-a.akamaihd.net",
+	"netanalitics.space",
+	"printapplink.com",
+	"promfflinkdev.com",
+	"pulseadnetwork.com",
+	"rafomedia.com",
+	"resultshub-a.akamaihd.net",
+	"savingsslider-a.akamaihd.net",
+	"saveyoutime.ru",
+	"searchfun.in",
+	"smartlink.cool",
+	"superfish.com",
+	"tlscdn.com",
+	"websmartcenter.com",
+	"zilionfast.in",
+}
+
+// injectedHostPattern matches URLs on one of the injectedScriptHosts.
+func injectedHostPattern() string {
+	hosts := make([]string, len(injectedScriptHosts))
+	for i, host := range injectedScriptHosts {
+		hosts[i] = regexp.QuoteMeta(host)
+	}
+	return `(?i)^(https?:)?//([a-z0-9-]+\.)*(` + strings.Join(hosts, "|") + `)(:\d+)?([/?#]|$)`
+}
+
+// DefaultFilterRules returns the built-in rules dropping reports caused by
+// browser extensions, scripts injected by adware and malware, and
+// about:blank documents.
+func DefaultFilterRules() []FilterRule {
+	injectedHosts := injectedHostPattern()
+	return []FilterRule{
+		{Name: "extension-blocked-uri", Field: "blocked_uri", Regex: extensionPattern, Action: FilterActionDrop},
+		{Name: "extension-source-file", Field: "source_file", Regex: extensionPattern, Action: FilterActionDrop},
+		{Name: "masked-extension-url", Field: "blocked_uri", Glob: "webkit-masked-url:*", Action: FilterActionDrop},
+		{Name: "masked-extension-source", Field: "source_file", Glob: "webkit-masked-url:*", Action: FilterActionDrop},
+		{Name: "injected-scheme-blocked-uri", Field: "blocked_uri", Regex: injectedSchemePattern, Action: FilterActionDrop},
+		{Name: "injected-scheme-source-file", Field: "source_file", Regex: injectedSchemePattern, Action: FilterActionDrop},
+		{Name: "injected-script-blocked-uri", Field: "blocked_uri", Regex: injectedHosts, Action: FilterActionDrop},
+		{Name: "injected-script-source-file", Field: "source_file", Regex: injectedHosts, Action: FilterActionDrop},
+		{Name: "about-blank-blocked", Field: "blocked_uri", Regex: `^about(:blank)?$`, Action: FilterActionDrop},
+		{Name: "about-blank-document", Field: "document_uri", Glob: "about:*", Action: FilterActionDrop},
+	}
+}
+
+// Compile validates the rule and prepares its pattern for matching.
+func (r *FilterRule) Compile() error {
+	if r.Name == "" {
+		return fmt.Errorf("filter rule has no name")
+	}
+	if _, ok := ReportFieldValue(&CSPReport{}, r.Field); !ok {
+		return fmt.Errorf("filter rule %q: unknown field %q", r.Name, r.Field)
+	}
+
+	switch r.Action {
+	case FilterActionDrop, FilterActionDowngrade:
+	case FilterActionTag:
+		if r.Tag == "" {
+			return fmt.Errorf("filter rule %q: tag action requires a tag", r.Name)
+		}
+	default:
+		return fmt.Errorf("filter rule %q: unknown action %q", r.Name, r.Action)
+	}
+
+	pattern := r.Regex
+	switch {
+	case r.Glob != "" && r.Regex != "":
+		return fmt.Errorf("filter rule %q: set either glob or regex, not both", r.Name)
+	case r.Glob != "":
+		pattern = globToRegex(r.Glob)
+	case r.Regex == "":
+		return fmt.Errorf("filter rule %q: glob or regex is required", r.Name)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("filter rule %q: invalid pattern: %w", r.Name, err)
+	}
+	r.re = re
+	return nil
+}
+
+// Matches reports whether the rule's field matches its pattern. Rules must be
+// compiled first.
+func (r *FilterRule) Matches(report *CSPReport) bool {
+	if r.re == nil {
+		return false
+	}
+	value, _ := ReportFieldValue(report, r.Field)
+	return r.re.MatchString(value)
+}
+
+// ReportFieldValue returns a report attribute by its field name. The second
+// return value is false for unknown field names.
+func ReportFieldValue(report *CSPReport, field string) (string, bool) {
+	switch field {
+	case "fingerprint":
+		return report.Fingerprint, true
+	case "remote_addr":
+		return report.RemoteAddr, true
+	case "user_agent":
+		return report.UserAgent, true
+	case "browser_type":
+		return report.BrowserType, true
+	}
+
+	parsed := report.ParsedReport
+	if parsed == nil {
+		parsed = &ParsedCSPReport{}
+	}
+
+	switch field {
+	case "document_uri":
+		return parsed.DocumentURI, true
+	case "referrer":
+		return parsed.Referrer, true
+	case "blocked_uri":
+		return parsed.BlockedURI, true
+	case "directive":
+		return parsed.CanonicalDirective(), true
+	case "governing_directive":
+		return parsed.GoverningDirective(), true
+	case "violated_directive":
+		return parsed.ViolatedDirective, true
+	case "effective_directive":
+		return parsed.EffectiveDirective, true
+	case "original_policy":
+		return parsed.OriginalPolicy, true
+	case "script_sample":
+		return parsed.ScriptSample, true
+	case "source_file":
+		return parsed.SourceFile, true
+	case "line_number":
+		return intString(parsed.LineNumber), true
+	case "column_number":
+		return intString(parsed.ColumnNumber), true
+	case "status_code":
+		return intString(parsed.StatusCode), true
+	case "disposition":
+		return parsed.Disposition, true
+	default:
+		return "", false
+	}
+}
+
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("(?i)^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+func intString(value *int) string {
+	if value == nil {
+		return ""
+	}
+	return strconv.Itoa(*value)
+}
@@ -0,0 +1,97 @@
+package models
+
+import "testing"
+
+func TestDefaultFilterRules(t *testing.T) {
+	rules := DefaultFilterRules()
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			t.Fatalf("Default rule failed to compile: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		parsed   ParsedCSPReport
+		expected bool
+	}{
+		{"chrome extension", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "chrome-extension://abcdef/inject.js"}, true},
+		{"firefox extension source", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "inline", SourceFile: "moz-extension://1234/content.js"}, true},
+		{"safari extension", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "Safari-Web-Extension://x/y.js"}, true},
+		{"masked url", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "webkit-masked-url://hidden/"}, true},
+		{"about blank", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "about"}, true},
+		{"about blank document", ParsedCSPReport{DocumentURI: "about:blank", BlockedURI: "https://cdn.example.com/a.js"}, true},
+		{"adware host", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "https://www.superfish.com/ws/sf_main.js"}, true},
+		{"adware subdomain source", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "inline", SourceFile: "https://istatic.datafastguru.info/fo/min/abc.js"}, true},
+		{"toolbar scheme", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "mxaddon-pkg://abc/x.js"}, true},
+		{"lookalike host", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "https://notsuperfish.com/x.js"}, false},
+		{"real violation", ParsedCSPReport{DocumentURI: "https://example.com/", BlockedURI: "https://evil.com/x.js"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := tt.parsed
+			report := &CSPReport{ParsedReport: &parsed}
+
+			matched := false
+			for i := range rules {
+				if rules[i].Matches(report) {
+					matched = true
+				}
+			}
+			if matched != tt.expected {
+				t.Errorf("Expected match %v, got %v", tt.expected, matched)
+			}
+		})
+	}
+}
+
+func TestFilterRule_Compile(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    FilterRule
+		wantErr bool
+	}{
+		{"glob", FilterRule{Name: "a", Field: "blocked_uri", Glob: "*.ads.example/*", Action: FilterActionDrop}, false},
+		{"regex", FilterRule{Name: "a", Field: "script_sample", Regex: "^window\\.ad", Action: FilterActionDowngrade}, false},
+		{"tag", FilterRule{Name: "a", Field: "directive", Glob: "img-src", Action: FilterActionTag, Tag: "images"}, false},
+		{"missing name", FilterRule{Field: "blocked_uri", Glob: "*", Action: FilterActionDrop}, true},
+		{"unknown field", FilterRule{Name: "a", Field: "planet", Glob: "*", Action: FilterActionDrop}, true},
+		{"unknown action", FilterRule{Name: "a", Field: "blocked_uri", Glob: "*", Action: "explode"}, true},
+		{"tag without tag", FilterRule{Name: "a", Field: "blocked_uri", Glob: "*", Action: FilterActionTag}, true},
+		{"no pattern", FilterRule{Name: "a", Field: "blocked_uri", Action: FilterActionDrop}, true},
+		{"both patterns", FilterRule{Name: "a", Field: "blocked_uri", Glob: "*", Regex: ".*", Action: FilterActionDrop}, true},
+		{"bad regex", FilterRule{Name: "a", Field: "blocked_uri", Regex: "(", Action: FilterActionDrop}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rule.Compile()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestFilterRule_GlobMatching(t *testing.T) {
+	rule := FilterRule{Name: "ads", Field: "blocked_uri", Glob: "https://*.ads.example/*.js", Action: FilterActionDrop}
+	if err := rule.Compile(); err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	tests := map[string]bool{
+		"https://cdn.ads.example/path/to/x.js": true,
+		"HTTPS://CDN.ADS.EXAMPLE/X.JS":         true,
+		"https://ads.example/x.js":             false,
+		"https://cdn.ads.example/x.css":        false,
+		"https://cdnXads.example/x.js":         false,
+	}
+
+	for uri, expected := range tests {
+		report := &CSPReport{ParsedReport: &ParsedCSPReport{BlockedURI: uri}}
+		if got := rule.Matches(report); got != expected {
+			t.Errorf("Expected %s match %v, got %v", uri, expected, got)
+		}
+	}
+}
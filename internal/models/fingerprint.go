@@ -0,0 +1,131 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// fingerprintLength is the number of hex characters kept from the SHA-256
+// digest; 64 bits is plenty to keep distinct violations apart.
+const fingerprintLength = 16
+
+var (
+	numericSegment = regexp.MustCompile(`^\d+$`)
+	uuidSegment    = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	hexSegment     = regexp.MustCompile(`^(?i)[0-9a-f]{16,}$`)
+)
+
+// FingerprintInput holds the normalized components a fingerprint is computed
+// from. Reports with equal inputs describe the same underlying violation.
+type FingerprintInput struct {
+	Directive     string `json:"directive"`
+	BlockedOrigin string `json:"blocked_origin"`
+	DocumentPath  string `json:"document_path"`
+	SourceFile    string `json:"source_file,omitempty"`
+}
+
+// NewFingerprintInput normalizes the parsed report fields that identify a
+// violation, dropping per-request noise such as IDs and query strings.
+func NewFingerprintInput(parsed *ParsedCSPReport) FingerprintInput {
+	if parsed == nil {
+		return FingerprintInput{}
+	}
+
+	return FingerprintInput{
+		Directive:     parsed.CanonicalDirective(),
+		BlockedOrigin: BlockedOrigin(parsed.BlockedURI),
+		DocumentPath:  templatePath(parsed.DocumentURI),
+		SourceFile:    stripQuery(parsed.SourceFile),
+	}
+}
+
+// Fingerprint returns a stable identifier for the normalized input.
+func (f FingerprintInput) Fingerprint() string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{f.Directive, f.BlockedOrigin, f.DocumentPath, f.SourceFile}, "\x00")))
+	return hex.EncodeToString(sum[:])[:fingerprintLength]
+}
+
+// FingerprintInput returns the fingerprint input of a report, using its
+// document route when set.
+func (r *CSPReport) FingerprintInput() FingerprintInput {
+	input := NewFingerprintInput(r.ParsedReport)
+	if r.DocumentRoute != "" {
+		input.DocumentPath = r.DocumentRoute
+	}
+	return input
+}
+
+// ApplyRoutes sets the document route of a report from the first matching
+// rule, or the automatic template, and recomputes its fingerprint.
+func (r *CSPReport) ApplyRoutes(rules []RouteRule) {
+	if r.ParsedReport == nil {
+		return
+	}
+	r.DocumentRoute = DocumentRoute(r.ParsedReport.DocumentURI, rules)
+	r.Fingerprint = r.FingerprintInput().Fingerprint()
+}
+
+// ComputeFingerprint returns the fingerprint of a parsed report.
+func ComputeFingerprint(parsed *ParsedCSPReport) string {
+	if parsed == nil {
+		return ""
+	}
+	return NewFingerprintInput(parsed).Fingerprint()
+}
+
+// BlockedOrigin reduces a blocked URI to its origin, or to the scheme or
+// keyword for values like "inline", "eval" and "data:".
+func BlockedOrigin(blockedURI string) string {
+	u, err := url.Parse(blockedURI)
+	if err != nil || u.Scheme == "" {
+		return strings.ToLower(blockedURI)
+	}
+	if u.Host == "" {
+		return strings.ToLower(u.Scheme)
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+// templatePath returns the document host and path with numeric, UUID and
+// long hex segments collapsed so every page instance maps to the same
+// template.
+func templatePath(documentURI string) string {
+	u, err := url.Parse(documentURI)
+	if err != nil {
+		return documentURI
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if numericSegment.MatchString(segment) || uuidSegment.MatchString(segment) || hexSegment.MatchString(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	path := strings.Join(segments, "/")
+	if path == "" {
+		path = "/"
+	}
+	return strings.ToLower(u.Host) + path
+}
+
+// URLHost returns the lowercased host of an absolute URL, or an empty string
+// for keywords such as "inline" or "eval".
+func URLHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// stripQuery removes the query string and fragment from a URL.
+func stripQuery(raw string) string {
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		return raw[:i]
+	}
+	return raw
+}
@@ -0,0 +1,117 @@
+package models
+
+import "testing"
+
+func TestNewFingerprintInput(t *testing.T) {
+	parsed := &ParsedCSPReport{
+		DocumentURI:       "https://Shop.Example.com/orders/123456/items/550e8400-e29b-41d4-a716-446655440000?token=abc",
+		ViolatedDirective: "Script-Src 'self' https://cdn.example.com",
+		BlockedURI:        "https://CDN.evil.com/lib/x.js?v=2",
+		SourceFile:        "https://shop.example.com/app.js?build=42#L1",
+	}
+
+	input := NewFingerprintInput(parsed)
+	expected := FingerprintInput{
+		Directive:     "script-src",
+		BlockedOrigin: "https://cdn.evil.com",
+		DocumentPath:  "shop.example.com/orders/{id}/items/{id}",
+		SourceFile:    "https://shop.example.com/app.js",
+	}
+
+	if input != expected {
+		t.Errorf("Expected %+v, got %+v", expected, input)
+	}
+}
+
+func TestComputeFingerprint(t *testing.T) {
+	base := &ParsedCSPReport{
+		DocumentURI:        "https://example.com/users/42",
+		EffectiveDirective: "script-src-elem",
+		BlockedURI:         "https://evil.com/a.js",
+	}
+
+	tests := []struct {
+		name  string
+		other *ParsedCSPReport
+		same  bool
+	}{
+		{
+			name: "Different ID and query string",
+			other: &ParsedCSPReport{
+				DocumentURI:        "https://example.com/users/1337?ref=mail",
+				EffectiveDirective: "script-src-elem",
+				BlockedURI:         "https://evil.com/b.js",
+			},
+			same: true,
+		},
+		{
+			name: "Different directive",
+			other: &ParsedCSPReport{
+				DocumentURI:        "https://example.com/users/42",
+				EffectiveDirective: "img-src",
+				BlockedURI:         "https://evil.com/a.js",
+			},
+			same: false,
+		},
+		{
+			name: "Different blocked origin",
+			other: &ParsedCSPReport{
+				DocumentURI:        "https://example.com/users/42",
+				EffectiveDirective: "script-src-elem",
+				BlockedURI:         "https://other.com/a.js",
+			},
+			same: false,
+		},
+		{
+			name: "Different page template",
+			other: &ParsedCSPReport{
+				DocumentURI:        "https://example.com/settings",
+				EffectiveDirective: "script-src-elem",
+				BlockedURI:         "https://evil.com/a.js",
+			},
+			same: false,
+		},
+	}
+
+	fingerprint := ComputeFingerprint(base)
+	if len(fingerprint) != fingerprintLength {
+		t.Fatalf("Expected fingerprint of length %d, got %q", fingerprintLength, fingerprint)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := ComputeFingerprint(tt.other)
+			if (other == fingerprint) != tt.same {
+				t.Errorf("Expected same=%v, got %s vs %s", tt.same, fingerprint, other)
+			}
+		})
+	}
+}
+
+func TestBlockedOrigin(t *testing.T) {
+	tests := map[string]string{
+		"inline":                        "inline",
+		"eval":                          "eval",
+		"data:image/png;base64,iVBOR":   "data",
+		"blob:https://example.com/uuid": "blob",
+		"https://cdn.com:8443/x.js":     "https://cdn.com:8443",
+		"wss://socket.example.com/feed": "wss://socket.example.com",
+	}
+
+	for input, expected := range tests {
+		if got := BlockedOrigin(input); got != expected {
+			t.Errorf("BlockedOrigin(%q): expected %q, got %q", input, expected, got)
+		}
+	}
+}
+
+func TestParseCSPReports_SetsFingerprint(t *testing.T) {
+	reports, err := ParseCSPReports([]byte(`{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "img-src", "blocked-uri": "https://tracker.com/p.gif"}}`), "", "")
+	if err != nil {
+		t.Fatalf("Failed to parse report: %v", err)
+	}
+
+	if reports[0].Fingerprint != ComputeFingerprint(reports[0].ParsedReport) {
+		t.Errorf("Expected fingerprint to be set during parsing, got %q", reports[0].Fingerprint)
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"unicode/utf8"
+)
+
+// ScriptSampleField is the name script samples are redacted under.
+const ScriptSampleField = "script_sample"
+
+// ScriptSampleLimit is the length browsers truncate script samples to. Only
+// shorter samples are known to hold the complete inline code.
+const ScriptSampleLimit = 40
+
+// IsInline reports whether the violation was caused by inline code.
+func (p *ParsedCSPReport) IsInline() bool {
+	return BlockedOrigin(p.BlockedURI) == "inline"
+}
+
+// InlineHash returns the 'sha256-...' source expression allowing the inline
+// code: the hash reported by the browser, or one computed from a sample too
+// short to have been truncated. computed tells which one it is.
+func (p *ParsedCSPReport) InlineHash() (source string, computed bool) {
+	if hash := normalizeHash(p.SHA256); hash != "" {
+		return hash, false
+	}
+	if p.ScriptSample != "" && utf8.RuneCountInString(p.ScriptSample) < ScriptSampleLimit {
+		sum := sha256.Sum256([]byte(p.ScriptSample))
+		return "'sha256-" + base64.StdEncoding.EncodeToString(sum[:]) + "'", true
+	}
+	return "", false
+}
+
+// InlineHash returns the inline hash of the report's violation like
+// ParsedCSPReport.InlineHash, except that no hash is computed from a script
+// sample that was redacted: a truncated or scrubbed sample no longer hashes
+// to the inline code.
+func (r *CSPReport) InlineHash() (source string, computed bool) {
+	if r.ParsedReport == nil {
+		return "", false
+	}
+	source, computed = r.ParsedReport.InlineHash()
+	if computed && r.Redacted(ScriptSampleField) {
+		return "", false
+	}
+	return source, computed
+}
+
+// Redacted reports whether redaction changed the given field.
+func (r *CSPReport) Redacted(field string) bool {
+	for _, redaction := range r.Redactions {
+		if redaction.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeHash quotes a reported SHA-256 digest, with or without its
+// algorithm prefix, as a source expression.
+func normalizeHash(digest string) string {
+	digest = strings.Trim(strings.TrimSpace(digest), "'")
+	if digest == "" {
+		return ""
+	}
+	if !strings.HasPrefix(digest, "sha256-") {
+		digest = "sha256-" + digest
+	}
+	return "'" + digest + "'"
+}
@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestInlineHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		sha256   string
+		sample   string
+		expected string
+		computed bool
+	}{
+		{"reported with prefix", "sha256-abc=", "", "'sha256-abc='", false},
+		{"reported bare", "abc=", "", "'sha256-abc='", false},
+		{"reported wins over sample", "'sha256-abc='", "alert(1)", "'sha256-abc='", false},
+		{"complete sample", "", "alert(1)", "'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='", true},
+		{"truncated sample", "", "window.dataLayer = window.dataLayer || [", "", false},
+		{"nothing", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parsed := &ParsedCSPReport{BlockedURI: "inline", SHA256: tt.sha256, ScriptSample: tt.sample}
+			hash, computed := parsed.InlineHash()
+			if hash != tt.expected || computed != tt.computed {
+				t.Errorf("Expected %q (computed %v), got %q (computed %v)", tt.expected, tt.computed, hash, computed)
+			}
+		})
+	}
+}
+
+func TestCSPReport_InlineHashIgnoresRedactedSamples(t *testing.T) {
+	tests := []struct {
+		name       string
+		sha256     string
+		redactions []Redaction
+		expected   string
+	}{
+		{"complete sample", "", nil, "'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='"},
+		{"redacted sample", "", []Redaction{{Field: ScriptSampleField, Rule: "email", Count: 1}}, ""},
+		{"other field redacted", "", []Redaction{{Field: "document_uri", Rule: "query", Count: 1}}, "'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='"},
+		{"reported hash", "abc=", []Redaction{{Field: ScriptSampleField, Rule: "email", Count: 1}}, "'sha256-abc='"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := &CSPReport{
+				ParsedReport: &ParsedCSPReport{BlockedURI: "inline", SHA256: tt.sha256, ScriptSample: "alert(1)"},
+				Redactions:   tt.redactions,
+			}
+			if hash, _ := report.InlineHash(); hash != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, hash)
+			}
+		})
+	}
+}
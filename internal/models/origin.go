@@ -0,0 +1,99 @@
+package models
+
+import (
+	"net/url"
+	"strings"
+)
+
+// OriginAllowlist decides which document origins reports are accepted for.
+// Entries are either full origins ("https://example.com"), exact hosts
+// ("example.com") or wildcard host patterns ("*.example.com", matching
+// subdomains only).
+type OriginAllowlist struct {
+	origins  map[string]bool
+	hosts    map[string]bool
+	suffixes []string
+}
+
+// NewOriginAllowlist returns nil when no entries are given, which allows
+// every origin.
+func NewOriginAllowlist(entries []string) *OriginAllowlist {
+	a := &OriginAllowlist{
+		origins: make(map[string]bool),
+		hosts:   make(map[string]bool),
+	}
+
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "://"):
+			a.origins[strings.TrimSuffix(entry, "/")] = true
+		case strings.HasPrefix(entry, "*."):
+			a.suffixes = append(a.suffixes, entry[1:])
+		default:
+			a.hosts[entry] = true
+		}
+	}
+
+	if len(a.origins) == 0 && len(a.hosts) == 0 && len(a.suffixes) == 0 {
+		return nil
+	}
+	return a
+}
+
+// Allows reports whether the document at rawURL is on the allowlist.
+func (a *OriginAllowlist) Allows(rawURL string) bool {
+	if a == nil {
+		return true
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if a.origins[strings.ToLower(u.Scheme+"://"+u.Host)] || a.hosts[host] {
+		return true
+	}
+	for _, suffix := range a.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check verifies the report's document URI and, for Report-To reports, the
+// envelope URL. It returns the offending host when either is not allowed.
+func (a *OriginAllowlist) Check(report *CSPReport) (string, bool) {
+	if a == nil {
+		return "", true
+	}
+
+	var documentURI string
+	if report.ParsedReport != nil {
+		documentURI = report.ParsedReport.DocumentURI
+	}
+	if !a.Allows(documentURI) {
+		return URLHost(documentURI), false
+	}
+
+	if envelopeURL := report.EnvelopeURL(); envelopeURL != "" && !a.Allows(envelopeURL) {
+		return URLHost(envelopeURL), false
+	}
+
+	return "", true
+}
+
+// EnvelopeURL returns the url field of a Report-To envelope, or an empty
+// string for other report formats.
+func (r *CSPReport) EnvelopeURL() string {
+	if reportType, _ := r.RawReport["type"].(string); reportType != "csp-violation" {
+		return ""
+	}
+	envelopeURL, _ := r.RawReport["url"].(string)
+	return envelopeURL
+}
@@ -0,0 +1,61 @@
+package models
+
+import "testing"
+
+func TestOriginAllowlist_Allows(t *testing.T) {
+	allowlist := NewOriginAllowlist([]string{"https://app.example.com", "example.org", "*.example.net", " "})
+
+	tests := map[string]bool{
+		"https://app.example.com/page":      true,
+		"https://APP.example.com/page":      true,
+		"http://app.example.com/page":       false,
+		"https://app.example.com:8443/page": false,
+		"https://example.org/":              true,
+		"http://example.org:8080/":          true,
+		"https://www.example.org/":          false,
+		"https://shop.example.net/cart":     true,
+		"https://example.net/":              false,
+		"https://evilexample.net/":          false,
+		"https://attacker.com/":             false,
+		"about:blank":                       false,
+		"":                                  false,
+	}
+
+	for uri, expected := range tests {
+		if got := allowlist.Allows(uri); got != expected {
+			t.Errorf("Expected Allows(%q) = %v, got %v", uri, expected, got)
+		}
+	}
+}
+
+func TestOriginAllowlist_Empty(t *testing.T) {
+	allowlist := NewOriginAllowlist([]string{"", " "})
+	if allowlist != nil {
+		t.Fatal("Expected nil allowlist for empty entries")
+	}
+	if !allowlist.Allows("https://anything.example/") {
+		t.Error("Expected nil allowlist to allow every origin")
+	}
+}
+
+func TestOriginAllowlist_CheckEnvelope(t *testing.T) {
+	allowlist := NewOriginAllowlist([]string{"example.com"})
+
+	report := &CSPReport{
+		RawReport: map[string]interface{}{
+			"type": "csp-violation",
+			"url":  "https://attacker.com/",
+		},
+		ParsedReport: &ParsedCSPReport{DocumentURI: "https://example.com/"},
+	}
+
+	host, ok := allowlist.Check(report)
+	if ok || host != "attacker.com" {
+		t.Errorf("Expected envelope host attacker.com to be rejected, got %q %v", host, ok)
+	}
+
+	report.RawReport["url"] = "https://example.com/"
+	if _, ok := allowlist.Check(report); !ok {
+		t.Error("Expected report to be allowed")
+	}
+}
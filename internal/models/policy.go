@@ -0,0 +1,152 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// PolicyDirective is a single directive of a parsed policy.
+type PolicyDirective struct {
+	Name    string   `json:"name"`
+	Sources []string `json:"sources"`
+}
+
+// Policy is a parsed Content-Security-Policy with directives in header order.
+type Policy struct {
+	Directives []PolicyDirective `json:"directives"`
+}
+
+// fallbackDirectives lists, per fetch directive, the directives that govern
+// it in order when it is absent from a policy, as defined by the CSP Level 3
+// directive fallback list. Every list ends at default-src.
+var fallbackDirectives = map[string][]string{
+	"script-src-elem": {"script-src", "default-src"},
+	"script-src-attr": {"script-src", "default-src"},
+	"script-src":      {"default-src"},
+	"style-src-elem":  {"style-src", "default-src"},
+	"style-src-attr":  {"style-src", "default-src"},
+	"style-src":       {"default-src"},
+	"worker-src":      {"child-src", "script-src", "default-src"},
+	"child-src":       {"default-src"},
+	"frame-src":       {"child-src", "default-src"},
+	"img-src":         {"default-src"},
+	"font-src":        {"default-src"},
+	"connect-src":     {"default-src"},
+	"media-src":       {"default-src"},
+	"object-src":      {"default-src"},
+	"manifest-src":    {"default-src"},
+	"prefetch-src":    {"default-src"},
+}
+
+// IsReportingDirective reports whether a directive only configures where
+// violations are reported.
+func IsReportingDirective(name string) bool {
+	return name == "report-uri" || name == "report-to"
+}
+
+// ParsePolicy parses a serialized policy. Directive names are lowercased and,
+// as in browsers, only the first occurrence of a directive is kept.
+func ParsePolicy(header string) *Policy {
+	policy := &Policy{}
+	for _, token := range strings.Split(header, ";") {
+		fields := strings.Fields(token)
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := strings.ToLower(fields[0])
+		if _, ok := policy.Sources(name); ok {
+			continue
+		}
+		policy.Directives = append(policy.Directives, PolicyDirective{
+			Name:    name,
+			Sources: fields[1:],
+		})
+	}
+	return policy
+}
+
+// Sources returns the source list of a directive present in the policy.
+func (p *Policy) Sources(name string) ([]string, bool) {
+	for _, directive := range p.Directives {
+		if directive.Name == name {
+			return directive.Sources, true
+		}
+	}
+	return nil, false
+}
+
+// Governing returns the directive that applies to name, following the fetch
+// directive fallback chain, and its sources. ok is false when neither the
+// directive nor any fallback is present.
+func (p *Policy) Governing(name string) (governing string, sources []string, ok bool) {
+	for _, current := range append([]string{name}, fallbackDirectives[name]...) {
+		if sources, ok := p.Sources(current); ok {
+			return current, sources, true
+		}
+	}
+	return "", nil, false
+}
+
+// HasSource reports whether a directive lists the source. Keywords and
+// schemes compare case-insensitively.
+func (p *Policy) HasSource(name, source string) bool {
+	sources, _ := p.Sources(name)
+	for _, existing := range sources {
+		if strings.EqualFold(existing, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// Set replaces the sources of a directive, appending it when absent.
+func (p *Policy) Set(name string, sources []string) {
+	for i := range p.Directives {
+		if p.Directives[i].Name == name {
+			p.Directives[i].Sources = sources
+			return
+		}
+	}
+	p.Directives = append(p.Directives, PolicyDirective{Name: name, Sources: sources})
+}
+
+// Clone returns a deep copy of the policy.
+func (p *Policy) Clone() *Policy {
+	clone := &Policy{Directives: make([]PolicyDirective, len(p.Directives))}
+	for i, directive := range p.Directives {
+		clone.Directives[i] = PolicyDirective{
+			Name:    directive.Name,
+			Sources: append([]string(nil), directive.Sources...),
+		}
+	}
+	return clone
+}
+
+// String serializes the policy in header form.
+func (p *Policy) String() string {
+	parts := make([]string, 0, len(p.Directives))
+	for _, directive := range p.Directives {
+		parts = append(parts, strings.Join(append([]string{directive.Name}, directive.Sources...), " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// PolicyFingerprint returns a stable identifier for a serialized policy, or
+// an empty string for an empty one. Whitespace, directive name case,
+// duplicate directives and the reporting directives do not change the
+// fingerprint, so a policy keeps it wherever its reports are sent.
+func PolicyFingerprint(header string) string {
+	policy := &Policy{}
+	for _, directive := range ParsePolicy(header).Directives {
+		if !IsReportingDirective(directive.Name) {
+			policy.Directives = append(policy.Directives, directive)
+		}
+	}
+	if len(policy.Directives) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(policy.String()))
+	return hex.EncodeToString(sum[:])[:fingerprintLength]
+}
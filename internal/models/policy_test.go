@@ -0,0 +1,110 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	policy := ParsePolicy("default-src 'self';  Script-Src 'self' https://cdn.example.com ; script-src 'none'; upgrade-insecure-requests;")
+
+	expected := []PolicyDirective{
+		{Name: "default-src", Sources: []string{"'self'"}},
+		{Name: "script-src", Sources: []string{"'self'", "https://cdn.example.com"}},
+		{Name: "upgrade-insecure-requests", Sources: []string{}},
+	}
+	if !reflect.DeepEqual(policy.Directives, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, policy.Directives)
+	}
+
+	serialized := "default-src 'self'; script-src 'self' https://cdn.example.com; upgrade-insecure-requests"
+	if got := policy.String(); got != serialized {
+		t.Errorf("Expected %q, got %q", serialized, got)
+	}
+}
+
+func TestPolicy_Governing(t *testing.T) {
+	policy := ParsePolicy("default-src 'self'; script-src https://cdn.example.com; child-src 'none'")
+
+	tests := []struct {
+		directive string
+		expected  string
+	}{
+		{"script-src-elem", "script-src"},
+		{"script-src", "script-src"},
+		{"img-src", "default-src"},
+		{"style-src-attr", "default-src"},
+		{"worker-src", "child-src"},
+		{"frame-src", "child-src"},
+		{"frame-ancestors", ""},
+	}
+
+	for _, tt := range tests {
+		governing, _, ok := policy.Governing(tt.directive)
+		if governing != tt.expected || ok != (tt.expected != "") {
+			t.Errorf("Expected %s to be governed by %q, got %q (%v)", tt.directive, tt.expected, governing, ok)
+		}
+	}
+}
+
+func TestPolicy_GoverningWithoutChildSrc(t *testing.T) {
+	policy := ParsePolicy("default-src 'self'; script-src 'self'")
+
+	tests := []struct {
+		directive string
+		expected  string
+	}{
+		// Frames never fall back to script-src, only workers do
+		{"frame-src", "default-src"},
+		{"child-src", "default-src"},
+		{"worker-src", "script-src"},
+		{"script-src-attr", "script-src"},
+	}
+
+	for _, tt := range tests {
+		if governing, _, _ := policy.Governing(tt.directive); governing != tt.expected {
+			t.Errorf("Expected %s to be governed by %q, got %q", tt.directive, tt.expected, governing)
+		}
+	}
+}
+
+func TestPolicy_SetAndClone(t *testing.T) {
+	policy := ParsePolicy("default-src 'self'")
+	clone := policy.Clone()
+
+	clone.Set("default-src", []string{"'none'"})
+	clone.Set("img-src", []string{"data:"})
+
+	if got := policy.String(); got != "default-src 'self'" {
+		t.Errorf("Expected original to be unchanged, got %q", got)
+	}
+	if got := clone.String(); got != "default-src 'none'; img-src data:" {
+		t.Errorf("Unexpected clone: %q", got)
+	}
+	if !clone.HasSource("img-src", "DATA:") {
+		t.Error("Expected case-insensitive source match")
+	}
+}
+
+func TestPolicyFingerprint(t *testing.T) {
+	fingerprint := PolicyFingerprint("default-src 'self'; script-src https://cdn.example.com")
+
+	if len(fingerprint) != fingerprintLength {
+		t.Errorf("Expected fingerprint of length %d, got %q", fingerprintLength, fingerprint)
+	}
+	if got := PolicyFingerprint("  DEFAULT-SRC 'self' ;script-src   https://cdn.example.com;"); got != fingerprint {
+		t.Errorf("Expected formatting not to change the fingerprint, got %q and %q", fingerprint, got)
+	}
+	if got := PolicyFingerprint("default-src 'self'; script-src https://other.example.com"); got == fingerprint {
+		t.Error("Expected a different policy to have a different fingerprint")
+	}
+	if got := PolicyFingerprint("default-src 'self'; script-src https://cdn.example.com; report-uri /csp-report; report-to csp-endpoint"); got != fingerprint {
+		t.Errorf("Expected reporting directives not to change the fingerprint, got %q and %q", fingerprint, got)
+	}
+	if got := PolicyFingerprint("report-uri /csp-report"); got != "" {
+		t.Errorf("Expected empty fingerprint for a reporting-only policy, got %q", got)
+	}
+	if got := PolicyFingerprint(" ; "); got != "" {
+		t.Errorf("Expected empty fingerprint for an empty policy, got %q", got)
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// RollupDimensions lists the report attributes rollups can be grouped by.
+var RollupDimensions = []string{"directive", "blocked_host", "document_host", "document_route", "browser_type", "disposition", "fingerprint"}
+
+// RollupOverflow is the value of every dimension of the rollup counting the
+// reports whose dimension combination did not fit in the open buckets.
+const RollupOverflow = "_other"
+
+// Rollup is the number of reports received within one time bucket for a
+// single combination of dimension values. Several rollups may exist for the
+// same bucket and dimensions; consumers sum their counts.
+type Rollup struct {
+	ID          string            `json:"id"`
+	BucketStart time.Time         `json:"bucket_start"`
+	Interval    string            `json:"interval"`
+	Dimensions  map[string]string `json:"dimensions"`
+	Count       int64             `json:"count"`
+}
+
+// IsRollupDimension reports whether name is a supported rollup dimension.
+func IsRollupDimension(name string) bool {
+	for _, dimension := range RollupDimensions {
+		if dimension == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RollupDimensionValue returns the value of a rollup dimension for a report.
+func RollupDimensionValue(report *CSPReport, dimension string) string {
+	switch dimension {
+	case "browser_type":
+		return report.BrowserType
+	case "fingerprint":
+		return report.Fingerprint
+	case "document_route":
+		return report.DocumentRoute
+	}
+
+	parsed := report.ParsedReport
+	if parsed == nil {
+		return ""
+	}
+
+	switch dimension {
+	case "directive":
+		return parsed.CanonicalDirective()
+	case "blocked_host":
+		return URLHost(parsed.BlockedURI)
+	case "document_host":
+		return URLHost(parsed.DocumentURI)
+	case "disposition":
+		return parsed.DispositionName()
+	default:
+		return ""
+	}
+}
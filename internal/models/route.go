@@ -0,0 +1,125 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// RouteRule maps the paths of matching document URIs onto a route. Pattern is
+// a route pattern where :name matches one path segment and a trailing *
+// matches the rest, e.g. /orders/:id or /static/*; the route is the pattern
+// with parameters written as {name}. Regex is matched against the path
+// instead and replaced by Route, which may reference groups as $1. Host
+// limits the rule to one document host.
+type RouteRule struct {
+	Host    string `json:"host,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Regex   string `json:"regex,omitempty"`
+	Route   string `json:"route,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Compile validates the rule and prepares it for matching.
+func (r *RouteRule) Compile() error {
+	switch {
+	case r.Pattern != "" && r.Regex != "":
+		return fmt.Errorf("route rule %q: set either pattern or regex, not both", r.Pattern+r.Regex)
+	case r.Pattern != "":
+		if !strings.HasPrefix(r.Pattern, "/") {
+			return fmt.Errorf("route rule %q: pattern must start with /", r.Pattern)
+		}
+		r.re = regexp.MustCompile(routePatternToRegex(r.Pattern))
+		r.Route = routePatternTemplate(r.Pattern)
+		return nil
+	case r.Regex != "":
+		if r.Route == "" {
+			return fmt.Errorf("route rule %q: regex requires a route", r.Regex)
+		}
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return fmt.Errorf("route rule %q: invalid regex: %w", r.Regex, err)
+		}
+		r.re = re
+		return nil
+	default:
+		return fmt.Errorf("route rule: pattern or regex is required")
+	}
+}
+
+// Apply returns the route of a document host and path, or false when the
+// rule does not match. Rules must be compiled first.
+func (r *RouteRule) Apply(host, path string) (string, bool) {
+	if r.re == nil || (r.Host != "" && !strings.EqualFold(r.Host, host)) {
+		return "", false
+	}
+
+	match := r.re.FindStringSubmatchIndex(path)
+	if match == nil {
+		return "", false
+	}
+	if r.Pattern != "" {
+		return r.Route, true
+	}
+	return string(r.re.ExpandString(nil, r.Route, path, match)), true
+}
+
+// DocumentRoute returns the host and path template of a document URI, with
+// the query string and fragment dropped and numeric, UUID and long hex path
+// segments collapsed to {id}. The first matching rule takes precedence over
+// the automatic collapsing.
+func DocumentRoute(documentURI string, rules []RouteRule) string {
+	if len(rules) > 0 {
+		if u, err := url.Parse(documentURI); err == nil {
+			// Rules match the host without its port, like document_host,
+			// while the route keeps the port as the automatic one does
+			hostname := strings.ToLower(u.Hostname())
+			path := u.Path
+			if path == "" {
+				path = "/"
+			}
+			for i := range rules {
+				if route, ok := rules[i].Apply(hostname, path); ok {
+					return strings.ToLower(u.Host) + route
+				}
+			}
+		}
+	}
+	return templatePath(documentURI)
+}
+
+// routePatternToRegex converts a route pattern into an anchored regular
+// expression, ignoring a trailing slash.
+func routePatternToRegex(pattern string) string {
+	segments := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+	var b strings.Builder
+	b.WriteString("^")
+	for i, segment := range segments {
+		if i > 0 {
+			b.WriteString("/")
+		}
+		switch {
+		case segment == "*" && i == len(segments)-1:
+			b.WriteString(".*")
+		case strings.HasPrefix(segment, ":"):
+			b.WriteString("[^/]+")
+		default:
+			b.WriteString(regexp.QuoteMeta(segment))
+		}
+	}
+	b.WriteString("/?$")
+	return b.String()
+}
+
+// routePatternTemplate writes the parameters of a route pattern as {name}.
+func routePatternTemplate(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") && len(segment) > 1 {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
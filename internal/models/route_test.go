@@ -0,0 +1,85 @@
+package models
+
+import "testing"
+
+func TestDocumentRoute(t *testing.T) {
+	rules := []RouteRule{
+		{Pattern: "/orders/:order/items/:item"},
+		{Pattern: "/static/*"},
+		{Host: "blog.example.com", Regex: `^/(\d{4})/[^/]+$`, Route: "/$1/{slug}"},
+	}
+	for i := range rules {
+		if err := rules[i].Compile(); err != nil {
+			t.Fatalf("Failed to compile rule %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		documentURI string
+		expected    string
+	}{
+		{"route pattern", "https://Shop.example.com/orders/A-12/items/7?token=secret", "shop.example.com/orders/{order}/items/{item}"},
+		{"trailing slash", "https://shop.example.com/orders/A-12/items/7/", "shop.example.com/orders/{order}/items/{item}"},
+		{"wildcard", "https://shop.example.com/static/js/app.js", "shop.example.com/static/*"},
+		{"regex with host", "https://blog.example.com/2024/hello-world", "blog.example.com/2024/{slug}"},
+		{"regex with host and port", "https://blog.example.com:8443/2024/hello-world", "blog.example.com:8443/2024/{slug}"},
+		{"regex on other host", "https://shop.example.com/2024/hello-world", "shop.example.com/{id}/hello-world"},
+		{"automatic collapsing", "https://shop.example.com/users/123456/profile#tab", "shop.example.com/users/{id}/profile"},
+		{"uuid segment", "https://shop.example.com/carts/9f1b8a52-3c4d-4e5f-8a9b-0c1d2e3f4a5b", "shop.example.com/carts/{id}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DocumentRoute(tt.documentURI, rules); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRouteRule_CompileErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule RouteRule
+	}{
+		{"empty", RouteRule{}},
+		{"pattern and regex", RouteRule{Pattern: "/a", Regex: "^/a$", Route: "/a"}},
+		{"relative pattern", RouteRule{Pattern: "orders/:id"}},
+		{"regex without route", RouteRule{Regex: "^/a$"}},
+		{"invalid regex", RouteRule{Regex: "(", Route: "/a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Compile(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestCSPReport_ApplyRoutes(t *testing.T) {
+	first := &CSPReport{ParsedReport: &ParsedCSPReport{DocumentURI: "https://shop.example.com/p/red-shoes", ViolatedDirective: "img-src", BlockedURI: "https://evil.com/x.png"}}
+	second := &CSPReport{ParsedReport: &ParsedCSPReport{DocumentURI: "https://shop.example.com/p/blue-hat", ViolatedDirective: "img-src", BlockedURI: "https://evil.com/x.png"}}
+
+	first.ApplyRoutes(nil)
+	second.ApplyRoutes(nil)
+	if first.Fingerprint == second.Fingerprint {
+		t.Fatal("Expected distinct fingerprints without route rules")
+	}
+
+	rules := []RouteRule{{Pattern: "/p/:slug"}}
+	if err := rules[0].Compile(); err != nil {
+		t.Fatalf("Failed to compile rule: %v", err)
+	}
+	first.ApplyRoutes(rules)
+	second.ApplyRoutes(rules)
+	if first.DocumentRoute != "shop.example.com/p/{slug}" || first.Fingerprint != second.Fingerprint {
+		t.Errorf("Expected both pages on route shop.example.com/p/{slug} with one fingerprint, got %q/%q and %q/%q",
+			first.DocumentRoute, first.Fingerprint, second.DocumentRoute, second.Fingerprint)
+	}
+	if first.FingerprintInput().DocumentPath != first.DocumentRoute {
+		t.Errorf("Expected the fingerprint input to use the route, got %+v", first.FingerprintInput())
+	}
+}
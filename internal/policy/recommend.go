@@ -0,0 +1,335 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"universal-csp-report/internal/models"
+	"universal-csp-report/internal/storage"
+)
+
+const (
+	// collectPageSize is the page size used when reading stored reports.
+	collectPageSize = 500
+
+	// strictDynamicThreshold is the number of script origins to allowlist from
+	// which 'strict-dynamic' is suggested instead.
+	strictDynamicThreshold = 5
+)
+
+// Source is a source expression the recommended policy adds to a directive,
+// with the number of reports it would have allowed.
+type Source struct {
+	Directive string `json:"directive"`
+	Source    string `json:"source"`
+	Evidence  int64  `json:"evidence"`
+}
+
+// Flag is a keyword the observed violations call for but that is not added
+// automatically because it weakens the policy, or a structural suggestion.
+type Flag struct {
+	Directive string `json:"directive"`
+	Source    string `json:"source"`
+	Evidence  int64  `json:"evidence"`
+	Reason    string `json:"reason"`
+}
+
+// Recommendation is the proposed policy for one document host. It only
+// relaxes the base policy: reports show what was blocked, never what a source
+// allowed, so they cannot justify removing a source.
+type Recommendation struct {
+	DocumentHost string   `json:"document_host"`
+	Reports      int64    `json:"reports"`
+	BasePolicy   string   `json:"base_policy,omitempty"`
+	Policy       string   `json:"policy"`
+	Additions    []Source `json:"additions"`
+	Flags        []Flag   `json:"flags,omitempty"`
+}
+
+type evidenceKey struct {
+	directive string
+	source    string
+}
+
+type hostEvidence struct {
+	reports  int64
+	origin   string
+	policies map[string]int64
+	sources  map[evidenceKey]int64
+	flags    map[evidenceKey]int64
+}
+
+// Recommender accumulates violations per document host and proposes the
+// policy that would have allowed them. Evidence counts reports rather than
+// stored documents, so deduplicated and sampled reports are weighted back up.
+type Recommender struct {
+	hosts map[string]*hostEvidence
+}
+
+func NewRecommender() *Recommender {
+	return &Recommender{hosts: make(map[string]*hostEvidence)}
+}
+
+// Add records a report. Downgraded reports are noise and are ignored.
+func (r *Recommender) Add(report *models.CSPReport) {
+	parsed := report.ParsedReport
+	if parsed == nil || report.Downgraded {
+		return
+	}
+
+	host := models.URLHost(parsed.DocumentURI)
+	directive := parsed.DirectiveName()
+	if host == "" || directive == "" {
+		return
+	}
+
+	evidence, ok := r.hosts[host]
+	if !ok {
+		evidence = &hostEvidence{
+			origin:   models.BlockedOrigin(parsed.DocumentURI),
+			policies: make(map[string]int64),
+			sources:  make(map[evidenceKey]int64),
+			flags:    make(map[evidenceKey]int64),
+		}
+		r.hosts[host] = evidence
+	}
+
+	weight := int64(math.Round(report.Weight()))
+	evidence.reports += weight
+	if parsed.OriginalPolicy != "" {
+		evidence.policies[parsed.OriginalPolicy] += weight
+	}
+
+	source, flag := sourceFor(report, directive, evidence.origin)
+	if source != "" {
+		evidence.sources[evidenceKey{directive, source}] += weight
+	}
+	if flag != "" {
+		evidence.flags[evidenceKey{directive, flag}] += weight
+	}
+}
+
+// Collect feeds stored reports matching query into the recommender, reading
+// at most maxReports. It reports whether the limit cut the results short.
+func (r *Recommender) Collect(ctx context.Context, store storage.QueryStorage, query storage.ReportQuery, maxReports int) (bool, error) {
+	query.Size = collectPageSize
+	read := 0
+
+	for {
+		page, err := store.QueryReports(ctx, query)
+		if err != nil {
+			return false, fmt.Errorf("failed to read reports: %w", err)
+		}
+
+		for _, report := range page.Reports {
+			if read >= maxReports {
+				return true, nil
+			}
+			r.Add(report)
+			read++
+		}
+
+		if page.Next == "" {
+			return false, nil
+		}
+		query.Cursor = page.Next
+	}
+}
+
+// Recommend proposes a policy per document host, busiest host first. Sources
+// and flags seen in fewer than minEvidence reports are left out.
+func (r *Recommender) Recommend(minEvidence int64) []Recommendation {
+	recommendations := make([]Recommendation, 0, len(r.hosts))
+	for host, evidence := range r.hosts {
+		recommendations = append(recommendations, evidence.recommend(host, minEvidence))
+	}
+
+	sort.Slice(recommendations, func(i, j int) bool {
+		if recommendations[i].Reports != recommendations[j].Reports {
+			return recommendations[i].Reports > recommendations[j].Reports
+		}
+		return recommendations[i].DocumentHost < recommendations[j].DocumentHost
+	})
+	return recommendations
+}
+
+func (e *hostEvidence) recommend(host string, minEvidence int64) Recommendation {
+	basePolicy := e.basePolicy()
+	base := models.ParsePolicy(basePolicy)
+	recommended := base.Clone()
+
+	// Reports for script-src-elem and script-src can land in the same
+	// directive, so evidence is merged per target first
+	additions := make(map[evidenceKey]int64)
+	for key, count := range e.sources {
+		additions[evidenceKey{targetDirective(base, key.directive), key.source}] += count
+	}
+
+	recommendation := Recommendation{
+		DocumentHost: host,
+		Reports:      e.reports,
+		BasePolicy:   basePolicy,
+		Additions:    []Source{},
+	}
+
+	var scriptOrigins, scriptEvidence int64
+	for _, addition := range sortedEvidence(additions) {
+		if addition.Evidence < minEvidence {
+			continue
+		}
+
+		sources, ok := recommended.Sources(addition.Directive)
+		if !ok {
+			// A new fetch directive starts from the default-src it replaces
+			_, sources, _ = base.Governing(addition.Directive)
+			sources = append([]string(nil), sources...)
+		}
+		if containsSource(sources, addition.Source) {
+			continue
+		}
+		recommended.Set(addition.Directive, append(withoutNone(sources), addition.Source))
+		recommendation.Additions = append(recommendation.Additions, addition)
+
+		if strings.HasPrefix(addition.Directive, "script-src") && strings.Contains(addition.Source, "://") {
+			scriptOrigins++
+			scriptEvidence += addition.Evidence
+		}
+	}
+
+	flags := make(map[evidenceKey]int64)
+	for key, count := range e.flags {
+		flags[evidenceKey{targetDirective(base, key.directive), key.source}] += count
+	}
+	for _, flag := range sortedEvidence(flags) {
+		if flag.Evidence < minEvidence || recommended.HasSource(flag.Directive, flag.Source) {
+			continue
+		}
+		recommendation.Flags = append(recommendation.Flags, Flag{
+			Directive: flag.Directive,
+			Source:    flag.Source,
+			Evidence:  flag.Evidence,
+			Reason:    flagReasons[flag.Source],
+		})
+	}
+
+	if scriptOrigins >= strictDynamicThreshold {
+		recommendation.Flags = append(recommendation.Flags, Flag{
+			Directive: "script-src",
+			Source:    "'strict-dynamic'",
+			Evidence:  scriptEvidence,
+			Reason:    fmt.Sprintf("%d script origins need allowlisting; nonces or hashes with 'strict-dynamic' are easier to maintain", scriptOrigins),
+		})
+	}
+
+	recommendation.Policy = recommended.String()
+	return recommendation
+}
+
+// basePolicy returns the most common original policy, the one the violations
+// were reported against.
+func (e *hostEvidence) basePolicy() string {
+	var best string
+	var bestCount int64
+	for policy, count := range e.policies {
+		if count > bestCount || (count == bestCount && policy < best) {
+			best, bestCount = policy, count
+		}
+	}
+	return best
+}
+
+var flagReasons = map[string]string{
+	"'unsafe-inline'":    "inline code without a reported or computable hash; add hashes or nonces rather than allowing all inline code",
+	"'unsafe-eval'":      "eval() or similar string-to-code calls are blocked",
+	"'wasm-unsafe-eval'": "WebAssembly compilation is blocked",
+	"'unsafe-hashes'":    "hashes only apply to event handler and style attributes together with 'unsafe-hashes'",
+}
+
+// sourceFor maps a violation to the source expression that would allow it,
+// or to a keyword flag for inline code and eval.
+func sourceFor(report *models.CSPReport, directive, documentOrigin string) (source, flag string) {
+	blocked := models.BlockedOrigin(report.ParsedReport.BlockedURI)
+
+	switch blocked {
+	case "inline":
+		hash, _ := report.InlineHash()
+		if hash == "" {
+			return "", "'unsafe-inline'"
+		}
+		if strings.HasSuffix(directive, "-attr") {
+			return hash, "'unsafe-hashes'"
+		}
+		return hash, ""
+	case "eval":
+		return "", "'unsafe-eval'"
+	case "wasm-eval":
+		return "", "'wasm-unsafe-eval'"
+	case "data", "blob", "mediastream", "filesystem":
+		return blocked + ":", ""
+	}
+
+	if !strings.Contains(blocked, "://") {
+		return "", ""
+	}
+	if blocked == documentOrigin {
+		return "'self'", ""
+	}
+	return blocked, ""
+}
+
+// targetDirective returns the directive a source should be added to: the one
+// governing the violated directive, except that default-src is never widened
+// and a specific fetch directive is added instead. Without a governing
+// directive, element and attribute sub-directives go to their parent.
+func targetDirective(base *models.Policy, directive string) string {
+	governing, _, ok := base.Governing(directive)
+	if !ok {
+		directive = strings.TrimSuffix(directive, "-elem")
+		return strings.TrimSuffix(directive, "-attr")
+	}
+	if governing == "default-src" {
+		return directive
+	}
+	return governing
+}
+
+func withoutNone(sources []string) []string {
+	kept := sources[:0:0]
+	for _, source := range sources {
+		if !strings.EqualFold(source, "'none'") {
+			kept = append(kept, source)
+		}
+	}
+	return kept
+}
+
+// sortedEvidence orders evidence by directive, then by descending count.
+func sortedEvidence(evidence map[evidenceKey]int64) []Source {
+	sources := make([]Source, 0, len(evidence))
+	for key, count := range evidence {
+		sources = append(sources, Source{Directive: key.directive, Source: key.source, Evidence: count})
+	}
+
+	sort.Slice(sources, func(i, j int) bool {
+		if sources[i].Directive != sources[j].Directive {
+			return sources[i].Directive < sources[j].Directive
+		}
+		if sources[i].Evidence != sources[j].Evidence {
+			return sources[i].Evidence > sources[j].Evidence
+		}
+		return sources[i].Source < sources[j].Source
+	})
+	return sources
+}
+
+func containsSource(sources []string, source string) bool {
+	for _, existing := range sources {
+		if strings.EqualFold(existing, source) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package policy
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"universal-csp-report/internal/models"
+	"universal-csp-report/internal/storage"
+)
+
+const testPolicy = "default-src 'self'; script-src 'self'; img-src 'none'"
+
+func newViolation(documentURI, directive, blockedURI string) *models.CSPReport {
+	return &models.CSPReport{
+		ParsedReport: &models.ParsedCSPReport{
+			DocumentURI:        documentURI,
+			EffectiveDirective: directive,
+			BlockedURI:         blockedURI,
+			OriginalPolicy:     testPolicy,
+		},
+	}
+}
+
+func TestRecommender_Recommend(t *testing.T) {
+	recommender := NewRecommender()
+
+	add := func(report *models.CSPReport, count int) {
+		for i := 0; i < count; i++ {
+			recommender.Add(report)
+		}
+	}
+
+	add(newViolation("https://shop.example.com/cart", "script-src-elem", "https://cdn.example.net/lib.js"), 3)
+	add(newViolation("https://shop.example.com/", "script-src", "https://cdn.example.net/other.js"), 1)
+	add(newViolation("https://shop.example.com/", "img-src", "data:image/png;base64,AAAA"), 2)
+	add(newViolation("https://shop.example.com/", "font-src", "https://fonts.example.org/a.woff2"), 1)
+	add(newViolation("https://shop.example.com/", "script-src", "eval"), 2)
+	add(newViolation("https://shop.example.com/", "script-src-elem", "inline"), 1)
+
+	hashed := newViolation("https://shop.example.com/", "script-src-elem", "inline")
+	hashed.ParsedReport.SHA256 = "sha256-abc="
+	add(hashed, 2)
+
+	noise := newViolation("https://shop.example.com/", "script-src", "https://noise.example/x.js")
+	noise.Downgraded = true
+	add(noise, 5)
+
+	add(newViolation("https://blog.example.com/", "style-src", "https://fonts.example.org/css?family=Sans"), 1)
+
+	recommendations := recommender.Recommend(1)
+	if len(recommendations) != 2 {
+		t.Fatalf("Expected 2 recommendations, got %d", len(recommendations))
+	}
+
+	shop := recommendations[0]
+	if shop.DocumentHost != "shop.example.com" || shop.Reports != 12 {
+		t.Errorf("Expected shop.example.com with 12 reports first, got %s with %d", shop.DocumentHost, shop.Reports)
+	}
+	if shop.BasePolicy != testPolicy {
+		t.Errorf("Expected base policy %q, got %q", testPolicy, shop.BasePolicy)
+	}
+
+	expectedAdditions := []Source{
+		{Directive: "font-src", Source: "https://fonts.example.org", Evidence: 1},
+		{Directive: "img-src", Source: "data:", Evidence: 2},
+		{Directive: "script-src", Source: "https://cdn.example.net", Evidence: 4},
+		{Directive: "script-src", Source: "'sha256-abc='", Evidence: 2},
+	}
+	if !reflect.DeepEqual(shop.Additions, expectedAdditions) {
+		t.Errorf("Expected additions %+v, got %+v", expectedAdditions, shop.Additions)
+	}
+
+	expectedPolicy := "default-src 'self'; script-src 'self' https://cdn.example.net 'sha256-abc='; img-src data:; font-src 'self' https://fonts.example.org"
+	if shop.Policy != expectedPolicy {
+		t.Errorf("Expected policy %q, got %q", expectedPolicy, shop.Policy)
+	}
+
+	if len(shop.Flags) != 2 {
+		t.Fatalf("Expected 2 flags, got %+v", shop.Flags)
+	}
+	if shop.Flags[0].Source != "'unsafe-eval'" || shop.Flags[0].Evidence != 2 {
+		t.Errorf("Expected 'unsafe-eval' flag with 2 reports, got %+v", shop.Flags[0])
+	}
+	if shop.Flags[1].Source != "'unsafe-inline'" || shop.Flags[1].Evidence != 1 {
+		t.Errorf("Expected 'unsafe-inline' flag with 1 report, got %+v", shop.Flags[1])
+	}
+
+	blog := recommendations[1]
+	if blog.Policy != testPolicy+"; style-src 'self' https://fonts.example.org" {
+		t.Errorf("Expected style-src to inherit default-src, got %q", blog.Policy)
+	}
+}
+
+func TestRecommender_WeightsCollapsedAndSampledReports(t *testing.T) {
+	recommender := NewRecommender()
+
+	collapsed := newViolation("https://shop.example.com/", "script-src", "https://cdn.example.net/lib.js")
+	collapsed.Occurrences = 3
+	recommender.Add(collapsed)
+
+	sampled := newViolation("https://shop.example.com/", "script-src", "eval")
+	sampled.SampleRate = 0.25
+	recommender.Add(sampled)
+
+	recommendation := recommender.Recommend(1)[0]
+	if recommendation.Reports != 7 {
+		t.Errorf("Expected 7 reports, got %d", recommendation.Reports)
+	}
+	if len(recommendation.Additions) != 1 || recommendation.Additions[0].Evidence != 3 {
+		t.Errorf("Expected the collapsed report to count 3 times, got %+v", recommendation.Additions)
+	}
+	if len(recommendation.Flags) != 1 || recommendation.Flags[0].Evidence != 4 {
+		t.Errorf("Expected the sampled report to count 4 times, got %+v", recommendation.Flags)
+	}
+}
+
+func TestRecommender_FramesDoNotWidenScriptSrc(t *testing.T) {
+	recommender := NewRecommender()
+	recommender.Add(newViolation("https://shop.example.com/", "frame-src", "https://www.youtube.com/embed/x"))
+
+	recommendation := recommender.Recommend(1)[0]
+	expected := []Source{{Directive: "frame-src", Source: "https://www.youtube.com", Evidence: 1}}
+	if !reflect.DeepEqual(recommendation.Additions, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, recommendation.Additions)
+	}
+	if policy := models.ParsePolicy(recommendation.Policy); policy.HasSource("script-src", "https://www.youtube.com") {
+		t.Errorf("Expected script-src to be left alone, got %q", recommendation.Policy)
+	}
+}
+
+func TestRecommender_MinEvidence(t *testing.T) {
+	recommender := NewRecommender()
+	recommender.Add(newViolation("https://shop.example.com/", "script-src", "https://rare.example/x.js"))
+	recommender.Add(newViolation("https://shop.example.com/", "script-src", "eval"))
+
+	recommendations := recommender.Recommend(2)
+	if len(recommendations[0].Additions) != 0 || len(recommendations[0].Flags) != 0 {
+		t.Errorf("Expected no additions or flags below the minimum evidence, got %+v", recommendations[0])
+	}
+	if recommendations[0].Policy != testPolicy {
+		t.Errorf("Expected the base policy unchanged, got %q", recommendations[0].Policy)
+	}
+}
+
+func TestRecommender_StrictDynamic(t *testing.T) {
+	recommender := NewRecommender()
+	for _, origin := range []string{"https://a.example", "https://b.example", "https://c.example", "https://d.example", "https://e.example"} {
+		recommender.Add(newViolation("https://shop.example.com/", "script-src-elem", origin+"/x.js"))
+	}
+
+	flags := recommender.Recommend(1)[0].Flags
+	if len(flags) != 1 || flags[0].Source != "'strict-dynamic'" || flags[0].Evidence != 5 {
+		t.Errorf("Expected a 'strict-dynamic' suggestion, got %+v", flags)
+	}
+}
+
+func TestSourceFor(t *testing.T) {
+	tests := []struct {
+		directive  string
+		blockedURI string
+		sha256     string
+		source     string
+		flag       string
+	}{
+		{"script-src", "https://cdn.example.net/x.js?v=1", "", "https://cdn.example.net", ""},
+		{"script-src", "https://shop.example.com/x.js", "", "'self'", ""},
+		{"connect-src", "wss://live.example.net/socket", "", "wss://live.example.net", ""},
+		{"img-src", "blob", "", "blob:", ""},
+		{"script-src", "inline", "", "", "'unsafe-inline'"},
+		{"script-src", "inline", "abc=", "'sha256-abc='", ""},
+		{"script-src-attr", "inline", "sha256-abc=", "'sha256-abc='", "'unsafe-hashes'"},
+		{"script-src", "eval", "", "", "'unsafe-eval'"},
+		{"script-src", "wasm-eval", "", "", "'wasm-unsafe-eval'"},
+		{"require-trusted-types-for", "trusted-types-sink", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		report := &models.CSPReport{ParsedReport: &models.ParsedCSPReport{BlockedURI: tt.blockedURI, SHA256: tt.sha256}}
+		source, flag := sourceFor(report, tt.directive, "https://shop.example.com")
+		if source != tt.source || flag != tt.flag {
+			t.Errorf("Expected %s/%s to give %q and %q, got %q and %q", tt.directive, tt.blockedURI, tt.source, tt.flag, source, flag)
+		}
+	}
+}
+
+func TestSourceFor_ComputedHash(t *testing.T) {
+	report := &models.CSPReport{ParsedReport: &models.ParsedCSPReport{BlockedURI: "inline", ScriptSample: "alert(1)"}}
+	source, flag := sourceFor(report, "script-src-elem", "https://shop.example.com")
+	if source != "'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='" || flag != "" {
+		t.Errorf("Expected the hash of the complete sample, got %q and %q", source, flag)
+	}
+
+	// A redacted sample no longer hashes to the inline code
+	report.Redactions = []models.Redaction{{Field: models.ScriptSampleField, Rule: "sample_length", Count: 1}}
+	source, flag = sourceFor(report, "script-src-elem", "https://shop.example.com")
+	if source != "" || flag != "'unsafe-inline'" {
+		t.Errorf("Expected no hash for a redacted sample, got %q and %q", source, flag)
+	}
+}
+
+type pagedStorage struct {
+	pages   []*storage.ReportPage
+	queries []storage.ReportQuery
+}
+
+func (s *pagedStorage) QueryReports(ctx context.Context, query storage.ReportQuery) (*storage.ReportPage, error) {
+	s.queries = append(s.queries, query)
+	return s.pages[len(s.queries)-1], nil
+}
+
+func TestRecommender_Collect(t *testing.T) {
+	report := newViolation("https://shop.example.com/", "script-src", "https://cdn.example.net/x.js")
+	store := &pagedStorage{pages: []*storage.ReportPage{
+		{Reports: []*models.CSPReport{report, report}, Next: "page2"},
+		{Reports: []*models.CSPReport{report, report}, Next: "page3"},
+	}}
+
+	recommender := NewRecommender()
+	truncated, err := recommender.Collect(context.Background(), store, storage.ReportQuery{DocumentHost: "shop.example.com"}, 3)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if !truncated {
+		t.Error("Expected results to be truncated")
+	}
+	if len(store.queries) != 2 || store.queries[1].Cursor != "page2" || store.queries[0].DocumentHost != "shop.example.com" {
+		t.Errorf("Unexpected queries: %+v", store.queries)
+	}
+	if reports := recommender.Recommend(1)[0].Reports; reports != 3 {
+		t.Errorf("Expected 3 reports, got %d", reports)
+	}
+}
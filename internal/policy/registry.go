@@ -0,0 +1,441 @@
+package policy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+// ReportingEndpoint is the Reporting API group name used in emitted headers.
+const ReportingEndpoint = "csp-endpoint"
+
+var (
+	ErrPolicyNotFound  = errors.New("policy not found")
+	ErrVersionNotFound = errors.New("policy version not found")
+	ErrInvalidPolicy   = errors.New("invalid policy")
+)
+
+// policyNamePattern keeps policy names safe for use in URLs.
+var policyNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// PolicyVersion is one immutable revision of a registered policy. The policy
+// is stored without reporting directives, which are added when the header is
+// emitted.
+type PolicyVersion struct {
+	Version     int       `json:"version"`
+	Policy      string    `json:"policy"`
+	Fingerprint string    `json:"fingerprint"`
+	ReportOnly  bool      `json:"report_only"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RegisteredPolicy is a named policy for a site. Active is the version served
+// in headers; zero means none.
+type RegisteredPolicy struct {
+	Name     string          `json:"name"`
+	Site     string          `json:"site"`
+	Project  string          `json:"project,omitempty"`
+	Active   int             `json:"active"`
+	Versions []PolicyVersion `json:"versions"`
+}
+
+// NewVersion describes a policy revision to register.
+type NewVersion struct {
+	Site       string
+	Project    string
+	Policy     string
+	ReportOnly bool
+	Activate   bool
+}
+
+// Registry stores named, versioned policies in a JSON file. Every change is
+// written through to the file.
+type Registry struct {
+	mu       sync.RWMutex
+	path     string
+	policies map[string]*RegisteredPolicy
+	// byFingerprint indexes every version by policy fingerprint, newest
+	// first, so reports can be linked without scanning the registry
+	byFingerprint map[string][]versionRef
+}
+
+// versionRef is a registered version as found by Lookup.
+type versionRef struct {
+	name    string
+	version int
+	site    string
+	project string
+	created time.Time
+}
+
+type registryFile struct {
+	Policies []*RegisteredPolicy `json:"policies"`
+}
+
+// LoadRegistry reads the registry from path. A missing file yields an empty
+// registry that is created on the first change.
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path, policies: make(map[string]*RegisteredPolicy)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy registry: %w", err)
+	}
+
+	var file registryFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse policy registry: %w", err)
+	}
+	for _, policy := range file.Policies {
+		if !policyNamePattern.MatchString(policy.Name) {
+			return nil, fmt.Errorf("invalid policy name %q", policy.Name)
+		}
+		if _, ok := r.policies[policy.Name]; ok {
+			return nil, fmt.Errorf("duplicate policy name %q", policy.Name)
+		}
+		r.policies[policy.Name] = policy
+	}
+	r.reindex()
+	return r, nil
+}
+
+// AddVersion registers a new version of the named policy, creating the policy
+// when it does not exist yet. The first version is always activated.
+func (r *Registry) AddVersion(name string, v NewVersion) (RegisteredPolicy, error) {
+	if !policyNamePattern.MatchString(name) {
+		return RegisteredPolicy{}, fmt.Errorf("%w: invalid name %q", ErrInvalidPolicy, name)
+	}
+
+	parsed := stripReporting(models.ParsePolicy(v.Policy))
+	if len(parsed.Directives) == 0 {
+		return RegisteredPolicy{}, fmt.Errorf("%w: no directives", ErrInvalidPolicy)
+	}
+	header := parsed.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	policy, ok := r.policies[name]
+	if !ok {
+		if v.Site == "" {
+			return RegisteredPolicy{}, fmt.Errorf("%w: site is required", ErrInvalidPolicy)
+		}
+		policy = &RegisteredPolicy{Name: name}
+	}
+	previousSite, previousProject := policy.Site, policy.Project
+	if v.Site != "" {
+		policy.Site = v.Site
+	}
+	if v.Project != "" {
+		policy.Project = v.Project
+	}
+
+	version := PolicyVersion{
+		Version:     len(policy.Versions) + 1,
+		Policy:      header,
+		Fingerprint: models.PolicyFingerprint(header),
+		ReportOnly:  v.ReportOnly,
+		CreatedAt:   time.Now().UTC(),
+	}
+	policy.Versions = append(policy.Versions, version)
+	previous := policy.Active
+	if v.Activate || policy.Active == 0 {
+		policy.Active = version.Version
+	}
+
+	r.policies[name] = policy
+	if err := r.save(); err != nil {
+		policy.Versions = policy.Versions[:len(policy.Versions)-1]
+		policy.Active = previous
+		policy.Site, policy.Project = previousSite, previousProject
+		if len(policy.Versions) == 0 {
+			delete(r.policies, name)
+		}
+		return RegisteredPolicy{}, err
+	}
+	return policy.clone(), nil
+}
+
+// Activate makes version the one served for the named policy.
+func (r *Registry) Activate(name string, version int) (RegisteredPolicy, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	policy, ok := r.policies[name]
+	if !ok {
+		return RegisteredPolicy{}, ErrPolicyNotFound
+	}
+	if version < 1 || version > len(policy.Versions) {
+		return RegisteredPolicy{}, ErrVersionNotFound
+	}
+
+	previous := policy.Active
+	policy.Active = version
+	if err := r.save(); err != nil {
+		policy.Active = previous
+		return RegisteredPolicy{}, err
+	}
+	return policy.clone(), nil
+}
+
+// Delete removes a policy and all of its versions.
+func (r *Registry) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	policy, ok := r.policies[name]
+	if !ok {
+		return ErrPolicyNotFound
+	}
+
+	delete(r.policies, name)
+	if err := r.save(); err != nil {
+		r.policies[name] = policy
+		return err
+	}
+	return nil
+}
+
+// Get returns the named policy with all of its versions.
+func (r *Registry) Get(name string) (RegisteredPolicy, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policy, ok := r.policies[name]
+	if !ok {
+		return RegisteredPolicy{}, false
+	}
+	return policy.clone(), true
+}
+
+// List returns all policies ordered by name. A non-empty site only returns
+// the policies of that site.
+func (r *Registry) List(site string) []RegisteredPolicy {
+	r.mu.RLock()
+	policies := make([]RegisteredPolicy, 0, len(r.policies))
+	for _, policy := range r.policies {
+		if site != "" && policy.Site != site {
+			continue
+		}
+		policies = append(policies, policy.clone())
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Name < policies[j].Name
+	})
+	return policies
+}
+
+// Lookup finds the registered version whose policy has the given
+// fingerprint, for a report from documentHost sent to project. Policies of
+// another project are skipped. When several versions share a policy, one
+// whose site matches the document host is preferred, then the most recently
+// created one.
+func (r *Registry) Lookup(fingerprint, documentHost, project string) (name string, version int, ok bool) {
+	if fingerprint == "" {
+		return "", 0, false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var fallback *versionRef
+	for i, ref := range r.byFingerprint[fingerprint] {
+		if project != "" && ref.project != "" && ref.project != project {
+			continue
+		}
+		if siteMatches(ref.site, documentHost) {
+			return ref.name, ref.version, true
+		}
+		if fallback == nil {
+			fallback = &r.byFingerprint[fingerprint][i]
+		}
+	}
+	if fallback == nil {
+		return "", 0, false
+	}
+	return fallback.name, fallback.version, true
+}
+
+// siteMatches reports whether host is the site or one of its subdomains.
+func siteMatches(site, host string) bool {
+	site = strings.ToLower(site)
+	host = strings.ToLower(host)
+	return site != "" && (host == site || strings.HasSuffix(host, "."+site))
+}
+
+// Headers returns the response headers serving the active version of the
+// named policy, with reports sent to reportURL.
+func (r *Registry) Headers(name, reportURL string) (http.Header, PolicyVersion, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	policy, ok := r.policies[name]
+	if !ok {
+		return nil, PolicyVersion{}, ErrPolicyNotFound
+	}
+	version, ok := policy.ActiveVersion()
+	if !ok {
+		return nil, PolicyVersion{}, ErrVersionNotFound
+	}
+	return version.Headers(reportURL), version, nil
+}
+
+// Middleware sets the headers of the named policy's active version on every
+// response, so Go services can serve registered policies directly. Requests
+// are passed through unchanged when the policy is missing.
+func (r *Registry) Middleware(name, reportURL string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if headers, _, err := r.Headers(name, reportURL); err == nil {
+				for key, values := range headers {
+					w.Header()[key] = values
+				}
+			}
+			next.ServeHTTP(w, req)
+		})
+	}
+}
+
+// ActiveVersion returns the version served in headers.
+func (p *RegisteredPolicy) ActiveVersion() (PolicyVersion, bool) {
+	return p.Version(p.Active)
+}
+
+// Version returns a version by number.
+func (p *RegisteredPolicy) Version(version int) (PolicyVersion, bool) {
+	if version < 1 || version > len(p.Versions) {
+		return PolicyVersion{}, false
+	}
+	return p.Versions[version-1], true
+}
+
+// HeaderName returns the header the version is served in.
+func (v PolicyVersion) HeaderName() string {
+	if v.ReportOnly {
+		return "Content-Security-Policy-Report-Only"
+	}
+	return "Content-Security-Policy"
+}
+
+// Headers returns the policy header, reporting to reportURL through both
+// report-uri and the Reporting API, and the matching Reporting-Endpoints
+// header. The fingerprint of the emitted policy matches the version's.
+func (v PolicyVersion) Headers(reportURL string) http.Header {
+	headers := make(http.Header)
+	value := v.Policy
+	if reportURL != "" {
+		value += "; report-uri " + reportURL + "; report-to " + ReportingEndpoint
+		headers.Set("Reporting-Endpoints", ReportingEndpoint+`="`+reportURL+`"`)
+	}
+	headers.Set(v.HeaderName(), value)
+	return headers
+}
+
+// ReportURL returns the collector URL reports should be sent to. With a
+// project and key the project endpoint is used.
+func ReportURL(baseURL, project, key string) string {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if project != "" && key != "" {
+		return baseURL + "/report/" + project + "/" + key
+	}
+	return baseURL + "/csp-report"
+}
+
+// stripReporting removes the reporting directives, which point at the
+// collector rather than being part of the policy itself.
+func stripReporting(policy *models.Policy) *models.Policy {
+	stripped := &models.Policy{}
+	for _, directive := range policy.Directives {
+		if models.IsReportingDirective(directive.Name) {
+			continue
+		}
+		stripped.Directives = append(stripped.Directives, directive)
+	}
+	return stripped
+}
+
+// save writes the registry to a temporary file and renames it over the
+// previous one. The caller must hold the write lock.
+func (r *Registry) save() error {
+	file := registryFile{Policies: make([]*RegisteredPolicy, 0, len(r.policies))}
+	for _, policy := range r.policies {
+		file.Policies = append(file.Policies, policy)
+	}
+	sort.Slice(file.Policies, func(i, j int) bool {
+		return file.Policies[i].Name < file.Policies[j].Name
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode policy registry: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write policy registry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write policy registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write policy registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		return fmt.Errorf("failed to write policy registry: %w", err)
+	}
+	r.reindex()
+	return nil
+}
+
+// reindex rebuilds the fingerprint index. The caller must hold the write
+// lock or own the registry.
+func (r *Registry) reindex() {
+	index := make(map[string][]versionRef)
+	for _, policy := range r.policies {
+		for _, v := range policy.Versions {
+			index[v.Fingerprint] = append(index[v.Fingerprint], versionRef{
+				name:    policy.Name,
+				version: v.Version,
+				site:    policy.Site,
+				project: policy.Project,
+				created: v.CreatedAt,
+			})
+		}
+	}
+	for _, refs := range index {
+		sort.Slice(refs, func(i, j int) bool {
+			if !refs[i].created.Equal(refs[j].created) {
+				return refs[i].created.After(refs[j].created)
+			}
+			if refs[i].name != refs[j].name {
+				return refs[i].name < refs[j].name
+			}
+			return refs[i].version > refs[j].version
+		})
+	}
+	r.byFingerprint = index
+}
+
+func (p *RegisteredPolicy) clone() RegisteredPolicy {
+	c := *p
+	c.Versions = append([]PolicyVersion(nil), p.Versions...)
+	return c
+}
@@ -0,0 +1,224 @@
+package policy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"universal-csp-report/internal/models"
+)
+
+func TestRegistry_VersionsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("Expected a missing file to load, got %v", err)
+	}
+
+	first, err := registry.AddVersion("shop", NewVersion{
+		Site:   "shop.example.com",
+		Policy: "default-src 'self'; report-uri /old",
+	})
+	if err != nil {
+		t.Fatalf("Failed to add version: %v", err)
+	}
+	if first.Active != 1 || first.Versions[0].Policy != "default-src 'self'" {
+		t.Errorf("Expected version 1 active without reporting directives, got %+v", first)
+	}
+
+	second, err := registry.AddVersion("shop", NewVersion{Policy: "default-src 'self'; img-src *", ReportOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to add version: %v", err)
+	}
+	if second.Active != 1 || len(second.Versions) != 2 || second.Site != "shop.example.com" {
+		t.Errorf("Expected version 2 to be added inactive to the same site, got %+v", second)
+	}
+
+	if _, err := registry.Activate("shop", 2); err != nil {
+		t.Fatalf("Failed to activate: %v", err)
+	}
+
+	reloaded, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("Failed to reload registry: %v", err)
+	}
+	policy, ok := reloaded.Get("shop")
+	if !ok || policy.Active != 2 || len(policy.Versions) != 2 {
+		t.Errorf("Expected the registry to persist, got %+v", policy)
+	}
+}
+
+func TestRegistry_Errors(t *testing.T) {
+	registry, err := LoadRegistry(filepath.Join(t.TempDir(), "policies.json"))
+	if err != nil {
+		t.Fatalf("Failed to load registry: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		policy  string
+		version NewVersion
+	}{
+		{"invalid name", "Shop!", NewVersion{Site: "example.com", Policy: "default-src 'self'"}},
+		{"missing site", "shop", NewVersion{Policy: "default-src 'self'"}},
+		{"empty policy", "shop", NewVersion{Site: "example.com", Policy: "report-uri /csp-report"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := registry.AddVersion(tt.policy, tt.version); !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("Expected ErrInvalidPolicy, got %v", err)
+			}
+		})
+	}
+
+	if _, err := registry.Activate("missing", 1); !errors.Is(err, ErrPolicyNotFound) {
+		t.Errorf("Expected ErrPolicyNotFound, got %v", err)
+	}
+	if _, err := registry.AddVersion("shop", NewVersion{Site: "example.com", Policy: "default-src 'self'"}); err != nil {
+		t.Fatalf("Failed to add version: %v", err)
+	}
+	if _, err := registry.Activate("shop", 2); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Expected ErrVersionNotFound, got %v", err)
+	}
+}
+
+func TestRegistry_FailedSaveRollsBack(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "registry")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	registry, err := LoadRegistry(filepath.Join(dir, "policies.json"))
+	if err != nil {
+		t.Fatalf("Failed to load registry: %v", err)
+	}
+	if _, err := registry.AddVersion("shop", NewVersion{Site: "shop.example.com", Project: "shop", Policy: "default-src 'self'"}); err != nil {
+		t.Fatalf("Failed to add version: %v", err)
+	}
+
+	// Writes fail once the directory is gone
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("Failed to remove directory: %v", err)
+	}
+	if _, err := registry.AddVersion("shop", NewVersion{Site: "blog.example.com", Project: "blog", Policy: "default-src *"}); err == nil {
+		t.Fatal("Expected the write to fail")
+	}
+
+	policy, _ := registry.Get("shop")
+	if policy.Site != "shop.example.com" || policy.Project != "shop" || len(policy.Versions) != 1 {
+		t.Errorf("Expected the failed version to be rolled back, got %+v", policy)
+	}
+	if name, _, ok := registry.Lookup(models.PolicyFingerprint("default-src 'self'"), "shop.example.com", "shop"); !ok || name != "shop" {
+		t.Errorf("Expected reports to still link to shop, got %q", name)
+	}
+}
+
+func TestLoadRegistry_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	if err := os.WriteFile(path, []byte(`{"policies":[{"name":"a"},{"name":"a"}]}`), 0o600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if _, err := LoadRegistry(path); err == nil {
+		t.Error("Expected duplicate policy names to be rejected")
+	}
+}
+
+func TestRegistry_HeadersMatchLookup(t *testing.T) {
+	registry, err := LoadRegistry(filepath.Join(t.TempDir(), "policies.json"))
+	if err != nil {
+		t.Fatalf("Failed to load registry: %v", err)
+	}
+	if _, err := registry.AddVersion("shop", NewVersion{Site: "example.com", Policy: "default-src 'self'", ReportOnly: true}); err != nil {
+		t.Fatalf("Failed to add version: %v", err)
+	}
+
+	reportURL := ReportURL("https://csp.example.com/", "shop", "secret")
+	if reportURL != "https://csp.example.com/report/shop/secret" {
+		t.Errorf("Expected project report URL, got %q", reportURL)
+	}
+
+	headers, version, err := registry.Headers("shop", reportURL)
+	if err != nil {
+		t.Fatalf("Failed to get headers: %v", err)
+	}
+
+	expected := "default-src 'self'; report-uri " + reportURL + "; report-to " + ReportingEndpoint
+	if got := headers.Get("Content-Security-Policy-Report-Only"); got != expected {
+		t.Errorf("Expected policy header %q, got %q", expected, got)
+	}
+	if got := headers.Get("Reporting-Endpoints"); got != `csp-endpoint="`+reportURL+`"` {
+		t.Errorf("Expected Reporting-Endpoints header, got %q", got)
+	}
+
+	// A browser reports the emitted policy as the original policy
+	name, number, ok := registry.Lookup(models.PolicyFingerprint(headers.Get(version.HeaderName())), "example.com", "")
+	if !ok || name != "shop" || number != 1 {
+		t.Errorf("Expected the emitted policy to link to shop v1, got %q v%d", name, number)
+	}
+	if _, _, ok := registry.Lookup(models.PolicyFingerprint("default-src *"), "example.com", ""); ok {
+		t.Error("Expected an unregistered policy not to be found")
+	}
+}
+
+func TestRegistry_LookupPrefersDocumentSite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.json")
+	registry, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("Failed to load registry: %v", err)
+	}
+	for _, v := range []struct{ name, site string }{{"shop", "shop.example.com"}, {"blog", "blog.example.com"}} {
+		if _, err := registry.AddVersion(v.name, NewVersion{Site: v.site, Policy: "default-src 'self'"}); err != nil {
+			t.Fatalf("Failed to add version: %v", err)
+		}
+	}
+	fingerprint := models.PolicyFingerprint("default-src 'self'")
+
+	// The index is rebuilt when the registry is loaded again
+	reloaded, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("Failed to reload registry: %v", err)
+	}
+
+	tests := []struct {
+		host     string
+		expected string
+	}{
+		{"shop.example.com", "shop"},
+		{"www.blog.example.com", "blog"},
+		{"other.example.org", "blog"},
+	}
+
+	for _, r := range []*Registry{registry, reloaded} {
+		for _, tt := range tests {
+			name, _, ok := r.Lookup(fingerprint, tt.host, "")
+			if !ok || name != tt.expected {
+				t.Errorf("Expected %s to link to %q, got %q", tt.host, tt.expected, name)
+			}
+		}
+	}
+}
+
+func TestRegistry_Middleware(t *testing.T) {
+	registry, err := LoadRegistry(filepath.Join(t.TempDir(), "policies.json"))
+	if err != nil {
+		t.Fatalf("Failed to load registry: %v", err)
+	}
+	if _, err := registry.AddVersion("shop", NewVersion{Site: "example.com", Policy: "default-src 'self'"}); err != nil {
+		t.Fatalf("Failed to add version: %v", err)
+	}
+
+	handler := registry.Middleware("shop", "https://csp.example.com/csp-report")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Header().Get("Content-Security-Policy") == "" || w.Header().Get("Reporting-Endpoints") == "" {
+		t.Errorf("Expected policy headers to be set, got %v", w.Header())
+	}
+}
@@ -2,10 +2,12 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"universal-csp-report/internal/alerting"
 	"universal-csp-report/internal/config"
 	"universal-csp-report/internal/models"
 	"universal-csp-report/internal/storage"
@@ -21,21 +23,68 @@ type BatchProcessor struct {
 	reportChan chan *models.CSPReport
 	batchChan  chan []*models.CSPReport
 
-	workers []Worker
-	batcher *Batcher
+	workers    []Worker
+	batcher    *Batcher
+	issues     *IssueStore
+	inline     *InlineStore
+	rollouts   *RolloutStore
+	dedup      *Deduplicator
+	aggregator *Aggregator
+	sampler    *Sampler
+	filter     *NoiseFilter
+	routes     []models.RouteRule
+	redactor   *Redactor
+	broker     *Broker
+	alerter    *alerting.Alerter
 
 	ctx    context.Context
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
 
+	// The dedup stage is stopped first so pending reports still reach the
+	// batcher before it shuts down
+	dedupCtx    context.Context
+	dedupCancel context.CancelFunc
+	dedupWg     sync.WaitGroup
+
 	stats Stats
 }
 
 type Stats struct {
-	QueueSize      int64 `json:"queue_size"`
-	ProcessedTotal int64 `json:"processed_total"`
-	ErrorsTotal    int64 `json:"errors_total"`
-	BatchesTotal   int64 `json:"batches_total"`
+	QueueSize       int64 `json:"queue_size"`
+	ReceivedTotal   int64 `json:"received_total"`
+	ProcessedTotal  int64 `json:"processed_total"`
+	ErrorsTotal     int64 `json:"errors_total"`
+	BatchesTotal    int64 `json:"batches_total"`
+	IssuesTracked   int64 `json:"issues_tracked"`
+	NewIssuesTotal  int64 `json:"new_issues_total"`
+	InlineTracked   int64 `json:"inline_scripts_tracked"`
+	RolloutsTracked int64 `json:"rollout_policies_tracked"`
+
+	DuplicatesTotal int64   `json:"duplicates_total"`
+	DedupPending    int64   `json:"dedup_pending"`
+	DedupRatio      float64 `json:"dedup_ratio"`
+
+	RollupBuckets     int64 `json:"rollup_buckets"`
+	RollupFlushErrors int64 `json:"rollup_flush_errors"`
+	RollupDropped     int64 `json:"rollup_dropped_total"`
+
+	SampledKeptTotal int64   `json:"sampled_kept_total"`
+	SampledOutTotal  int64   `json:"sampled_out_total"`
+	SampledOutRatio  float64 `json:"sampled_out_ratio"`
+
+	FilteredTotal int64            `json:"filtered_total"`
+	FilterHits    map[string]int64 `json:"filter_hits,omitempty"`
+
+	StreamSubscribers int64 `json:"stream_subscribers"`
+	StreamDropped     int64 `json:"stream_dropped"`
+
+	RedactedTotal int64            `json:"redacted_total"`
+	RedactionHits map[string]int64 `json:"redaction_hits,omitempty"`
+
+	AlertsFired       int64 `json:"alerts_fired"`
+	AlertsSuppressed  int64 `json:"alerts_suppressed"`
+	AlertNotifyErrors int64 `json:"alert_notify_errors"`
 }
 
 type Worker struct {
@@ -58,15 +107,164 @@ func New(cfg config.BatchProcessorConfig, store storage.Storage, logger *logrus.
 	reportChan := make(chan *models.CSPReport, cfg.QueueSize)
 	batchChan := make(chan []*models.CSPReport, cfg.WorkerCount*config.BatchChannelMultiplier)
 
-	return &BatchProcessor{
+	bp := &BatchProcessor{
 		config:     cfg,
 		storage:    store,
 		logger:     logger,
 		reportChan: reportChan,
 		batchChan:  batchChan,
+		issues:     NewIssueStore(cfg.MaxIssues, cfg.MaxIssuePages),
+		inline:     NewInlineStore(cfg.MaxInlineScripts),
+		rollouts:   NewRolloutStore(cfg.MaxRollouts, cfg.MaxIssuePages),
+		broker:     newBrokerFor(cfg.Stream),
 		ctx:        ctx,
 		cancel:     cancel,
 	}
+	bp.dedupCtx, bp.dedupCancel = context.WithCancel(context.Background())
+
+	if cfg.Dedup.Enabled {
+		window := time.Duration(cfg.Dedup.Window) * time.Second
+		dedup, unknown := NewDeduplicator(window, cfg.Dedup.MaxEntries, cfg.Dedup.Key, bp.enqueue)
+		if len(unknown) > 0 {
+			logger.WithField("fields", unknown).Warn("Ignoring unknown dedup key fields")
+		}
+		bp.dedup = dedup
+	}
+
+	if cfg.Filter.Enabled {
+		bp.filter = newNoiseFilterFor(cfg.Filter, logger)
+	}
+
+	bp.routes = newRoutesFor(cfg.Routes, logger)
+
+	if cfg.Redaction.Enabled {
+		bp.redactor = newRedactorFor(cfg.Redaction, logger)
+	}
+
+	if cfg.Sampling.Enabled {
+		bp.sampler = NewSampler(cfg.Sampling, bp.issues.Count)
+	}
+
+	if cfg.Rollup.Enabled {
+		bp.aggregator = newAggregatorFor(cfg.Rollup, store, logger)
+	}
+
+	if cfg.Alerting.Enabled {
+		bp.alerter = newAlerterFor(cfg.Alerting, logger)
+	}
+
+	return bp
+}
+
+// newAlerterFor falls back to the default window sizes when they are not
+// positive and warns when no notifier is configured.
+func newAlerterFor(cfg config.AlertingConfig, logger *logrus.Logger) *alerting.Alerter {
+	if cfg.Window <= 0 {
+		cfg.Window = config.DefaultAlertWindow
+	}
+	if cfg.BaselineWindows <= 0 {
+		cfg.BaselineWindows = config.DefaultAlertBaselineWindows
+	}
+
+	notifiers := alerting.NewNotifiers(cfg)
+	if len(notifiers) == 0 {
+		logger.Warn("Alerting is enabled but no notifier is configured, alerts are only listed in the API")
+	}
+	return alerting.NewAlerter(cfg, notifiers, logger)
+}
+
+func newBrokerFor(cfg config.StreamConfig) *Broker {
+	bufferSize := cfg.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = config.DefaultStreamBufferSize
+	}
+	return NewBroker(cfg.MaxSubscribers, bufferSize)
+}
+
+// newNoiseFilterFor builds the filter from the default rules and the rules
+// file. Rules that fail to load or compile are logged and skipped.
+func newNoiseFilterFor(cfg config.FilterConfig, logger *logrus.Logger) *NoiseFilter {
+	var rules []models.FilterRule
+	if cfg.DefaultRules {
+		rules = append(rules, models.DefaultFilterRules()...)
+	}
+
+	if cfg.RulesFile != "" {
+		fileRules, err := LoadFilterRules(cfg.RulesFile)
+		if err != nil {
+			logger.WithError(err).WithField("file", cfg.RulesFile).Error("Failed to load filter rules")
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	filter, errs := NewNoiseFilter(rules)
+	for _, err := range errs {
+		logger.WithError(err).Error("Ignoring invalid filter rule")
+	}
+	return filter
+}
+
+// newRoutesFor compiles the route patterns followed by the rules file. Rules
+// that fail to load or compile are logged and skipped.
+func newRoutesFor(cfg config.RouteConfig, logger *logrus.Logger) []models.RouteRule {
+	var rules []models.RouteRule
+	for _, pattern := range cfg.Patterns {
+		rules = append(rules, models.RouteRule{Pattern: pattern})
+	}
+
+	if cfg.RulesFile != "" {
+		fileRules, err := LoadRouteRules(cfg.RulesFile)
+		if err != nil {
+			logger.WithError(err).WithField("file", cfg.RulesFile).Error("Failed to load route rules")
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	compiled := make([]models.RouteRule, 0, len(rules))
+	for _, rule := range rules {
+		if err := rule.Compile(); err != nil {
+			logger.WithError(err).Error("Ignoring invalid route rule")
+			continue
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled
+}
+
+// newRedactorFor builds the redactor from the configuration and the rules
+// file. Rules that fail to load or compile are logged and skipped.
+func newRedactorFor(cfg config.RedactionConfig, logger *logrus.Logger) *Redactor {
+	var rules []RedactionRule
+	if cfg.RulesFile != "" {
+		fileRules, err := LoadRedactionRules(cfg.RulesFile)
+		if err != nil {
+			logger.WithError(err).WithField("file", cfg.RulesFile).Error("Failed to load redaction rules")
+		}
+		rules = fileRules
+	}
+
+	redactor, errs := NewRedactor(cfg, rules)
+	for _, err := range errs {
+		logger.WithError(err).Error("Ignoring invalid redaction rule")
+	}
+	return redactor
+}
+
+// newAggregatorFor returns nil, disabling rollups, when the backend cannot
+// store them or the configuration is invalid.
+func newAggregatorFor(cfg config.RollupConfig, store storage.Storage, logger *logrus.Logger) *Aggregator {
+	rollupStore, ok := store.(storage.RollupStorage)
+	if !ok {
+		logger.Warn("Storage backend does not support rollups, disabling rollups")
+		return nil
+	}
+
+	aggregator, err := NewAggregator(cfg.Intervals, cfg.Dimensions, cfg.MaxBuckets, cfg.MaxRetries, rollupStore, logger)
+	if err != nil {
+		logger.WithError(err).Error("Invalid rollup configuration, disabling rollups")
+		return nil
+	}
+	return aggregator
 }
 
 func (bp *BatchProcessor) Start() {
@@ -83,6 +281,24 @@ func (bp *BatchProcessor) Start() {
 	bp.wg.Add(1)
 	go bp.batcher.start(bp.ctx, &bp.wg)
 
+	if bp.dedup != nil {
+		bp.dedupWg.Add(1)
+		go bp.dedup.start(bp.dedupCtx, &bp.dedupWg)
+	}
+
+	if bp.aggregator != nil {
+		bp.wg.Add(1)
+		go bp.aggregator.start(bp.ctx, &bp.wg)
+	}
+
+	if bp.alerter != nil {
+		bp.wg.Add(1)
+		go func() {
+			defer bp.wg.Done()
+			bp.alerter.Run(bp.ctx)
+		}()
+	}
+
 	bp.workers = make([]Worker, bp.config.WorkerCount)
 	for i := 0; i < bp.config.WorkerCount; i++ {
 		bp.workers[i] = Worker{
@@ -104,30 +320,148 @@ func (bp *BatchProcessor) Start() {
 
 func (bp *BatchProcessor) Stop() {
 	bp.logger.Info("Stopping batch processor")
+	bp.dedupCancel()
+	bp.dedupWg.Wait()
 	bp.cancel()
 	bp.wg.Wait()
 	bp.logger.Info("Batch processor stopped")
 }
 
 func (bp *BatchProcessor) Submit(report *models.CSPReport) error {
+	atomic.AddInt64(&bp.stats.ReceivedTotal, 1)
+
+	// Redaction comes first so nothing downstream sees the original values;
+	// the route and fingerprint are then derived from the redacted URI
+	redacted := bp.redactor != nil && bp.redactor.Apply(report)
+	if redacted || len(bp.routes) > 0 {
+		report.ApplyRoutes(bp.routes)
+	}
+
+	if bp.filter != nil && !bp.filter.Apply(report) {
+		return nil
+	}
+
+	if !report.Downgraded {
+		isNew := bp.issues.Record(report)
+		if isNew {
+			atomic.AddInt64(&bp.stats.NewIssuesTotal, 1)
+		}
+		if bp.alerter != nil {
+			bp.alerter.Observe(report, isNew)
+		}
+		bp.inline.Record(report)
+		bp.rollouts.Record(report)
+	}
+
+	if bp.aggregator != nil {
+		bp.aggregator.Add(report)
+	}
+
+	bp.broker.Publish(report)
+
+	// Sampling happens after issues and rollups are counted so both stay exact
+	if bp.sampler != nil && !bp.sampler.Keep(report) {
+		return nil
+	}
+
+	if bp.dedup != nil {
+		bp.dedup.Add(report)
+		return nil
+	}
+
+	bp.enqueue(report)
+	return nil
+}
+
+// enqueue hands a report to the batcher, dropping it when the queue is full.
+func (bp *BatchProcessor) enqueue(report *models.CSPReport) {
 	select {
 	case bp.reportChan <- report:
 		atomic.AddInt64(&bp.stats.QueueSize, 1)
-		return nil
 	default:
 		atomic.AddInt64(&bp.stats.ErrorsTotal, 1)
 		bp.logger.Warn("Report queue is full, dropping report")
-		return nil
 	}
 }
 
 func (bp *BatchProcessor) GetStatus() Stats {
-	return Stats{
-		QueueSize:      atomic.LoadInt64(&bp.stats.QueueSize),
-		ProcessedTotal: atomic.LoadInt64(&bp.stats.ProcessedTotal),
-		ErrorsTotal:    atomic.LoadInt64(&bp.stats.ErrorsTotal),
-		BatchesTotal:   atomic.LoadInt64(&bp.stats.BatchesTotal),
+	stats := Stats{
+		QueueSize:       atomic.LoadInt64(&bp.stats.QueueSize),
+		ReceivedTotal:   atomic.LoadInt64(&bp.stats.ReceivedTotal),
+		ProcessedTotal:  atomic.LoadInt64(&bp.stats.ProcessedTotal),
+		ErrorsTotal:     atomic.LoadInt64(&bp.stats.ErrorsTotal),
+		BatchesTotal:    atomic.LoadInt64(&bp.stats.BatchesTotal),
+		IssuesTracked:   int64(bp.issues.Len()),
+		NewIssuesTotal:  atomic.LoadInt64(&bp.stats.NewIssuesTotal),
+		InlineTracked:   int64(bp.inline.Len()),
+		RolloutsTracked: int64(bp.rollouts.Len()),
 	}
+
+	if bp.dedup != nil {
+		received, collapsed, pending := bp.dedup.Stats()
+		stats.DuplicatesTotal = collapsed
+		stats.DedupPending = int64(pending)
+		if received > 0 {
+			stats.DedupRatio = float64(collapsed) / float64(received)
+		}
+	}
+
+	if bp.aggregator != nil {
+		pending, flushErrors, dropped := bp.aggregator.Stats()
+		stats.RollupBuckets = int64(pending)
+		stats.RollupFlushErrors = flushErrors
+		stats.RollupDropped = dropped
+	}
+
+	if bp.filter != nil {
+		stats.FilteredTotal, stats.FilterHits = bp.filter.Stats()
+	}
+
+	if bp.redactor != nil {
+		stats.RedactedTotal, stats.RedactionHits = bp.redactor.Stats()
+	}
+
+	stats.StreamSubscribers, stats.StreamDropped = bp.broker.Stats()
+
+	if bp.alerter != nil {
+		stats.AlertsFired, stats.AlertsSuppressed, stats.AlertNotifyErrors = bp.alerter.Stats()
+	}
+
+	if bp.sampler != nil {
+		kept, sampledOut := bp.sampler.Stats()
+		stats.SampledKeptTotal = kept
+		stats.SampledOutTotal = sampledOut
+		if total := kept + sampledOut; total > 0 {
+			stats.SampledOutRatio = float64(sampledOut) / float64(total)
+		}
+	}
+
+	return stats
+}
+
+// Issues returns the store grouping submitted reports by fingerprint.
+func (bp *BatchProcessor) Issues() *IssueStore {
+	return bp.issues
+}
+
+// Inline returns the store grouping inline violations by page.
+func (bp *BatchProcessor) Inline() *InlineStore {
+	return bp.inline
+}
+
+// Rollouts returns the store tracking violations per policy and disposition.
+func (bp *BatchProcessor) Rollouts() *RolloutStore {
+	return bp.rollouts
+}
+
+// Broker returns the broker publishing incoming reports to live subscribers.
+func (bp *BatchProcessor) Broker() *Broker {
+	return bp.broker
+}
+
+// Alerter returns the alerter, or nil when alerting is disabled.
+func (bp *BatchProcessor) Alerter() *alerting.Alerter {
+	return bp.alerter
 }
 
 func (b *Batcher) start(ctx context.Context, wg *sync.WaitGroup) {
@@ -206,6 +540,17 @@ func (w *Worker) processBatch(batch []*models.CSPReport, stats *Stats, logger *l
 	atomic.AddInt64(&stats.QueueSize, -int64(len(batch)))
 	atomic.AddInt64(&stats.BatchesTotal, 1)
 
+	var partial *storage.PartialBatchError
+	if errors.As(err, &partial) && partial.Failed < len(batch) {
+		atomic.AddInt64(&stats.ErrorsTotal, int64(partial.Failed))
+		atomic.AddInt64(&stats.ProcessedTotal, int64(len(batch)-partial.Failed))
+		logger.WithError(err).WithFields(logrus.Fields{
+			"batch_size": len(batch),
+			"failed":     partial.Failed,
+			"duration":   duration,
+		}).Error("Failed to store part of batch")
+		return
+	}
 	if err != nil {
 		atomic.AddInt64(&stats.ErrorsTotal, int64(len(batch)))
 		logger.WithError(err).WithFields(logrus.Fields{
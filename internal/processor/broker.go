@@ -0,0 +1,140 @@
+package processor
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"universal-csp-report/internal/models"
+)
+
+// ErrTooManySubscribers is returned by Subscribe when the broker is full.
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// StreamFilter selects the reports a subscriber receives. Empty fields match
+// everything; Host matches either the document or the blocked host.
+type StreamFilter struct {
+	Project   string
+	Directive string
+	Host      string
+}
+
+// Matches reports whether a report passes the filter.
+func (f StreamFilter) Matches(report *models.CSPReport) bool {
+	if f.Project != "" && report.Project != f.Project {
+		return false
+	}
+	if f.Directive == "" && f.Host == "" {
+		return true
+	}
+
+	parsed := report.ParsedReport
+	if parsed == nil {
+		return false
+	}
+	if directive := strings.ToLower(f.Directive); directive != "" && parsed.CanonicalDirective() != directive && parsed.DirectiveName() != directive {
+		return false
+	}
+	if host := strings.ToLower(f.Host); host != "" && models.URLHost(parsed.DocumentURI) != host && models.URLHost(parsed.BlockedURI) != host {
+		return false
+	}
+	return true
+}
+
+// Subscription receives matching reports on C until it is unsubscribed.
+type Subscription struct {
+	C <-chan *models.CSPReport
+
+	ch      chan *models.CSPReport
+	filter  StreamFilter
+	dropped int64
+}
+
+// Dropped returns the number of reports dropped because the subscriber did
+// not keep up.
+func (s *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&s.dropped)
+}
+
+// Broker fans reports out to live subscribers. Publishing never blocks: a
+// subscriber whose buffer is full misses reports instead of slowing ingestion.
+type Broker struct {
+	maxSubscribers int
+	bufferSize     int
+
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	count       int64
+
+	dropped int64
+}
+
+func NewBroker(maxSubscribers, bufferSize int) *Broker {
+	return &Broker{
+		maxSubscribers: maxSubscribers,
+		bufferSize:     bufferSize,
+		subscribers:    make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for reports matching filter.
+func (b *Broker) Subscribe(filter StreamFilter) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxSubscribers > 0 && len(b.subscribers) >= b.maxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+
+	ch := make(chan *models.CSPReport, b.bufferSize)
+	sub := &Subscription{C: ch, ch: ch, filter: filter}
+	b.subscribers[sub] = struct{}{}
+	atomic.StoreInt64(&b.count, int64(len(b.subscribers)))
+	return sub, nil
+}
+
+// Unsubscribe removes a subscriber and closes its channel.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.ch)
+	atomic.StoreInt64(&b.count, int64(len(b.subscribers)))
+}
+
+// Publish sends a copy of the report to every matching subscriber. The copy
+// keeps later pipeline stages from mutating what subscribers read.
+func (b *Broker) Publish(report *models.CSPReport) {
+	if atomic.LoadInt64(&b.count) == 0 {
+		return
+	}
+
+	snapshot := *report
+	snapshot.Tags = append([]string(nil), report.Tags...)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(&snapshot) {
+			continue
+		}
+		select {
+		case sub.ch <- &snapshot:
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+			atomic.AddInt64(&b.dropped, 1)
+		}
+	}
+}
+
+// Stats returns the number of subscribers and of reports dropped for slow
+// subscribers.
+func (b *Broker) Stats() (subscribers, dropped int64) {
+	return atomic.LoadInt64(&b.count), atomic.LoadInt64(&b.dropped)
+}
@@ -0,0 +1,101 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamFilter_Matches(t *testing.T) {
+	report := newTestReport("https://shop.example.com/cart", "https://evil.com/x.js", time.Now())
+	report.Project = "shop"
+
+	tests := []struct {
+		name     string
+		filter   StreamFilter
+		expected bool
+	}{
+		{"empty", StreamFilter{}, true},
+		{"project", StreamFilter{Project: "shop"}, true},
+		{"other project", StreamFilter{Project: "blog"}, false},
+		{"directive", StreamFilter{Directive: "Script-Src"}, true},
+		{"other directive", StreamFilter{Directive: "img-src"}, false},
+		{"document host", StreamFilter{Host: "shop.example.com"}, true},
+		{"blocked host", StreamFilter{Host: "EVIL.com"}, true},
+		{"other host", StreamFilter{Host: "example.org"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Matches(report); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestBroker_DropsForSlowSubscribers(t *testing.T) {
+	broker := NewBroker(0, 2)
+
+	slow, err := broker.Subscribe(StreamFilter{})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	filtered, err := broker.Subscribe(StreamFilter{Directive: "img-src"})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		broker.Publish(newTestReport("https://example.com/", "https://evil.com/x.js", time.Now()))
+	}
+
+	if len(slow.C) != 2 || slow.Dropped() != 3 {
+		t.Errorf("Expected 2 buffered and 3 dropped, got %d and %d", len(slow.C), slow.Dropped())
+	}
+	if len(filtered.C) != 0 || filtered.Dropped() != 0 {
+		t.Errorf("Expected filtered subscriber to receive nothing, got %d", len(filtered.C))
+	}
+
+	subscribers, dropped := broker.Stats()
+	if subscribers != 2 || dropped != 3 {
+		t.Errorf("Expected 2 subscribers and 3 dropped, got %d and %d", subscribers, dropped)
+	}
+
+	broker.Unsubscribe(slow)
+	broker.Unsubscribe(slow)
+	for range slow.C {
+	}
+	if subscribers, _ := broker.Stats(); subscribers != 1 {
+		t.Errorf("Expected 1 subscriber after unsubscribe, got %d", subscribers)
+	}
+}
+
+func TestBroker_MaxSubscribers(t *testing.T) {
+	broker := NewBroker(1, 1)
+
+	first, err := broker.Subscribe(StreamFilter{})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := broker.Subscribe(StreamFilter{}); err != ErrTooManySubscribers {
+		t.Errorf("Expected ErrTooManySubscribers, got %v", err)
+	}
+
+	broker.Unsubscribe(first)
+	if _, err := broker.Subscribe(StreamFilter{}); err != nil {
+		t.Errorf("Expected subscribe to succeed after unsubscribe, got %v", err)
+	}
+}
+
+func TestBroker_PublishesSnapshot(t *testing.T) {
+	broker := NewBroker(0, 1)
+	sub, _ := broker.Subscribe(StreamFilter{})
+
+	report := newTestReport("https://example.com/", "https://evil.com/x.js", time.Now())
+	broker.Publish(report)
+	report.Occurrences = 42
+
+	if received := <-sub.C; received.Occurrences == 42 {
+		t.Error("Expected subscriber to receive a copy unaffected by later changes")
+	}
+}
@@ -0,0 +1,200 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+// dedupKeyFields lists the field names accepted in the dedup key definition.
+var dedupKeyFields = map[string]bool{
+	"fingerprint":   true,
+	"remote_addr":   true,
+	"user_agent":    true,
+	"browser_type":  true,
+	"document_uri":  true,
+	"blocked_uri":   true,
+	"directive":     true,
+	"source_file":   true,
+	"line_number":   true,
+	"column_number": true,
+	"disposition":   true,
+}
+
+type dedupEntry struct {
+	key     string
+	report  *models.CSPReport
+	expires time.Time
+}
+
+// Deduplicator collapses reports with the same key seen within a time window
+// into the first report, which is emitted once the window closes carrying the
+// occurrence count and first/last timestamps.
+type Deduplicator struct {
+	window     time.Duration
+	maxEntries int
+	keyFields  []string
+	emit       func(*models.CSPReport)
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+	order   []*dedupEntry
+
+	received  int64
+	collapsed int64
+}
+
+// NewDeduplicator creates a deduplicator keyed on the given field names.
+// Unknown field names are returned so the caller can report them. Without any
+// known field it falls back to config.DefaultDedupKey, as a constant key would
+// collapse every report in the window into one.
+func NewDeduplicator(window time.Duration, maxEntries int, keyFields []string, emit func(*models.CSPReport)) (*Deduplicator, []string) {
+	d := &Deduplicator{
+		window:     window,
+		maxEntries: maxEntries,
+		emit:       emit,
+		entries:    make(map[string]*dedupEntry),
+	}
+
+	var unknown []string
+	for _, field := range keyFields {
+		field = strings.TrimSpace(field)
+		if dedupKeyFields[field] {
+			d.keyFields = append(d.keyFields, field)
+		} else if field != "" {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(d.keyFields) == 0 {
+		d.keyFields = config.DefaultDedupKey
+	}
+
+	return d, unknown
+}
+
+// Add records a report, either holding it as the first occurrence of its key
+// or folding it into the pending report for that key.
+func (d *Deduplicator) Add(report *models.CSPReport) {
+	key := d.key(report)
+
+	var evicted *models.CSPReport
+
+	d.mu.Lock()
+	d.received++
+
+	if entry, ok := d.entries[key]; ok {
+		d.collapsed++
+		pending := entry.report
+		pending.Occurrences++
+		if pending.LastOccurrence == nil || report.Timestamp.After(*pending.LastOccurrence) {
+			last := report.Timestamp
+			pending.LastOccurrence = &last
+		}
+		d.mu.Unlock()
+		return
+	}
+
+	// Bound memory by emitting the oldest pending report early
+	if d.maxEntries > 0 && len(d.entries) >= d.maxEntries {
+		evicted = d.popOldest()
+	}
+
+	first := report.Timestamp
+	report.Occurrences = 1
+	report.FirstOccurrence = &first
+	report.LastOccurrence = &first
+
+	entry := &dedupEntry{key: key, report: report, expires: time.Now().Add(d.window)}
+	d.entries[key] = entry
+	d.order = append(d.order, entry)
+	d.mu.Unlock()
+
+	if evicted != nil {
+		d.emit(evicted)
+	}
+}
+
+// Flush emits all pending reports whose window has closed before now.
+func (d *Deduplicator) Flush(now time.Time) {
+	var expired []*models.CSPReport
+
+	d.mu.Lock()
+	for len(d.order) > 0 && !d.order[0].expires.After(now) {
+		expired = append(expired, d.popOldest())
+	}
+	d.mu.Unlock()
+
+	for _, report := range expired {
+		d.emit(report)
+	}
+}
+
+// FlushAll emits every pending report regardless of its window.
+func (d *Deduplicator) FlushAll() {
+	d.mu.Lock()
+	pending := make([]*models.CSPReport, 0, len(d.order))
+	for len(d.order) > 0 {
+		pending = append(pending, d.popOldest())
+	}
+	d.mu.Unlock()
+
+	for _, report := range pending {
+		d.emit(report)
+	}
+}
+
+// Stats returns the number of reports received and collapsed into an
+// earlier occurrence, plus the number currently pending.
+func (d *Deduplicator) Stats() (received, collapsed int64, pending int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.received, d.collapsed, len(d.entries)
+}
+
+func (d *Deduplicator) start(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	interval := d.window / 4
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			d.FlushAll()
+			return
+		case now := <-ticker.C:
+			d.Flush(now)
+		}
+	}
+}
+
+// popOldest removes the oldest entry. The caller must hold d.mu.
+func (d *Deduplicator) popOldest() *models.CSPReport {
+	entry := d.order[0]
+	d.order[0] = nil
+	d.order = d.order[1:]
+	delete(d.entries, entry.key)
+	return entry.report
+}
+
+func (d *Deduplicator) key(report *models.CSPReport) string {
+	parts := make([]string, len(d.keyFields))
+	for i, field := range d.keyFields {
+		parts[i] = dedupKeyValue(report, field)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func dedupKeyValue(report *models.CSPReport, field string) string {
+	value, _ := models.ReportFieldValue(report, field)
+	return value
+}
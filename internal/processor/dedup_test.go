@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+type collector struct {
+	mu      sync.Mutex
+	reports []*models.CSPReport
+}
+
+func (c *collector) emit(report *models.CSPReport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reports = append(c.reports, report)
+}
+
+func newTestReport(documentURI, blockedURI string, at time.Time) *models.CSPReport {
+	parsed := &models.ParsedCSPReport{
+		DocumentURI:       documentURI,
+		ViolatedDirective: "script-src",
+		BlockedURI:        blockedURI,
+	}
+	return &models.CSPReport{
+		Timestamp:    at,
+		RemoteAddr:   "10.0.0.1",
+		BrowserType:  "chrome",
+		ParsedReport: parsed,
+		Fingerprint:  models.ComputeFingerprint(parsed),
+	}
+}
+
+func TestDeduplicator_FallsBackToDefaultKey(t *testing.T) {
+	for _, keyFields := range [][]string{nil, {"bogus", " "}} {
+		out := &collector{}
+		dedup, _ := NewDeduplicator(time.Minute, 100, keyFields, out.emit)
+
+		start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+		dedup.Add(newTestReport("https://example.com/", "https://evil.com/x.js", start))
+		dedup.Add(newTestReport("https://example.com/other", "https://evil.com/y.js", start))
+
+		dedup.Flush(time.Now().Add(2 * time.Minute))
+		if len(out.reports) != 2 {
+			t.Errorf("Expected distinct reports to be kept apart with key %v, got %d reports", keyFields, len(out.reports))
+		}
+	}
+}
+
+func TestDeduplicator_CollapsesWithinWindow(t *testing.T) {
+	out := &collector{}
+	dedup, unknown := NewDeduplicator(time.Minute, 100, []string{"fingerprint", "document_uri", "bogus"}, out.emit)
+	if len(unknown) != 1 || unknown[0] != "bogus" {
+		t.Errorf("Expected unknown field to be reported, got %v", unknown)
+	}
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		dedup.Add(newTestReport("https://example.com/", "https://evil.com/x.js", start.Add(time.Duration(i)*time.Second)))
+	}
+	dedup.Add(newTestReport("https://example.com/other", "https://evil.com/x.js", start))
+
+	dedup.Flush(time.Now())
+	if len(out.reports) != 0 {
+		t.Fatalf("Expected reports to be held during the window, got %d", len(out.reports))
+	}
+
+	dedup.Flush(time.Now().Add(2 * time.Minute))
+	if len(out.reports) != 2 {
+		t.Fatalf("Expected 2 collapsed reports, got %d", len(out.reports))
+	}
+
+	first := out.reports[0]
+	if first.Occurrences != 3 {
+		t.Errorf("Expected 3 occurrences, got %d", first.Occurrences)
+	}
+	if !first.FirstOccurrence.Equal(start) || !first.LastOccurrence.Equal(start.Add(2*time.Second)) {
+		t.Errorf("Unexpected occurrence range %v - %v", first.FirstOccurrence, first.LastOccurrence)
+	}
+	if out.reports[1].Occurrences != 1 {
+		t.Errorf("Expected single occurrence, got %d", out.reports[1].Occurrences)
+	}
+
+	received, collapsed, pending := dedup.Stats()
+	if received != 4 || collapsed != 2 || pending != 0 {
+		t.Errorf("Unexpected stats received=%d collapsed=%d pending=%d", received, collapsed, pending)
+	}
+}
+
+func TestDeduplicator_BoundedMemory(t *testing.T) {
+	out := &collector{}
+	dedup, _ := NewDeduplicator(time.Hour, 2, []string{"document_uri"}, out.emit)
+
+	now := time.Now()
+	dedup.Add(newTestReport("https://example.com/a", "inline", now))
+	dedup.Add(newTestReport("https://example.com/b", "inline", now))
+	dedup.Add(newTestReport("https://example.com/c", "inline", now))
+
+	if len(out.reports) != 1 || out.reports[0].ParsedReport.DocumentURI != "https://example.com/a" {
+		t.Fatalf("Expected oldest entry to be emitted early, got %d reports", len(out.reports))
+	}
+
+	dedup.FlushAll()
+	if len(out.reports) != 3 {
+		t.Errorf("Expected FlushAll to emit remaining reports, got %d", len(out.reports))
+	}
+}
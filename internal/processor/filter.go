@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+
+	"universal-csp-report/internal/models"
+)
+
+type noiseRule struct {
+	models.FilterRule
+	hits int64
+}
+
+// NoiseFilter applies filter rules to incoming reports, dropping, tagging or
+// downgrading those that match, and counts hits per rule.
+type NoiseFilter struct {
+	rules   []*noiseRule
+	dropped int64
+}
+
+// NewNoiseFilter compiles the given rules. Invalid rules are skipped and
+// returned as errors so the caller can report them.
+func NewNoiseFilter(rules []models.FilterRule) (*NoiseFilter, []error) {
+	f := &NoiseFilter{}
+
+	var errs []error
+	for _, rule := range rules {
+		if err := rule.Compile(); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		f.rules = append(f.rules, &noiseRule{FilterRule: rule})
+	}
+
+	return f, errs
+}
+
+// LoadFilterRules reads a JSON array of filter rules from a file.
+func LoadFilterRules(path string) ([]models.FilterRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filter rules: %w", err)
+	}
+
+	var rules []models.FilterRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse filter rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// Apply runs every rule against a report and returns false when the report
+// should be dropped.
+func (f *NoiseFilter) Apply(report *models.CSPReport) bool {
+	for _, rule := range f.rules {
+		if !rule.Matches(report) {
+			continue
+		}
+		atomic.AddInt64(&rule.hits, 1)
+
+		switch rule.Action {
+		case models.FilterActionDrop:
+			atomic.AddInt64(&f.dropped, 1)
+			return false
+		case models.FilterActionTag:
+			if !containsString(report.Tags, rule.Tag) {
+				report.Tags = append(report.Tags, rule.Tag)
+			}
+		case models.FilterActionDowngrade:
+			report.Downgraded = true
+		}
+	}
+
+	return true
+}
+
+// Stats returns the number of dropped reports and the hit count per rule.
+func (f *NoiseFilter) Stats() (dropped int64, hits map[string]int64) {
+	hits = make(map[string]int64, len(f.rules))
+	for _, rule := range f.rules {
+		hits[rule.Name] += atomic.LoadInt64(&rule.hits)
+	}
+	return atomic.LoadInt64(&f.dropped), hits
+}
@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+func TestNoiseFilter_Actions(t *testing.T) {
+	rules := append(models.DefaultFilterRules(),
+		models.FilterRule{Name: "tag-cdn", Field: "blocked_uri", Glob: "https://cdn.*", Action: models.FilterActionTag, Tag: "cdn"},
+		models.FilterRule{Name: "downgrade-img", Field: "directive", Glob: "img-src", Action: models.FilterActionDowngrade},
+		models.FilterRule{Name: "broken", Field: "nope", Glob: "*", Action: models.FilterActionDrop},
+	)
+
+	filter, errs := NewNoiseFilter(rules)
+	if len(errs) != 1 {
+		t.Fatalf("Expected the invalid rule to be reported, got %v", errs)
+	}
+
+	extension := newTestReport("https://example.com/", "chrome-extension://abc/x.js", time.Now())
+	if filter.Apply(extension) {
+		t.Error("Expected extension report to be dropped")
+	}
+
+	cdn := newTestReport("https://example.com/", "https://cdn.example.com/x.js", time.Now())
+	if !filter.Apply(cdn) {
+		t.Fatal("Expected CDN report to be kept")
+	}
+	if len(cdn.Tags) != 1 || cdn.Tags[0] != "cdn" || cdn.Downgraded {
+		t.Errorf("Expected report tagged cdn and not downgraded, got %v %v", cdn.Tags, cdn.Downgraded)
+	}
+
+	image := newTestReport("https://example.com/", "https://img.example.com/a.png", time.Now())
+	image.ParsedReport.ViolatedDirective = "img-src"
+	if !filter.Apply(image) || !image.Downgraded {
+		t.Error("Expected image report to be kept and downgraded")
+	}
+
+	dropped, hits := filter.Stats()
+	if dropped != 1 {
+		t.Errorf("Expected 1 dropped report, got %d", dropped)
+	}
+	if hits["extension-blocked-uri"] != 1 || hits["tag-cdn"] != 1 || hits["downgrade-img"] != 1 {
+		t.Errorf("Unexpected rule hits: %v", hits)
+	}
+}
+
+func TestLoadFilterRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	content := `[{"name": "ads", "field": "blocked_uri", "regex": "adserver", "action": "drop"}]`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	rules, err := LoadFilterRules(path)
+	if err != nil {
+		t.Fatalf("LoadFilterRules failed: %v", err)
+	}
+	if len(rules) != 1 || rules[0].Name != "ads" || rules[0].Action != models.FilterActionDrop {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	if _, err := LoadFilterRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestLoadRouteRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "routes.json")
+	content := `[{"pattern": "/orders/:id"}, {"host": "blog.example.com", "regex": "^/\\d{4}/.+$", "route": "/{year}/{slug}"}]`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write rules: %v", err)
+	}
+
+	rules, err := LoadRouteRules(path)
+	if err != nil {
+		t.Fatalf("LoadRouteRules failed: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Pattern != "/orders/:id" || rules[1].Host != "blog.example.com" {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	if _, err := LoadRouteRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,241 @@
+package processor
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+// InlineScript groups inline violations on the same page from the same source
+// location with the same sample.
+type InlineScript struct {
+	Page         string           `json:"page"`
+	Directive    string           `json:"directive"`
+	SourceFile   string           `json:"source_file,omitempty"`
+	LineNumber   int              `json:"line_number,omitempty"`
+	ColumnNumber int              `json:"column_number,omitempty"`
+	Sample       string           `json:"sample,omitempty"`
+	FirstSeen    time.Time        `json:"first_seen"`
+	LastSeen     time.Time        `json:"last_seen"`
+	Count        int64            `json:"count"`
+	Hashes       map[string]int64 `json:"hashes,omitempty"`
+	ComputedHash string           `json:"computed_hash,omitempty"`
+}
+
+// InlineHash is a candidate hash for a page with the reports supporting it.
+// Computed hashes come from complete script samples rather than the browser.
+type InlineHash struct {
+	Hash      string `json:"hash"`
+	Directive string `json:"directive"`
+	Count     int64  `json:"count"`
+	Computed  bool   `json:"computed"`
+}
+
+// InlinePage lists the inline violations of a page and the hashes that would
+// allow them.
+type InlinePage struct {
+	Page    string         `json:"page"`
+	Count   int64          `json:"count"`
+	Hashes  []InlineHash   `json:"hashes"`
+	Scripts []InlineScript `json:"scripts"`
+}
+
+type inlineKey struct {
+	page      string
+	directive string
+	file      string
+	line      int
+	column    int
+	sample    string
+}
+
+// InlineStore tracks inline script and style violations in memory. When full,
+// the least recently seen group is evicted.
+type InlineStore struct {
+	mu      sync.RWMutex
+	scripts *lru[inlineKey, *InlineScript]
+}
+
+func NewInlineStore(maxGroups int) *InlineStore {
+	return &InlineStore{
+		scripts: newLRU[inlineKey, *InlineScript](maxGroups),
+	}
+}
+
+// Record adds an inline violation to its group. Other reports are ignored.
+func (s *InlineStore) Record(report *models.CSPReport) {
+	parsed := report.ParsedReport
+	if parsed == nil || !parsed.IsInline() {
+		return
+	}
+
+	directive := parsed.DirectiveName()
+	if !strings.HasPrefix(directive, "script-src") && !strings.HasPrefix(directive, "style-src") {
+		return
+	}
+
+	key := inlineKey{
+		page:      pageURL(parsed.DocumentURI),
+		directive: directive,
+		file:      pageURL(parsed.SourceFile),
+		line:      intValue(parsed.LineNumber),
+		column:    intValue(parsed.ColumnNumber),
+		sample:    parsed.ScriptSample,
+	}
+	hash, computed := report.InlineHash()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	script, ok := s.scripts.Touch(key)
+	if !ok {
+		script = &InlineScript{
+			Page:         key.page,
+			Directive:    key.directive,
+			SourceFile:   key.file,
+			LineNumber:   key.line,
+			ColumnNumber: key.column,
+			Sample:       key.sample,
+			FirstSeen:    report.Timestamp,
+		}
+		s.scripts.Add(key, script)
+	}
+
+	script.Count++
+	if report.Timestamp.After(script.LastSeen) {
+		script.LastSeen = report.Timestamp
+	}
+	if report.Timestamp.Before(script.FirstSeen) {
+		script.FirstSeen = report.Timestamp
+	}
+
+	switch {
+	case hash != "" && computed:
+		script.ComputedHash = hash
+	case hash != "":
+		if script.Hashes == nil {
+			script.Hashes = make(map[string]int64)
+		}
+		script.Hashes[hash]++
+	}
+}
+
+// Pages returns the inline violations grouped by page, busiest page first. A
+// non-empty page only returns that page.
+func (s *InlineStore) Pages(page string) []InlinePage {
+	s.mu.RLock()
+	byPage := make(map[string]*InlinePage)
+	s.scripts.Range(func(key inlineKey, script *InlineScript) {
+		if page != "" && key.page != page {
+			return
+		}
+
+		entry, ok := byPage[key.page]
+		if !ok {
+			entry = &InlinePage{Page: key.page}
+			byPage[key.page] = entry
+		}
+		entry.Count += script.Count
+		entry.Scripts = append(entry.Scripts, script.clone())
+	})
+	s.mu.RUnlock()
+
+	pages := make([]InlinePage, 0, len(byPage))
+	for _, entry := range byPage {
+		sort.Slice(entry.Scripts, func(i, j int) bool {
+			if entry.Scripts[i].Count != entry.Scripts[j].Count {
+				return entry.Scripts[i].Count > entry.Scripts[j].Count
+			}
+			return entry.Scripts[i].Sample < entry.Scripts[j].Sample
+		})
+		entry.Hashes = candidateHashes(entry.Scripts)
+		pages = append(pages, *entry)
+	}
+
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].Count != pages[j].Count {
+			return pages[i].Count > pages[j].Count
+		}
+		return pages[i].Page < pages[j].Page
+	})
+	return pages
+}
+
+// Len returns the number of tracked groups.
+func (s *InlineStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.scripts.Len()
+}
+
+// candidateHashes merges the reported and computed hashes of a page's
+// scripts, most reported first.
+func candidateHashes(scripts []InlineScript) []InlineHash {
+	type hashKey struct {
+		hash      string
+		directive string
+	}
+
+	candidates := make(map[hashKey]*InlineHash)
+	add := func(hash, directive string, count int64, computed bool) {
+		key := hashKey{hash, directive}
+		candidate, ok := candidates[key]
+		if !ok {
+			candidate = &InlineHash{Hash: hash, Directive: directive, Computed: computed}
+			candidates[key] = candidate
+		}
+		candidate.Count += count
+		// A hash the browser reported needs no caveat
+		candidate.Computed = candidate.Computed && computed
+	}
+
+	for _, script := range scripts {
+		for hash, count := range script.Hashes {
+			add(hash, script.Directive, count, false)
+		}
+		if script.ComputedHash != "" {
+			add(script.ComputedHash, script.Directive, script.Count, true)
+		}
+	}
+
+	hashes := make([]InlineHash, 0, len(candidates))
+	for _, candidate := range candidates {
+		hashes = append(hashes, *candidate)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		if hashes[i].Count != hashes[j].Count {
+			return hashes[i].Count > hashes[j].Count
+		}
+		return hashes[i].Hash < hashes[j].Hash
+	})
+	return hashes
+}
+
+func (s *InlineScript) clone() InlineScript {
+	c := *s
+	if s.Hashes != nil {
+		c.Hashes = make(map[string]int64, len(s.Hashes))
+		for hash, count := range s.Hashes {
+			c.Hashes[hash] = count
+		}
+	}
+	return c
+}
+
+// pageURL removes the query string and fragment from a URL.
+func pageURL(raw string) string {
+	if i := strings.IndexAny(raw, "?#"); i >= 0 {
+		return raw[:i]
+	}
+	return raw
+}
+
+func intValue(value *int) int {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+func newInlineReport(documentURI, directive, sample, sha256 string, line int) *models.CSPReport {
+	return &models.CSPReport{
+		Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		ParsedReport: &models.ParsedCSPReport{
+			DocumentURI:        documentURI,
+			EffectiveDirective: directive,
+			BlockedURI:         "inline",
+			ScriptSample:       sample,
+			SHA256:             sha256,
+			SourceFile:         documentURI,
+			LineNumber:         &line,
+		},
+	}
+}
+
+func TestInlineStore_GroupsByPageLocationAndSample(t *testing.T) {
+	store := NewInlineStore(100)
+
+	store.Record(newInlineReport("https://shop.example.com/cart?session=1", "script-src-elem", "alert(1)", "", 10))
+	store.Record(newInlineReport("https://shop.example.com/cart?session=2", "script-src-elem", "alert(1)", "", 10))
+	store.Record(newInlineReport("https://shop.example.com/cart", "script-src-elem", "", "sha256-abc=", 20))
+	store.Record(newInlineReport("https://shop.example.com/cart", "script-src-elem", "", "abc=", 20))
+	store.Record(newInlineReport("https://shop.example.com/cart", "script-src-elem", "window.dataLayer = window.dataLayer || [", "", 30))
+	store.Record(newInlineReport("https://shop.example.com/", "style-src-attr", "color: red", "", 1))
+
+	// Not inline, or not a script or style directive
+	external := newInlineReport("https://shop.example.com/cart", "script-src-elem", "", "", 1)
+	external.ParsedReport.BlockedURI = "https://cdn.example.net/x.js"
+	store.Record(external)
+	store.Record(newInlineReport("https://shop.example.com/cart", "img-src", "", "", 1))
+
+	if store.Len() != 4 {
+		t.Fatalf("Expected 4 groups, got %d", store.Len())
+	}
+
+	pages := store.Pages("")
+	if len(pages) != 2 {
+		t.Fatalf("Expected 2 pages, got %d", len(pages))
+	}
+
+	cart := pages[0]
+	if cart.Page != "https://shop.example.com/cart" || cart.Count != 5 {
+		t.Errorf("Expected cart page with 5 reports first, got %s with %d", cart.Page, cart.Count)
+	}
+	if len(cart.Scripts) != 3 {
+		t.Errorf("Expected 3 scripts on the cart page, got %d", len(cart.Scripts))
+	}
+
+	expected := []InlineHash{
+		{Hash: "'sha256-abc='", Directive: "script-src-elem", Count: 2},
+		{Hash: "'sha256-bhHHL3z2vDgxUt0W3dWQOrprscmda2Y5pLsLg4GF+pI='", Directive: "script-src-elem", Count: 2, Computed: true},
+	}
+	if len(cart.Hashes) != len(expected) {
+		t.Fatalf("Expected %d hashes, got %+v", len(expected), cart.Hashes)
+	}
+	for i := range expected {
+		if cart.Hashes[i] != expected[i] {
+			t.Errorf("Expected hash %+v, got %+v", expected[i], cart.Hashes[i])
+		}
+	}
+
+	if filtered := store.Pages("https://shop.example.com/"); len(filtered) != 1 || filtered[0].Scripts[0].Directive != "style-src-attr" {
+		t.Errorf("Expected only the home page, got %+v", filtered)
+	}
+}
+
+func TestInlineStore_EvictsOldest(t *testing.T) {
+	store := NewInlineStore(2)
+
+	for i, sample := range []string{"a()", "b()", "c()"} {
+		report := newInlineReport("https://shop.example.com/", "script-src-elem", sample, "", 1)
+		report.Timestamp = report.Timestamp.Add(time.Duration(i) * time.Minute)
+		store.Record(report)
+	}
+
+	scripts := store.Pages("")[0].Scripts
+	if len(scripts) != 2 {
+		t.Fatalf("Expected 2 groups, got %d", len(scripts))
+	}
+	for _, script := range scripts {
+		if script.Sample == "a()" {
+			t.Error("Expected the oldest group to be evicted")
+		}
+	}
+}
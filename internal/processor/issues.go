@@ -0,0 +1,145 @@
+package processor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+// Issue groups all reports sharing a fingerprint.
+type Issue struct {
+	Fingerprint   string                  `json:"fingerprint"`
+	Input         models.FingerprintInput `json:"input"`
+	FirstSeen     time.Time               `json:"first_seen"`
+	LastSeen      time.Time               `json:"last_seen"`
+	Count         int64                   `json:"count"`
+	AffectedPages []string                `json:"affected_pages"`
+	Browsers      map[string]int64        `json:"browsers"`
+}
+
+// IssueStore tracks issues in memory. When full, the least recently seen
+// issue is evicted; each issue keeps at most maxPages distinct pages.
+type IssueStore struct {
+	mu       sync.RWMutex
+	issues   *lru[string, *Issue]
+	maxPages int
+}
+
+func NewIssueStore(maxIssues, maxPages int) *IssueStore {
+	return &IssueStore{
+		issues:   newLRU[string, *Issue](maxIssues),
+		maxPages: maxPages,
+	}
+}
+
+// Record adds a report to the issue matching its fingerprint, creating the
+// issue on first sight. It returns true when a new issue was created.
+func (s *IssueStore) Record(report *models.CSPReport) bool {
+	if report.Fingerprint == "" || report.ParsedReport == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	issue, ok := s.issues.Touch(report.Fingerprint)
+	if !ok {
+		issue = &Issue{
+			Fingerprint: report.Fingerprint,
+			Input:       report.FingerprintInput(),
+			FirstSeen:   report.Timestamp,
+			Browsers:    make(map[string]int64),
+		}
+		s.issues.Add(report.Fingerprint, issue)
+	}
+
+	issue.Count++
+	if report.Timestamp.After(issue.LastSeen) {
+		issue.LastSeen = report.Timestamp
+	}
+	if report.Timestamp.Before(issue.FirstSeen) {
+		issue.FirstSeen = report.Timestamp
+	}
+	issue.Browsers[report.BrowserType]++
+
+	page := report.ParsedReport.DocumentURI
+	if page != "" && len(issue.AffectedPages) < s.maxPages && !containsString(issue.AffectedPages, page) {
+		issue.AffectedPages = append(issue.AffectedPages, page)
+	}
+
+	return !ok
+}
+
+// Get returns a copy of the issue with the given fingerprint.
+func (s *IssueStore) Get(fingerprint string) (Issue, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	issue, ok := s.issues.Peek(fingerprint)
+	if !ok {
+		return Issue{}, false
+	}
+	return issue.clone(), true
+}
+
+// List returns up to limit issues ordered by descending count. A limit of
+// zero or less returns all issues.
+func (s *IssueStore) List(limit int) []Issue {
+	s.mu.RLock()
+	issues := make([]Issue, 0, s.issues.Len())
+	s.issues.Range(func(_ string, issue *Issue) {
+		issues = append(issues, issue.clone())
+	})
+	s.mu.RUnlock()
+
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Count != issues[j].Count {
+			return issues[i].Count > issues[j].Count
+		}
+		return issues[i].Fingerprint < issues[j].Fingerprint
+	})
+
+	if limit > 0 && len(issues) > limit {
+		issues = issues[:limit]
+	}
+	return issues
+}
+
+// Count returns the number of reports recorded for a fingerprint.
+func (s *IssueStore) Count(fingerprint string) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if issue, ok := s.issues.Peek(fingerprint); ok {
+		return issue.Count
+	}
+	return 0
+}
+
+// Len returns the number of tracked issues.
+func (s *IssueStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.issues.Len()
+}
+
+func (i *Issue) clone() Issue {
+	c := *i
+	c.AffectedPages = append([]string(nil), i.AffectedPages...)
+	c.Browsers = make(map[string]int64, len(i.Browsers))
+	for browser, count := range i.Browsers {
+		c.Browsers[browser] = count
+	}
+	return c
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
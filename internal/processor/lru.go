@@ -0,0 +1,74 @@
+package processor
+
+import "container/list"
+
+// lru is a map that remembers the order its entries were last touched in, so
+// the least recently used one can be evicted in constant time. It is not safe
+// for concurrent use; the stores guard it with their own lock, and readers
+// holding a read lock must only use the methods that do not touch the order.
+type lru[K comparable, V any] struct {
+	capacity int
+	order    *list.List
+	entries  map[K]*list.Element
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// newLRU returns an lru holding at most capacity entries, or any number when
+// capacity is zero or less.
+func newLRU[K comparable, V any](capacity int) *lru[K, V] {
+	return &lru[K, V]{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[K]*list.Element),
+	}
+}
+
+// Touch returns the value of key and marks it as most recently used.
+func (c *lru[K, V]) Touch(key K) (V, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// Peek returns the value of key without changing the order.
+func (c *lru[K, V]) Peek(key K) (V, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+	return element.Value.(*lruEntry[K, V]).value, true
+}
+
+// Add inserts key as most recently used, evicting the least recently used
+// entry when full. The key must not be present yet.
+func (c *lru[K, V]) Add(key K, value V) {
+	if c.capacity > 0 && c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry[K, V]).key)
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{key: key, value: value})
+}
+
+// Range calls fn for every entry, most recently used first, without changing
+// the order.
+func (c *lru[K, V]) Range(fn func(key K, value V)) {
+	for element := c.order.Front(); element != nil; element = element.Next() {
+		entry := element.Value.(*lruEntry[K, V])
+		fn(entry.key, entry.value)
+	}
+}
+
+// Len returns the number of entries.
+func (c *lru[K, V]) Len() int {
+	return c.order.Len()
+}
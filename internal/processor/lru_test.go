@@ -0,0 +1,42 @@
+package processor
+
+import "testing"
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newLRU[string, int](2)
+
+	cache.Add("a", 1)
+	cache.Add("b", 2)
+	if _, ok := cache.Touch("a"); !ok {
+		t.Fatal("Expected a to be present")
+	}
+	cache.Add("c", 3)
+
+	if _, ok := cache.Peek("b"); ok {
+		t.Error("Expected b to be evicted")
+	}
+	if value, ok := cache.Peek("a"); !ok || value != 1 {
+		t.Errorf("Expected a=1, got %d (%v)", value, ok)
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+
+	var keys []string
+	cache.Range(func(key string, _ int) {
+		keys = append(keys, key)
+	})
+	if len(keys) != 2 || keys[0] != "c" || keys[1] != "a" {
+		t.Errorf("Expected [c a], got %v", keys)
+	}
+}
+
+func TestLRU_Unbounded(t *testing.T) {
+	cache := newLRU[int, int](0)
+	for i := 0; i < 100; i++ {
+		cache.Add(i, i)
+	}
+	if cache.Len() != 100 {
+		t.Errorf("Expected 100 entries, got %d", cache.Len())
+	}
+}
@@ -0,0 +1,350 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+// RedactionRule scrubs every match of a regular expression from URIs and
+// script samples, replacing it with Replacement.
+type RedactionRule struct {
+	Name        string `json:"name"`
+	Regex       string `json:"regex"`
+	Replacement string `json:"replacement,omitempty"`
+
+	re *regexp.Regexp
+	// valid, when set, must accept a match for it to be replaced
+	valid func(string) bool
+}
+
+// builtinScrubbers are the scrubbers that can be enabled by name.
+var builtinScrubbers = map[string]RedactionRule{
+	"email": {
+		Regex:       `[A-Za-z0-9._%+-]+(?:@|%40)[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+		Replacement: "[email]",
+	},
+	"jwt": {
+		Regex:       `eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+		Replacement: "[jwt]",
+	},
+	"credit_card": {
+		Regex:       `\b(?:\d[ -]?){12,18}\d\b`,
+		Replacement: "[card]",
+		valid:       luhnValid,
+	},
+}
+
+// Audit rule names of the URI and sample redactions.
+const (
+	redactQuery      = "query"
+	redactQueryParam = "query_param"
+	redactFragment   = "fragment"
+	redactTruncate   = "truncate"
+)
+
+// rawURIKeys and rawSampleKeys are the lowercased raw report keys holding
+// URIs and script samples, in every spelling the parser accepts.
+var (
+	rawURIKeys = map[string]bool{
+		"documenturl": true, "document-url": true, "document-uri": true, "documenturi": true, "document_uri": true, "document_url": true,
+		"blockedurl": true, "blockeduri": true, "blocked-url": true, "blocked-uri": true, "blocked_uri": true, "blocked_url": true,
+		"sourcefile": true, "source-file": true, "source_file": true,
+		"referrer": true, "url": true,
+	}
+	rawSampleKeys = map[string]bool{
+		"sample": true, "script-sample": true, "scriptsample": true, "script_sample": true,
+	}
+)
+
+// Redactor removes personal data from reports: query parameters by name or
+// the whole query string, fragments, scrubber matches and overly long script
+// samples. It applies to the parsed URIs and sample and to the same values in
+// the raw report, and records what it changed on the report.
+type Redactor struct {
+	stripQuery    bool
+	queryParams   map[string]bool
+	stripFragment bool
+	scrubbers     []RedactionRule
+	sampleLength  int
+
+	redacted int64
+	mu       sync.Mutex
+	hits     map[string]int64
+}
+
+// NewRedactor builds a redactor from the configuration and the custom rules.
+// Unknown scrubbers and invalid rules are skipped and returned as errors so
+// the caller can report them.
+func NewRedactor(cfg config.RedactionConfig, rules []RedactionRule) (*Redactor, []error) {
+	r := &Redactor{
+		stripQuery:    cfg.StripQuery,
+		queryParams:   make(map[string]bool, len(cfg.QueryParams)),
+		stripFragment: cfg.StripFragment,
+		sampleLength:  cfg.SampleLength,
+		hits:          make(map[string]int64),
+	}
+	for _, param := range cfg.QueryParams {
+		r.queryParams[strings.ToLower(param)] = true
+	}
+
+	var errs []error
+	var all []RedactionRule
+	for _, name := range cfg.Scrubbers {
+		rule, ok := builtinScrubbers[name]
+		if !ok {
+			errs = append(errs, fmt.Errorf("unknown scrubber %q", name))
+			continue
+		}
+		rule.Name = name
+		all = append(all, rule)
+	}
+
+	for _, rule := range append(all, rules...) {
+		if rule.Name == "" {
+			errs = append(errs, fmt.Errorf("redaction rule has no name"))
+			continue
+		}
+		re, err := regexp.Compile(rule.Regex)
+		if err != nil || rule.Regex == "" {
+			errs = append(errs, fmt.Errorf("redaction rule %q: invalid regex", rule.Name))
+			continue
+		}
+		if rule.Replacement == "" {
+			rule.Replacement = "[redacted]"
+		}
+		rule.re = re
+		r.scrubbers = append(r.scrubbers, rule)
+	}
+
+	return r, errs
+}
+
+// LoadRedactionRules reads a JSON array of redaction rules from a file.
+func LoadRedactionRules(path string) ([]RedactionRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction rules: %w", err)
+	}
+
+	var rules []RedactionRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse redaction rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// Apply redacts a report in place and returns whether anything changed.
+func (r *Redactor) Apply(report *models.CSPReport) bool {
+	audit := &redactionAudit{}
+
+	if parsed := report.ParsedReport; parsed != nil {
+		parsed.DocumentURI = r.redactURI(parsed.DocumentURI, "document_uri", audit)
+		parsed.Referrer = r.redactURI(parsed.Referrer, "referrer", audit)
+		parsed.BlockedURI = r.redactURI(parsed.BlockedURI, "blocked_uri", audit)
+		parsed.SourceFile = r.redactURI(parsed.SourceFile, "source_file", audit)
+		parsed.ScriptSample = r.redactSample(parsed.ScriptSample, models.ScriptSampleField, audit)
+	}
+	if report.RawReport != nil {
+		r.redactRaw(report.RawReport, "raw_report", audit)
+	}
+
+	if len(audit.entries) == 0 {
+		return false
+	}
+
+	report.Redactions = append(report.Redactions, audit.entries...)
+	report.RefreshHumanReadable()
+
+	atomic.AddInt64(&r.redacted, 1)
+	r.mu.Lock()
+	for _, entry := range audit.entries {
+		r.hits[entry.Rule] += int64(entry.Count)
+	}
+	r.mu.Unlock()
+	return true
+}
+
+// Stats returns the number of redacted reports and the redactions per rule.
+func (r *Redactor) Stats() (int64, map[string]int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hits := make(map[string]int64, len(r.hits))
+	for rule, count := range r.hits {
+		hits[rule] = count
+	}
+	return atomic.LoadInt64(&r.redacted), hits
+}
+
+// redactURI removes the fragment and query parameters of a URI, then applies
+// the scrubbers. The query string is edited as text so the rest of the URI
+// is kept byte for byte.
+func (r *Redactor) redactURI(raw, field string, audit *redactionAudit) string {
+	if raw == "" {
+		return raw
+	}
+
+	base, fragment, hasFragment := strings.Cut(raw, "#")
+	if hasFragment && r.stripFragment {
+		audit.add(field, redactFragment, 1)
+		hasFragment = false
+	}
+
+	path, query, hasQuery := strings.Cut(base, "?")
+	if hasQuery {
+		query = r.redactQuery(query, field, audit)
+		hasQuery = query != ""
+	}
+
+	result := path
+	if hasQuery {
+		result += "?" + query
+	}
+	if hasFragment {
+		result += "#" + fragment
+	}
+	return r.scrub(result, field, audit)
+}
+
+func (r *Redactor) redactQuery(query, field string, audit *redactionAudit) string {
+	if query == "" {
+		return ""
+	}
+	if r.stripQuery {
+		audit.add(field, redactQuery, 1)
+		return ""
+	}
+
+	params := strings.Split(query, "&")
+	kept := params[:0]
+	for _, param := range params {
+		name, _, _ := strings.Cut(param, "=")
+		if decoded, err := url.QueryUnescape(name); err == nil {
+			name = decoded
+		}
+		if r.queryParams[strings.ToLower(name)] {
+			audit.add(field, redactQueryParam+":"+strings.ToLower(name), 1)
+			continue
+		}
+		kept = append(kept, param)
+	}
+	return strings.Join(kept, "&")
+}
+
+// redactSample applies the scrubbers to a script sample and truncates it.
+func (r *Redactor) redactSample(sample, field string, audit *redactionAudit) string {
+	if sample == "" {
+		return sample
+	}
+
+	sample = r.scrub(sample, field, audit)
+	if r.sampleLength > 0 {
+		if runes := []rune(sample); len(runes) > r.sampleLength {
+			sample = string(runes[:r.sampleLength])
+			audit.add(field, redactTruncate, 1)
+		}
+	}
+	return sample
+}
+
+func (r *Redactor) scrub(value, field string, audit *redactionAudit) string {
+	for _, rule := range r.scrubbers {
+		count := 0
+		value = rule.re.ReplaceAllStringFunc(value, func(match string) string {
+			if rule.valid != nil && !rule.valid(match) {
+				return match
+			}
+			count++
+			return rule.Replacement
+		})
+		if count > 0 {
+			audit.add(field, rule.Name, count)
+		}
+	}
+	return value
+}
+
+// redactRaw walks the raw report, treating values under URI and sample keys
+// like the parsed fields and scrubbing every other string.
+func (r *Redactor) redactRaw(value map[string]interface{}, path string, audit *redactionAudit) {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	// Sorted so the audit order is stable
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value[key] = r.redactRawValue(value[key], key, path+"."+key, audit)
+	}
+}
+
+func (r *Redactor) redactRawValue(value interface{}, key, path string, audit *redactionAudit) interface{} {
+	switch v := value.(type) {
+	case string:
+		switch lower := strings.ToLower(key); {
+		case rawURIKeys[lower]:
+			return r.redactURI(v, path, audit)
+		case rawSampleKeys[lower]:
+			return r.redactSample(v, path, audit)
+		default:
+			return r.scrub(v, path, audit)
+		}
+	case map[string]interface{}:
+		r.redactRaw(v, path, audit)
+	case []interface{}:
+		for i := range v {
+			v[i] = r.redactRawValue(v[i], key, fmt.Sprintf("%s.%d", path, i), audit)
+		}
+	}
+	return value
+}
+
+// redactionAudit collects the redactions of one report, merging repeated
+// hits of a rule on the same field.
+type redactionAudit struct {
+	entries []models.Redaction
+}
+
+func (a *redactionAudit) add(field, rule string, count int) {
+	for i := range a.entries {
+		if a.entries[i].Field == field && a.entries[i].Rule == rule {
+			a.entries[i].Count += count
+			return
+		}
+	}
+	a.entries = append(a.entries, models.Redaction{Field: field, Rule: rule, Count: count})
+}
+
+// luhnValid reports whether the digits of a number pass the Luhn checksum,
+// which keeps long numeric IDs from being taken for card numbers.
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
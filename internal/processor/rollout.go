@@ -0,0 +1,209 @@
+package processor
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+// maxRolloutViolations bounds the report-only violations kept per policy.
+const maxRolloutViolations = 1000
+
+// PolicyRollout summarizes the reports received for one policy, identified by
+// the fingerprint of the original policy, split by disposition. Violations
+// lists what the policy blocks while report-only, i.e. what would break if it
+// were enforced; it is only filled for a single rollout.
+type PolicyRollout struct {
+	PolicyFingerprint  string             `json:"policy_fingerprint"`
+	Policy             string             `json:"policy"`
+	FirstSeen          time.Time          `json:"first_seen"`
+	LastSeen           time.Time          `json:"last_seen"`
+	Enforced           int64              `json:"enforced"`
+	ReportOnly         int64              `json:"report_only"`
+	LastReportOnly     *time.Time         `json:"last_report_only,omitempty"`
+	BreakingViolations int                `json:"breaking_violations"`
+	AffectedPages      int                `json:"affected_pages"`
+	Violations         []RolloutViolation `json:"violations,omitempty"`
+	violations         *lru[string, *RolloutViolation]
+}
+
+// RolloutViolation is a report-only violation of a policy grouped by issue
+// fingerprint.
+type RolloutViolation struct {
+	Fingerprint string                  `json:"fingerprint"`
+	Input       models.FingerprintInput `json:"input"`
+	FirstSeen   time.Time               `json:"first_seen"`
+	LastSeen    time.Time               `json:"last_seen"`
+	Count       int64                   `json:"count"`
+	Pages       []string                `json:"pages"`
+}
+
+// RolloutStore tracks policy rollouts in memory. When full, the least
+// recently seen policy is evicted.
+type RolloutStore struct {
+	mu       sync.RWMutex
+	policies *lru[string, *PolicyRollout]
+	maxPages int
+}
+
+func NewRolloutStore(maxPolicies, maxPages int) *RolloutStore {
+	return &RolloutStore{
+		policies: newLRU[string, *PolicyRollout](maxPolicies),
+		maxPages: maxPages,
+	}
+}
+
+// Record counts a report towards its policy. Reports without an original
+// policy or a known disposition are ignored.
+func (s *RolloutStore) Record(report *models.CSPReport) {
+	parsed := report.ParsedReport
+	if parsed == nil || report.PolicyFingerprint == "" {
+		return
+	}
+	disposition := parsed.DispositionName()
+	if disposition == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rollout, ok := s.policies.Touch(report.PolicyFingerprint)
+	if !ok {
+		rollout = &PolicyRollout{
+			PolicyFingerprint: report.PolicyFingerprint,
+			Policy:            parsed.OriginalPolicy,
+			FirstSeen:         report.Timestamp,
+			violations:        newLRU[string, *RolloutViolation](maxRolloutViolations),
+		}
+		s.policies.Add(report.PolicyFingerprint, rollout)
+	}
+
+	if report.Timestamp.After(rollout.LastSeen) {
+		rollout.LastSeen = report.Timestamp
+	}
+	if report.Timestamp.Before(rollout.FirstSeen) {
+		rollout.FirstSeen = report.Timestamp
+	}
+
+	if disposition == models.DispositionEnforce {
+		rollout.Enforced++
+		return
+	}
+
+	rollout.ReportOnly++
+	if rollout.LastReportOnly == nil || report.Timestamp.After(*rollout.LastReportOnly) {
+		timestamp := report.Timestamp
+		rollout.LastReportOnly = &timestamp
+	}
+
+	if report.Fingerprint == "" {
+		return
+	}
+
+	violation, ok := rollout.violations.Touch(report.Fingerprint)
+	if !ok {
+		violation = &RolloutViolation{
+			Fingerprint: report.Fingerprint,
+			Input:       report.FingerprintInput(),
+			FirstSeen:   report.Timestamp,
+		}
+		rollout.violations.Add(report.Fingerprint, violation)
+	}
+
+	violation.Count++
+	if report.Timestamp.After(violation.LastSeen) {
+		violation.LastSeen = report.Timestamp
+	}
+	if report.Timestamp.Before(violation.FirstSeen) {
+		violation.FirstSeen = report.Timestamp
+	}
+
+	page := pageURL(parsed.DocumentURI)
+	if page != "" && len(violation.Pages) < s.maxPages && !containsString(violation.Pages, page) {
+		violation.Pages = append(violation.Pages, page)
+	}
+}
+
+// List returns all rollouts without their violations, most recently seen
+// first.
+func (s *RolloutStore) List() []PolicyRollout {
+	s.mu.RLock()
+	rollouts := make([]PolicyRollout, 0, s.policies.Len())
+	s.policies.Range(func(_ string, rollout *PolicyRollout) {
+		rollouts = append(rollouts, rollout.summary())
+	})
+	s.mu.RUnlock()
+
+	sort.Slice(rollouts, func(i, j int) bool {
+		if !rollouts[i].LastSeen.Equal(rollouts[j].LastSeen) {
+			return rollouts[i].LastSeen.After(rollouts[j].LastSeen)
+		}
+		return rollouts[i].PolicyFingerprint < rollouts[j].PolicyFingerprint
+	})
+	return rollouts
+}
+
+// Get returns a rollout with its report-only violations ordered by
+// descending count.
+func (s *RolloutStore) Get(policyFingerprint string) (PolicyRollout, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rollout, ok := s.policies.Peek(policyFingerprint)
+	if !ok {
+		return PolicyRollout{}, false
+	}
+
+	result := rollout.summary()
+	result.Violations = make([]RolloutViolation, 0, rollout.violations.Len())
+	rollout.violations.Range(func(_ string, violation *RolloutViolation) {
+		v := *violation
+		v.Pages = append([]string(nil), violation.Pages...)
+		result.Violations = append(result.Violations, v)
+	})
+
+	sort.Slice(result.Violations, func(i, j int) bool {
+		if result.Violations[i].Count != result.Violations[j].Count {
+			return result.Violations[i].Count > result.Violations[j].Count
+		}
+		return result.Violations[i].Fingerprint < result.Violations[j].Fingerprint
+	})
+	return result, true
+}
+
+// Len returns the number of tracked policies.
+func (s *RolloutStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policies.Len()
+}
+
+// summary copies the rollout without its violations, counting the distinct
+// affected pages. The caller must hold the store lock.
+func (r *PolicyRollout) summary() PolicyRollout {
+	pages := make(map[string]struct{})
+	r.violations.Range(func(_ string, violation *RolloutViolation) {
+		for _, page := range violation.Pages {
+			pages[page] = struct{}{}
+		}
+	})
+
+	c := PolicyRollout{
+		PolicyFingerprint:  r.PolicyFingerprint,
+		Policy:             r.Policy,
+		FirstSeen:          r.FirstSeen,
+		LastSeen:           r.LastSeen,
+		Enforced:           r.Enforced,
+		ReportOnly:         r.ReportOnly,
+		BreakingViolations: r.violations.Len(),
+		AffectedPages:      len(pages),
+	}
+	if r.LastReportOnly != nil {
+		last := *r.LastReportOnly
+		c.LastReportOnly = &last
+	}
+	return c
+}
@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
+
+const rolloutPolicy = "default-src 'self'; script-src 'self'"
+
+func newRolloutReport(disposition, documentURI, blockedURI string, at time.Time) *models.CSPReport {
+	report := newTestReport(documentURI, blockedURI, at)
+	report.ParsedReport.Disposition = disposition
+	report.ParsedReport.OriginalPolicy = rolloutPolicy
+	report.PolicyFingerprint = models.PolicyFingerprint(rolloutPolicy)
+	return report
+}
+
+func TestRolloutStore_SplitsByDisposition(t *testing.T) {
+	store := NewRolloutStore(10, 10)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	store.Record(newRolloutReport("enforce", "https://example.com/a", "https://evil.com/x.js", base))
+	store.Record(newRolloutReport("report", "https://example.com/a?x=1", "https://cdn.example.net/x.js", base.Add(time.Minute)))
+	store.Record(newRolloutReport("report", "https://example.com/a?x=2", "https://cdn.example.net/x.js", base.Add(2*time.Minute)))
+	store.Record(newRolloutReport("Report", "https://example.com/c", "https://fonts.example.org/x.js", base.Add(3*time.Minute)))
+
+	// No disposition or no original policy
+	store.Record(newRolloutReport("", "https://example.com/a", "https://evil.com/x.js", base))
+	unknown := newRolloutReport("report", "https://example.com/a", "https://evil.com/x.js", base)
+	unknown.PolicyFingerprint = ""
+	store.Record(unknown)
+
+	rollouts := store.List()
+	if len(rollouts) != 1 {
+		t.Fatalf("Expected 1 policy, got %d", len(rollouts))
+	}
+
+	rollout := rollouts[0]
+	if rollout.Policy != rolloutPolicy {
+		t.Errorf("Expected policy %q, got %q", rolloutPolicy, rollout.Policy)
+	}
+	if rollout.Enforced != 1 || rollout.ReportOnly != 3 {
+		t.Errorf("Expected 1 enforced and 3 report-only, got %d and %d", rollout.Enforced, rollout.ReportOnly)
+	}
+	if rollout.BreakingViolations != 2 || rollout.AffectedPages != 2 {
+		t.Errorf("Expected 2 breaking violations on 2 pages, got %d on %d", rollout.BreakingViolations, rollout.AffectedPages)
+	}
+	if rollout.LastReportOnly == nil || !rollout.LastReportOnly.Equal(base.Add(3*time.Minute)) {
+		t.Errorf("Unexpected last report-only violation: %v", rollout.LastReportOnly)
+	}
+	if rollout.Violations != nil {
+		t.Error("Expected List to omit violations")
+	}
+
+	detail, ok := store.Get(rollout.PolicyFingerprint)
+	if !ok {
+		t.Fatal("Expected rollout to be found")
+	}
+	if len(detail.Violations) != 2 {
+		t.Fatalf("Expected 2 violations, got %d", len(detail.Violations))
+	}
+	top := detail.Violations[0]
+	if top.Input.BlockedOrigin != "https://cdn.example.net" || top.Count != 2 || len(top.Pages) != 1 {
+		t.Errorf("Unexpected top violation: %+v", top)
+	}
+
+	if _, ok := store.Get("missing"); ok {
+		t.Error("Expected unknown policy not to be found")
+	}
+}
+
+func TestRolloutStore_EvictsOldestPolicy(t *testing.T) {
+	store := NewRolloutStore(1, 10)
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	first := newRolloutReport("report", "https://example.com/", "https://evil.com/x.js", base)
+	second := newRolloutReport("report", "https://example.com/", "https://evil.com/x.js", base.Add(time.Minute))
+	second.ParsedReport.OriginalPolicy = "default-src 'none'"
+	second.PolicyFingerprint = models.PolicyFingerprint("default-src 'none'")
+
+	store.Record(first)
+	store.Record(second)
+
+	rollouts := store.List()
+	if len(rollouts) != 1 || rollouts[0].PolicyFingerprint != second.PolicyFingerprint {
+		t.Errorf("Expected only the newest policy, got %+v", rollouts)
+	}
+}
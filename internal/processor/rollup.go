@@ -0,0 +1,241 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"universal-csp-report/internal/models"
+	"universal-csp-report/internal/storage"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rollupGrace delays flushing a closed bucket so reports still in flight
+// when the bucket ends are counted in it.
+const rollupGrace = 5 * time.Second
+
+type rollupInterval struct {
+	label    string
+	duration time.Duration
+}
+
+type rollupBucket struct {
+	rollup *models.Rollup
+	end    time.Time
+}
+
+// Aggregator maintains in-memory counts per time bucket and dimension
+// combination and periodically flushes closed buckets to storage. Counts are
+// taken before dedup and sampling so they stay exact.
+//
+// Memory is bounded twice: once maxBuckets buckets are open, new dimension
+// combinations are counted in an overflow bucket whose dimensions are all
+// models.RollupOverflow, and at most maxRetries rollups that failed to store
+// are kept for the next flush, the oldest being dropped first.
+type Aggregator struct {
+	intervals  []rollupInterval
+	dimensions []string
+	maxBuckets int
+	maxRetries int
+	store      storage.RollupStorage
+	logger     *logrus.Logger
+
+	mu      sync.Mutex
+	buckets map[string]*rollupBucket
+	retries []*models.Rollup
+
+	flushErrors int64
+	dropped     int64
+}
+
+// NewAggregator creates an aggregator for the given interval labels (e.g.
+// "1m", "1h") and dimension names. Limits of zero or less are unbounded.
+func NewAggregator(intervals, dimensions []string, maxBuckets, maxRetries int, store storage.RollupStorage, logger *logrus.Logger) (*Aggregator, error) {
+	a := &Aggregator{
+		maxBuckets: maxBuckets,
+		maxRetries: maxRetries,
+		store:      store,
+		logger:     logger,
+		buckets:    make(map[string]*rollupBucket),
+	}
+
+	for _, label := range intervals {
+		label = strings.TrimSpace(label)
+		duration, err := time.ParseDuration(label)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("invalid rollup interval %q", label)
+		}
+		a.intervals = append(a.intervals, rollupInterval{label: label, duration: duration})
+	}
+
+	for _, dimension := range dimensions {
+		dimension = strings.TrimSpace(dimension)
+		if !models.IsRollupDimension(dimension) {
+			return nil, fmt.Errorf("unknown rollup dimension %q", dimension)
+		}
+		a.dimensions = append(a.dimensions, dimension)
+	}
+
+	return a, nil
+}
+
+// Add counts a report in every configured interval.
+func (a *Aggregator) Add(report *models.CSPReport) {
+	values := make(map[string]string, len(a.dimensions))
+	for _, dimension := range a.dimensions {
+		values[dimension] = models.RollupDimensionValue(report, dimension)
+	}
+	dimensionKey := a.dimensionKey(values)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, interval := range a.intervals {
+		start := report.Timestamp.UTC().Truncate(interval.duration)
+		key := interval.label + "|" + start.Format(time.RFC3339) + "|" + dimensionKey
+
+		bucket, ok := a.buckets[key]
+		if !ok && a.maxBuckets > 0 && len(a.buckets) >= a.maxBuckets {
+			key = interval.label + "|" + start.Format(time.RFC3339) + "|" + models.RollupOverflow
+			bucket, ok = a.buckets[key]
+			if !ok {
+				bucket = a.newBucket(start, interval, a.overflowValues())
+				a.buckets[key] = bucket
+			}
+		} else if !ok {
+			bucket = a.newBucket(start, interval, values)
+			a.buckets[key] = bucket
+		}
+		bucket.rollup.Count++
+	}
+}
+
+func (a *Aggregator) newBucket(start time.Time, interval rollupInterval, values map[string]string) *rollupBucket {
+	return &rollupBucket{
+		rollup: &models.Rollup{
+			BucketStart: start,
+			Interval:    interval.label,
+			Dimensions:  values,
+		},
+		end: start.Add(interval.duration),
+	}
+}
+
+func (a *Aggregator) overflowValues() map[string]string {
+	values := make(map[string]string, len(a.dimensions))
+	for _, dimension := range a.dimensions {
+		values[dimension] = models.RollupOverflow
+	}
+	return values
+}
+
+// Flush writes buckets that closed before now, or all buckets when force is
+// set. Rollups that fail to store are kept and retried on the next flush.
+func (a *Aggregator) Flush(now time.Time, force bool) {
+	a.mu.Lock()
+	ready := a.retries
+	a.retries = nil
+	for key, bucket := range a.buckets {
+		if !force && bucket.end.Add(rollupGrace).After(now) {
+			continue
+		}
+		if bucket.rollup.ID == "" {
+			bucket.rollup.ID = rollupID(key, now)
+		}
+		ready = append(ready, bucket.rollup)
+		delete(a.buckets, key)
+	}
+	a.mu.Unlock()
+
+	if len(ready) == 0 {
+		return
+	}
+
+	if err := a.store.StoreRollups(ready); err != nil {
+		atomic.AddInt64(&a.flushErrors, 1)
+		a.logger.WithError(err).WithField("rollups", len(ready)).Error("Failed to store rollups")
+		a.requeue(ready)
+		return
+	}
+
+	a.logger.WithField("rollups", len(ready)).Debug("Rollups flushed")
+}
+
+// Stats returns the number of open buckets and rollups awaiting a retry, the
+// number of failed flushes and the number of rollups dropped from the retry
+// buffer.
+func (a *Aggregator) Stats() (pending int, flushErrors, dropped int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.buckets) + len(a.retries), atomic.LoadInt64(&a.flushErrors), atomic.LoadInt64(&a.dropped)
+}
+
+func (a *Aggregator) start(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+
+	ticker := time.NewTicker(a.tickInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			a.Flush(time.Now(), true)
+			return
+		case now := <-ticker.C:
+			a.Flush(now, false)
+		}
+	}
+}
+
+func (a *Aggregator) tickInterval() time.Duration {
+	shortest := time.Hour
+	for _, interval := range a.intervals {
+		if interval.duration < shortest {
+			shortest = interval.duration
+		}
+	}
+
+	tick := shortest / 6
+	if tick < time.Second {
+		tick = time.Second
+	}
+	return tick
+}
+
+// requeue puts rollups back so they are retried. Retried rollups keep their
+// ID so a partially applied earlier attempt is overwritten, not duplicated.
+// Beyond maxRetries the oldest rollups are dropped.
+func (a *Aggregator) requeue(rollups []*models.Rollup) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.retries = append(rollups, a.retries...)
+	if a.maxRetries > 0 && len(a.retries) > a.maxRetries {
+		overflow := len(a.retries) - a.maxRetries
+		atomic.AddInt64(&a.dropped, int64(overflow))
+		a.logger.WithField("rollups", overflow).Warn("Dropping rollups that could not be stored")
+		a.retries = a.retries[overflow:]
+	}
+}
+
+func (a *Aggregator) dimensionKey(values map[string]string) string {
+	parts := make([]string, len(a.dimensions))
+	for i, dimension := range a.dimensions {
+		parts[i] = values[dimension]
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// rollupID derives a document ID that is unique per bucket and flush, so
+// late reports for an already flushed bucket add a new rollup instead of
+// replacing the earlier count.
+func rollupID(key string, flushedAt time.Time) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d", key, flushedAt.UnixNano())))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,188 @@
+package processor
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/models"
+
+	"github.com/sirupsen/logrus"
+)
+
+type rollupRecorder struct {
+	mu      sync.Mutex
+	rollups []*models.Rollup
+	fail    bool
+}
+
+func (r *rollupRecorder) StoreRollups(rollups []*models.Rollup) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("storage unavailable")
+	}
+	r.rollups = append(r.rollups, rollups...)
+	return nil
+}
+
+func TestAggregator_CountsPerBucketAndDimension(t *testing.T) {
+	recorder := &rollupRecorder{}
+	aggregator, err := NewAggregator([]string{"1m", "1h"}, []string{"directive", "blocked_host"}, 0, 0, recorder, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	aggregator.Add(newTestReport("https://example.com/a", "https://evil.com/x.js", base))
+	aggregator.Add(newTestReport("https://example.com/b", "https://evil.com/y.js", base.Add(10*time.Second)))
+	aggregator.Add(newTestReport("https://example.com/a", "https://other.com/x.js", base.Add(20*time.Second)))
+	aggregator.Add(newTestReport("https://example.com/a", "https://evil.com/x.js", base.Add(70*time.Second)))
+
+	// Only the first minute bucket has closed
+	aggregator.Flush(base.Add(time.Minute+rollupGrace), false)
+
+	counts := make(map[string]int64)
+	for _, rollup := range recorder.rollups {
+		if rollup.Interval != "1m" || !rollup.BucketStart.Equal(base) {
+			t.Errorf("Unexpected rollup flushed: %s %s", rollup.Interval, rollup.BucketStart)
+		}
+		if rollup.ID == "" {
+			t.Error("Expected rollup ID to be set")
+		}
+		counts[rollup.Dimensions["blocked_host"]] += rollup.Count
+	}
+	if counts["evil.com"] != 2 || counts["other.com"] != 1 {
+		t.Errorf("Expected 2 evil.com and 1 other.com, got %v", counts)
+	}
+
+	aggregator.Flush(base, true)
+
+	var hourly int64
+	for _, rollup := range recorder.rollups {
+		if rollup.Interval == "1h" {
+			hourly += rollup.Count
+		}
+	}
+	if hourly != 4 {
+		t.Errorf("Expected 4 reports in hourly rollups, got %d", hourly)
+	}
+	if pending, _, _ := aggregator.Stats(); pending != 0 {
+		t.Errorf("Expected no pending buckets, got %d", pending)
+	}
+}
+
+func TestAggregator_RetriesFailedFlush(t *testing.T) {
+	recorder := &rollupRecorder{fail: true}
+	aggregator, err := NewAggregator([]string{"1m"}, []string{"directive"}, 0, 0, recorder, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	aggregator.Add(newTestReport("https://example.com/", "https://evil.com/x.js", base))
+	aggregator.Flush(base, true)
+
+	pending, flushErrors, _ := aggregator.Stats()
+	if pending != 1 || flushErrors != 1 {
+		t.Fatalf("Expected 1 pending bucket and 1 flush error, got %d and %d", pending, flushErrors)
+	}
+
+	recorder.fail = false
+	aggregator.Flush(base, true)
+
+	if len(recorder.rollups) != 1 || recorder.rollups[0].Count != 1 {
+		t.Errorf("Expected the failed rollup to be stored on retry, got %v", recorder.rollups)
+	}
+}
+
+func TestAggregator_CountsOverflowBucket(t *testing.T) {
+	recorder := &rollupRecorder{}
+	aggregator, err := NewAggregator([]string{"1m"}, []string{"blocked_host"}, 2, 0, recorder, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, host := range []string{"a.com", "b.com", "c.com", "d.com", "a.com"} {
+		aggregator.Add(newTestReport("https://example.com/", "https://"+host+"/x.js", base))
+	}
+	if pending, _, _ := aggregator.Stats(); pending != 3 {
+		t.Fatalf("Expected 2 buckets plus the overflow bucket, got %d", pending)
+	}
+
+	aggregator.Flush(base, true)
+
+	counts := make(map[string]int64)
+	for _, rollup := range recorder.rollups {
+		counts[rollup.Dimensions["blocked_host"]] += rollup.Count
+	}
+	if counts["a.com"] != 2 || counts["b.com"] != 1 || counts[models.RollupOverflow] != 2 {
+		t.Errorf("Expected a.com=2, b.com=1 and 2 overflowing reports, got %v", counts)
+	}
+}
+
+func TestAggregator_BoundsRetries(t *testing.T) {
+	recorder := &rollupRecorder{fail: true}
+	aggregator, err := NewAggregator([]string{"1m"}, []string{"blocked_host"}, 0, 3, recorder, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, host := range []string{"a.com", "b.com", "c.com"} {
+		aggregator.Add(newTestReport("https://example.com/", "https://"+host+"/x.js", base))
+	}
+	aggregator.Flush(base, true)
+	aggregator.Add(newTestReport("https://example.com/", "https://e.com/x.js", base))
+	aggregator.Flush(base, true)
+
+	pending, flushErrors, dropped := aggregator.Stats()
+	if pending != 3 || flushErrors != 2 || dropped != 1 {
+		t.Fatalf("Expected 3 retries, 2 flush errors and 1 dropped rollup, got %d, %d and %d", pending, flushErrors, dropped)
+	}
+
+	recorder.fail = false
+	aggregator.Flush(base, true)
+
+	if len(recorder.rollups) != 3 {
+		t.Fatalf("Expected 3 rollups to be stored, got %d", len(recorder.rollups))
+	}
+	kept := false
+	for _, rollup := range recorder.rollups {
+		kept = kept || rollup.Dimensions["blocked_host"] == "e.com"
+	}
+	if !kept {
+		t.Error("Expected the newest rollup to be kept")
+	}
+}
+
+func TestAggregator_CountsDocumentRoutes(t *testing.T) {
+	recorder := &rollupRecorder{}
+	aggregator, err := NewAggregator([]string{"1m"}, []string{"document_route"}, 0, 0, recorder, logrus.New())
+	if err != nil {
+		t.Fatalf("Failed to create aggregator: %v", err)
+	}
+
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, page := range []string{"https://example.com/orders/1", "https://example.com/orders/2?ref=mail"} {
+		report := newTestReport(page, "https://evil.com/x.js", base)
+		report.DocumentRoute = "example.com/orders/{id}"
+		aggregator.Add(report)
+	}
+	aggregator.Flush(base, true)
+
+	if len(recorder.rollups) != 1 || recorder.rollups[0].Dimensions["document_route"] != "example.com/orders/{id}" || recorder.rollups[0].Count != 2 {
+		t.Errorf("Expected both pages counted under their route, got %+v", recorder.rollups)
+	}
+}
+
+func TestNewAggregator_RejectsInvalidConfig(t *testing.T) {
+	if _, err := NewAggregator([]string{"soon"}, nil, 0, 0, &rollupRecorder{}, logrus.New()); err == nil {
+		t.Error("Expected error for invalid interval")
+	}
+	if _, err := NewAggregator([]string{"1m"}, []string{"planet"}, 0, 0, &rollupRecorder{}, logrus.New()); err == nil {
+		t.Error("Expected error for unknown dimension")
+	}
+}
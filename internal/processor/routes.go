@@ -0,0 +1,24 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"universal-csp-report/internal/models"
+)
+
+// LoadRouteRules reads a JSON array of route rules from a file.
+func LoadRouteRules(path string) ([]models.RouteRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read route rules: %w", err)
+	}
+
+	var rules []models.RouteRule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse route rules: %w", err)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"math/rand"
+	"strings"
+	"sync/atomic"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+// Sampler decides which reports are stored. Kept reports carry the rate they
+// were sampled at so counts can be reweighted downstream.
+type Sampler struct {
+	rate             float64
+	directiveRates   map[string]float64
+	hostRates        map[string]float64
+	fingerprintRates map[string]float64
+	keepFirst        int64
+
+	// seen returns how many reports with a fingerprint have been received,
+	// including the current one.
+	seen   func(fingerprint string) int64
+	random func() float64
+
+	kept       int64
+	sampledOut int64
+}
+
+func NewSampler(cfg config.SamplingConfig, seen func(fingerprint string) int64) *Sampler {
+	s := &Sampler{
+		rate:             clampRate(cfg.Rate),
+		directiveRates:   make(map[string]float64, len(cfg.DirectiveRates)),
+		hostRates:        make(map[string]float64, len(cfg.HostRates)),
+		fingerprintRates: make(map[string]float64, len(cfg.FingerprintRates)),
+		keepFirst:        int64(cfg.KeepFirst),
+		seen:             seen,
+		random:           rand.Float64,
+	}
+
+	for directive, rate := range cfg.DirectiveRates {
+		s.directiveRates[strings.ToLower(directive)] = clampRate(rate)
+	}
+	for host, rate := range cfg.HostRates {
+		s.hostRates[strings.ToLower(host)] = clampRate(rate)
+	}
+	for fingerprint, rate := range cfg.FingerprintRates {
+		s.fingerprintRates[fingerprint] = clampRate(rate)
+	}
+
+	return s
+}
+
+// Keep reports whether a report should be stored and sets its SampleRate.
+// Downgraded reports are not tracked as issues, so they never count as the
+// first reports of a fingerprint.
+func (s *Sampler) Keep(report *models.CSPReport) bool {
+	rate := s.rateFor(report)
+
+	if rate < 1 && s.keepFirst > 0 && !report.Downgraded && report.Fingerprint != "" && s.seen != nil && s.seen(report.Fingerprint) <= s.keepFirst {
+		rate = 1
+	}
+
+	if rate < 1 && s.random() >= rate {
+		atomic.AddInt64(&s.sampledOut, 1)
+		return false
+	}
+
+	report.SampleRate = rate
+	atomic.AddInt64(&s.kept, 1)
+	return true
+}
+
+// Stats returns the number of reports kept and sampled out.
+func (s *Sampler) Stats() (kept, sampledOut int64) {
+	return atomic.LoadInt64(&s.kept), atomic.LoadInt64(&s.sampledOut)
+}
+
+func (s *Sampler) rateFor(report *models.CSPReport) float64 {
+	if rate, ok := s.fingerprintRates[report.Fingerprint]; ok {
+		return rate
+	}
+
+	parsed := report.ParsedReport
+	if parsed == nil {
+		return s.rate
+	}
+
+	if rate, ok := s.hostRates[models.URLHost(parsed.BlockedURI)]; ok {
+		return rate
+	}
+	if rate, ok := s.hostRates[models.URLHost(parsed.DocumentURI)]; ok {
+		return rate
+	}
+	if rate, ok := s.directiveRates[parsed.DirectiveName()]; ok {
+		return rate
+	}
+	if rate, ok := s.directiveRates[parsed.CanonicalDirective()]; ok {
+		return rate
+	}
+
+	return s.rate
+}
+
+func clampRate(rate float64) float64 {
+	if rate < 0 {
+		return 0
+	}
+	if rate > 1 {
+		return 1
+	}
+	return rate
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/config"
+)
+
+func TestSampler_RatePrecedence(t *testing.T) {
+	report := newTestReport("https://app.example.com/", "https://evil.com/x.js", time.Now())
+
+	tests := []struct {
+		name     string
+		cfg      config.SamplingConfig
+		expected float64
+	}{
+		{"default", config.SamplingConfig{Rate: 0.5}, 0.5},
+		{"directive", config.SamplingConfig{Rate: 0.5, DirectiveRates: map[string]float64{"Script-Src": 0.2}}, 0.2},
+		{"document host", config.SamplingConfig{Rate: 0.5, HostRates: map[string]float64{"app.example.com": 0.3}}, 0.3},
+		{"blocked host over directive", config.SamplingConfig{
+			DirectiveRates: map[string]float64{"script-src": 0.2},
+			HostRates:      map[string]float64{"evil.com": 0.1},
+		}, 0.1},
+		{"fingerprint over host", config.SamplingConfig{
+			HostRates:        map[string]float64{"evil.com": 0.1},
+			FingerprintRates: map[string]float64{report.Fingerprint: 0.05},
+		}, 0.05},
+		{"clamped", config.SamplingConfig{Rate: 3}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampler := NewSampler(tt.cfg, nil)
+			if rate := sampler.rateFor(report); rate != tt.expected {
+				t.Errorf("Expected rate %v, got %v", tt.expected, rate)
+			}
+		})
+	}
+}
+
+func TestSampler_KeepsFirstOfNewFingerprint(t *testing.T) {
+	seen := int64(0)
+	sampler := NewSampler(config.SamplingConfig{Rate: 0.25, KeepFirst: 3}, func(string) int64 { return seen })
+
+	// Reject everything that is actually sampled
+	sampler.random = func() float64 { return 0.99 }
+
+	for i := 0; i < 10; i++ {
+		seen++
+		report := newTestReport("https://example.com/", "https://evil.com/x.js", time.Now())
+		kept := sampler.Keep(report)
+
+		if i < 3 {
+			if !kept || report.SampleRate != 1 {
+				t.Errorf("Expected report %d to be kept at rate 1, got kept=%v rate=%v", i, kept, report.SampleRate)
+			}
+		} else if kept {
+			t.Errorf("Expected report %d to be sampled out", i)
+		}
+	}
+
+	kept, sampledOut := sampler.Stats()
+	if kept != 3 || sampledOut != 7 {
+		t.Errorf("Expected 3 kept and 7 sampled out, got %d and %d", kept, sampledOut)
+	}
+}
+
+func TestSampler_SamplesDowngradedReports(t *testing.T) {
+	// Downgraded reports are never recorded as issues, so seen stays at 0
+	sampler := NewSampler(config.SamplingConfig{Rate: 0.25, KeepFirst: 3}, func(string) int64 { return 0 })
+	sampler.random = func() float64 { return 0.99 }
+
+	report := newTestReport("https://example.com/", "https://evil.com/x.js", time.Now())
+	report.Downgraded = true
+	if sampler.Keep(report) {
+		t.Error("Expected a downgraded report to be sampled at the configured rate")
+	}
+}
+
+func TestSampler_SetsSampleRate(t *testing.T) {
+	sampler := NewSampler(config.SamplingConfig{Rate: 0.25}, nil)
+	sampler.random = func() float64 { return 0.1 }
+
+	report := newTestReport("https://example.com/", "https://evil.com/x.js", time.Now())
+	if !sampler.Keep(report) {
+		t.Fatal("Expected report to be kept")
+	}
+	if report.SampleRate != 0.25 {
+		t.Errorf("Expected sample rate 0.25, got %v", report.SampleRate)
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type silenceRequest struct {
+	Fingerprint string `json:"fingerprint" binding:"required"`
+	Duration    string `json:"duration" binding:"required"`
+}
+
+// requireAlerter responds with 501 and returns false when alerting is
+// disabled.
+func (s *Server) requireAlerter(c *gin.Context) bool {
+	if s.processor.Alerter() == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Alerting is disabled"})
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleListAlerts(c *gin.Context) {
+	if !s.requireAlerter(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"alerts": s.processor.Alerter().Recent()})
+}
+
+func (s *Server) handleListSilences(c *gin.Context) {
+	if !s.requireAlerter(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"silences": s.processor.Alerter().Silences()})
+}
+
+func (s *Server) handleCreateSilence(c *gin.Context) {
+	if !s.requireAlerter(c) {
+		return
+	}
+
+	var req silenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid silence"})
+		return
+	}
+
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid duration"})
+		return
+	}
+
+	until := time.Now().UTC().Add(duration)
+	s.processor.Alerter().Silence(req.Fingerprint, until)
+	c.JSON(http.StatusCreated, gin.H{"fingerprint": req.Fingerprint, "until": until})
+}
+
+func (s *Server) handleDeleteSilence(c *gin.Context) {
+	if !s.requireAlerter(c) {
+		return
+	}
+
+	if !s.processor.Alerter().Unsilence(c.Param("fingerprint")) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Silence not found"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
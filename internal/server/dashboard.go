@@ -0,0 +1,28 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dashboardFiles holds the read-only web UI. The assets contain no data; the
+// UI reads everything through the /api endpoints, which enforce API keys.
+//
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+func (s *Server) registerDashboard(router *gin.Engine) {
+	assets, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to load dashboard assets")
+		return
+	}
+
+	router.GET("/", func(c *gin.Context) {
+		c.Redirect(http.StatusFound, "/dashboard/")
+	})
+	router.StaticFS("/dashboard", http.FS(assets))
+}
@@ -0,0 +1,176 @@
+"use strict";
+
+const keyStorage = "csp-dashboard-api-key";
+const refreshInterval = 5000;
+
+let lastReceived = null;
+let lastPoll = null;
+
+function apiKey() {
+  return sessionStorage.getItem(keyStorage) || "";
+}
+
+async function api(path) {
+  const headers = {};
+  if (apiKey()) {
+    headers["X-API-Key"] = apiKey();
+  }
+
+  const res = await fetch(path, { headers });
+  if (res.status === 401) {
+    showLogin(apiKey() !== "");
+    throw new Error("unauthorized");
+  }
+
+  const body = await res.json();
+  if (!res.ok) {
+    throw new Error(body.error || res.statusText);
+  }
+  return body;
+}
+
+function el(tag, text, attrs) {
+  const node = document.createElement(tag);
+  if (text !== undefined) {
+    node.textContent = text;
+  }
+  Object.assign(node, attrs || {});
+  return node;
+}
+
+function showLogin(failed) {
+  document.getElementById("content").hidden = true;
+  document.getElementById("login").hidden = false;
+  document.getElementById("login-error").hidden = !failed;
+  document.getElementById("logout").hidden = true;
+}
+
+function showContent() {
+  document.getElementById("login").hidden = true;
+  document.getElementById("content").hidden = false;
+  document.getElementById("logout").hidden = !apiKey();
+}
+
+function showError(id, message) {
+  const node = document.getElementById(id);
+  node.textContent = message;
+  node.hidden = false;
+}
+
+async function loadRate() {
+  const res = await fetch("/metrics");
+  const metrics = await res.json();
+  const now = Date.now();
+
+  // received_total counts reports as they arrive, before filtering, sampling
+  // and deduplication, and unlike processed_total does not jump per batch
+  if (lastReceived !== null) {
+    const seconds = (now - lastPoll) / 1000;
+    const rate = Math.max(0, (metrics.received_total - lastReceived) / seconds);
+    document.getElementById("rate").textContent = rate.toFixed(1) + " reports/s";
+  }
+
+  lastReceived = metrics.received_total;
+  lastPoll = now;
+}
+
+async function loadSummary() {
+  let summary;
+  try {
+    summary = await api("/api/summary?size=5");
+  } catch (err) {
+    if (err.message !== "unauthorized") {
+      showError("summary-error", err.message);
+    }
+    return;
+  }
+
+  const container = document.getElementById("summary");
+  container.replaceChildren();
+  for (const [dimension, buckets] of Object.entries(summary.top)) {
+    const card = el("div", undefined, { className: "card" });
+    card.append(el("h3", dimension.replace("_", " ")));
+
+    const list = el("ol");
+    for (const bucket of buckets) {
+      list.append(el("li", bucket.key + " (" + bucket.count + ")"));
+    }
+    if (buckets.length === 0) {
+      card.append(el("p", "No reports", { className: "muted" }));
+    } else {
+      card.append(list);
+    }
+    container.append(card);
+  }
+}
+
+async function loadIssues() {
+  const body = await api("/api/issues?limit=50");
+  const rows = document.getElementById("issues");
+  rows.replaceChildren();
+
+  for (const issue of body.issues) {
+    const row = el("tr");
+    row.append(
+      el("td", issue.input.directive),
+      el("td", issue.input.blocked_origin),
+      el("td", issue.input.document_path),
+      el("td", String(issue.count)),
+      el("td", new Date(issue.last_seen).toLocaleString()),
+    );
+    row.addEventListener("click", () => loadReports(issue.fingerprint));
+    rows.append(row);
+  }
+  showContent();
+}
+
+async function loadReports(fingerprint) {
+  document.getElementById("issue").hidden = false;
+  document.getElementById("issue-fingerprint").textContent = fingerprint;
+  document.getElementById("reports-error").hidden = true;
+
+  const container = document.getElementById("reports");
+  container.replaceChildren();
+
+  let page;
+  try {
+    page = await api("/api/reports?size=10&fingerprint=" + encodeURIComponent(fingerprint));
+  } catch (err) {
+    if (err.message !== "unauthorized") {
+      showError("reports-error", err.message);
+    }
+    return;
+  }
+
+  for (const report of page.reports) {
+    const card = el("div", undefined, { className: "report" });
+    card.append(
+      el("strong", new Date(report.timestamp).toLocaleString()),
+      el("p", report.human_readable),
+      el("pre", JSON.stringify(report.raw_report, null, 2)),
+    );
+    container.append(card);
+  }
+  if (page.reports.length === 0) {
+    container.append(el("p", "No stored reports", { className: "muted" }));
+  }
+  document.getElementById("issue").scrollIntoView({ behavior: "smooth" });
+}
+
+async function refresh() {
+  await Promise.allSettled([loadRate(), loadIssues(), loadSummary()]);
+}
+
+document.getElementById("login").addEventListener("submit", (event) => {
+  event.preventDefault();
+  sessionStorage.setItem(keyStorage, document.getElementById("api-key").value);
+  refresh();
+});
+
+document.getElementById("logout").addEventListener("click", () => {
+  sessionStorage.removeItem(keyStorage);
+  showLogin(false);
+});
+
+refresh();
+setInterval(refresh, refreshInterval);
@@ -0,0 +1,45 @@
+package server
+
+import "sync"
+
+// maxRejectedHosts bounds the per-host rejection counters; further hosts
+// are counted under otherRejectedHosts.
+const (
+	maxRejectedHosts   = 1000
+	otherRejectedHosts = "other"
+)
+
+// originCounter counts reports rejected by the origin allowlist per host.
+type originCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newOriginCounter() *originCounter {
+	return &originCounter{counts: make(map[string]int64)}
+}
+
+func (o *originCounter) inc(host string) {
+	if host == "" {
+		host = otherRejectedHosts
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if _, ok := o.counts[host]; !ok && len(o.counts) >= maxRejectedHosts {
+		host = otherRejectedHosts
+	}
+	o.counts[host]++
+}
+
+func (o *originCounter) snapshot() map[string]int64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	counts := make(map[string]int64, len(o.counts))
+	for host, count := range o.counts {
+		counts[host] = count
+	}
+	return counts
+}
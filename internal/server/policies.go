@@ -0,0 +1,224 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"universal-csp-report/internal/models"
+	"universal-csp-report/internal/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type policyVersionRequest struct {
+	Site       string `json:"site"`
+	Project    string `json:"project"`
+	Policy     string `json:"policy" binding:"required"`
+	ReportOnly bool   `json:"report_only"`
+	Activate   bool   `json:"activate"`
+}
+
+type activateRequest struct {
+	Version int `json:"version" binding:"required"`
+}
+
+// newRegistryFor loads the policy registry, or returns nil when none is
+// configured or it cannot be read.
+func newRegistryFor(path string, logger *logrus.Logger) *policy.Registry {
+	if path == "" {
+		return nil
+	}
+
+	registry, err := policy.LoadRegistry(path)
+	if err != nil {
+		logger.WithError(err).Error("Policy registry disabled")
+		return nil
+	}
+	return registry
+}
+
+// linkPolicy stamps a report with the registered policy version it was sent
+// for.
+func (s *Server) linkPolicy(report *models.CSPReport) {
+	if s.registry == nil {
+		return
+	}
+	var host string
+	if report.ParsedReport != nil {
+		host = models.URLHost(report.ParsedReport.DocumentURI)
+	}
+	if name, version, ok := s.registry.Lookup(report.PolicyFingerprint, host, report.Project); ok {
+		report.PolicyName = name
+		report.PolicyVersion = version
+	}
+}
+
+// requireRegistry responds with 501 and returns false when no policy
+// registry is configured.
+func (s *Server) requireRegistry(c *gin.Context) bool {
+	if s.registry == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Policy registry is disabled"})
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleListPolicies(c *gin.Context) {
+	if !s.requireRegistry(c) {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": s.registry.List(c.Query("site"))})
+}
+
+func (s *Server) handleGetPolicy(c *gin.Context) {
+	if !s.requireRegistry(c) {
+		return
+	}
+
+	registered, ok := s.registry.Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy not found"})
+		return
+	}
+	c.JSON(http.StatusOK, registered)
+}
+
+func (s *Server) handleAddPolicyVersion(c *gin.Context) {
+	if !s.requireRegistry(c) {
+		return
+	}
+
+	var req policyVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy"})
+		return
+	}
+	if _, ok := s.projects[req.Project]; req.Project != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown project"})
+		return
+	}
+
+	registered, err := s.registry.AddVersion(c.Param("name"), policy.NewVersion{
+		Site:       req.Site,
+		Project:    req.Project,
+		Policy:     req.Policy,
+		ReportOnly: req.ReportOnly,
+		Activate:   req.Activate,
+	})
+	if err != nil {
+		s.respondRegistryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, registered)
+}
+
+func (s *Server) handleActivatePolicy(c *gin.Context) {
+	if !s.requireRegistry(c) {
+		return
+	}
+
+	var req activateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	registered, err := s.registry.Activate(c.Param("name"), req.Version)
+	if err != nil {
+		s.respondRegistryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, registered)
+}
+
+func (s *Server) handleDeletePolicy(c *gin.Context) {
+	if !s.requireRegistry(c) {
+		return
+	}
+
+	if err := s.registry.Delete(c.Param("name")); err != nil {
+		s.respondRegistryError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// handlePolicyHeaders returns the response headers serving a policy, by
+// default its active version, with reports sent back to this service.
+func (s *Server) handlePolicyHeaders(c *gin.Context) {
+	if !s.requireRegistry(c) {
+		return
+	}
+
+	registered, ok := s.registry.Get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy not found"})
+		return
+	}
+
+	number := registered.Active
+	if raw := c.Query("version"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+			return
+		}
+		number = parsed
+	}
+	version, ok := registered.Version(number)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy version not found"})
+		return
+	}
+
+	var key string
+	if p, ok := s.projects[registered.Project]; ok {
+		key = p.config.Key
+	}
+	reportURL := policy.ReportURL(s.publicURL(c), registered.Project, key)
+
+	headers := make(map[string]string)
+	for name, values := range version.Headers(reportURL) {
+		headers[name] = values[0]
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"name":        registered.Name,
+		"site":        registered.Site,
+		"version":     version.Version,
+		"fingerprint": version.Fingerprint,
+		"headers":     headers,
+	})
+}
+
+// publicURL returns the base URL browsers reach this service at, derived
+// from the request unless PUBLIC_URL is set.
+func (s *Server) publicURL(c *gin.Context) string {
+	if s.config.PublicURL != "" {
+		return s.config.PublicURL
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+func (s *Server) respondRegistryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, policy.ErrPolicyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy not found"})
+	case errors.Is(err, policy.ErrVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy version not found"})
+	case errors.Is(err, policy.ErrInvalidPolicy):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		s.logger.WithError(err).Error("Failed to update policy registry")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update policy registry"})
+	}
+}
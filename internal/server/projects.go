@@ -0,0 +1,75 @@
+package server
+
+import (
+	"crypto/subtle"
+	"sync"
+	"time"
+
+	"universal-csp-report/internal/config"
+
+	"golang.org/x/time/rate"
+)
+
+// project holds a tenant's configuration along with its rate limiter and
+// daily quota usage.
+type project struct {
+	config  config.ProjectConfig
+	limiter *rate.Limiter
+
+	mu   sync.Mutex
+	day  string
+	used int
+}
+
+func newProjects(cfgs []config.ProjectConfig) map[string]*project {
+	projects := make(map[string]*project, len(cfgs))
+	for _, cfg := range cfgs {
+		p := &project{config: cfg}
+		if cfg.RateLimit > 0 {
+			burst := cfg.RateBurst
+			if burst < cfg.RateLimit {
+				burst = cfg.RateLimit
+			}
+			p.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit), burst)
+		}
+		projects[cfg.ID] = p
+	}
+	return projects
+}
+
+func (p *project) validKey(key string) bool {
+	return subtle.ConstantTimeCompare([]byte(key), []byte(p.config.Key)) == 1
+}
+
+func (p *project) allow() bool {
+	return p.limiter == nil || p.limiter.Allow()
+}
+
+// reserve takes count reports from today's quota, returning false when that
+// would exceed it. Quotas reset at midnight UTC.
+func (p *project) reserve(count int, now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if day := now.UTC().Format("2006-01-02"); day != p.day {
+		p.day = day
+		p.used = 0
+	}
+
+	if p.config.DailyQuota > 0 && p.used+count > p.config.DailyQuota {
+		return false
+	}
+	p.used += count
+	return true
+}
+
+// usage returns the number of reports accepted today.
+func (p *project) usage(now time.Time) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.day != now.UTC().Format("2006-01-02") {
+		return 0
+	}
+	return p.used
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"universal-csp-report/internal/policy"
+	"universal-csp-report/internal/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultRecommendationWindow = 7 * 24 * time.Hour
+	defaultRecommendationSample = 10000
+	maxRecommendationSample     = 100000
+)
+
+// handleRecommendations proposes a relaxed policy per document host from the
+// stored violations in the window, reading at most max_reports of them.
+func (s *Server) handleRecommendations(c *gin.Context) {
+	if s.reports == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Storage backend does not support queries"})
+		return
+	}
+
+	query := storage.ReportQuery{
+		Project:      c.Query("project"),
+		DocumentHost: c.Query("document_host"),
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from time"})
+		return
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to time"})
+		return
+	}
+	if query.To.IsZero() {
+		query.To = time.Now().UTC()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultRecommendationWindow)
+	}
+
+	minEvidence, err := strconv.ParseInt(c.DefaultQuery("min_evidence", "1"), 10, 64)
+	if err != nil || minEvidence < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid min_evidence"})
+		return
+	}
+
+	maxReports, err := strconv.Atoi(c.DefaultQuery("max_reports", strconv.Itoa(defaultRecommendationSample)))
+	if err != nil || maxReports <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid max_reports"})
+		return
+	}
+	if maxReports > maxRecommendationSample {
+		maxReports = maxRecommendationSample
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), queryTimeout)
+	defer cancel()
+
+	recommender := policy.NewRecommender()
+	truncated, err := recommender.Collect(ctx, s.reports, query, maxReports)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to collect reports for recommendations")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":            query.From,
+		"to":              query.To,
+		"truncated":       truncated,
+		"recommendations": recommender.Recommend(minEvidence),
+	})
+}
@@ -2,36 +2,73 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"universal-csp-report/internal/config"
 	"universal-csp-report/internal/models"
+	"universal-csp-report/internal/policy"
 	"universal-csp-report/internal/processor"
+	"universal-csp-report/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
 )
 
+const (
+	// queryTimeout bounds read-side API queries against the storage backend.
+	queryTimeout = 30 * time.Second
+
+	// defaultSummaryWindow is summarized when no time range is given.
+	defaultSummaryWindow = 24 * time.Hour
+)
+
 type Server struct {
 	config    config.ServerConfig
 	processor *processor.BatchProcessor
+	reports   storage.QueryStorage
+	summaries storage.SummaryStorage
 	logger    *logrus.Logger
 	server    *http.Server
 	limiter   *rate.Limiter
+
+	allowlist        *models.OriginAllowlist
+	originRejections *originCounter
+	projects         map[string]*project
+	registry         *policy.Registry
 }
 
-func New(cfg config.ServerConfig, proc *processor.BatchProcessor, logger *logrus.Logger) *Server {
+// metricsResponse adds server-side counters to the processor stats.
+type metricsResponse struct {
+	processor.Stats
+	OriginRejections    map[string]int64 `json:"origin_rejections,omitempty"`
+	ProjectReportsToday map[string]int   `json:"project_reports_today,omitempty"`
+}
+
+// New creates the HTTP server. The report query API is available when store
+// implements storage.QueryStorage.
+func New(cfg config.ServerConfig, proc *processor.BatchProcessor, store storage.Storage, logger *logrus.Logger) *Server {
 	limiter := rate.NewLimiter(rate.Limit(cfg.RateLimit), cfg.RateBurst)
+	reports, _ := store.(storage.QueryStorage)
+	summaries, _ := store.(storage.SummaryStorage)
 
 	return &Server{
-		config:    cfg,
-		processor: proc,
-		logger:    logger,
-		limiter:   limiter,
+		config:           cfg,
+		processor:        proc,
+		reports:          reports,
+		summaries:        summaries,
+		logger:           logger,
+		limiter:          limiter,
+		allowlist:        models.NewOriginAllowlist(cfg.AllowedOrigins),
+		originRejections: newOriginCounter(),
+		projects:         newProjects(cfg.Projects),
+		registry:         newRegistryFor(cfg.PolicyRegistry, logger),
 	}
 }
 
@@ -48,9 +85,50 @@ func (s *Server) Start() error {
 
 	router.POST("/csp-report", s.handleCSPReport)
 	router.POST("/csp", s.handleCSPReport)
+	router.POST("/report/:project/:key", s.handleProjectReport)
+	router.POST("/csp/:project/:key", s.handleProjectReport)
 	router.GET("/health", s.handleHealth)
 	router.GET("/metrics", s.handleMetrics)
 
+	switch {
+	case len(s.config.APIKeys) > 0:
+	case s.config.APIAuthDisabled:
+		s.logger.Warn("API_AUTH_DISABLED is set, /api endpoints are unauthenticated")
+	default:
+		s.logger.Warn("API_KEYS is not set, /api endpoints reject all requests")
+	}
+
+	api := router.Group("/api")
+	api.Use(s.apiAuthMiddleware())
+	api.GET("/issues", s.handleListIssues)
+	api.GET("/issues/:fingerprint", s.handleGetIssue)
+	api.GET("/inline", s.handleInlineHashes)
+	api.GET("/rollouts", s.handleListRollouts)
+	api.GET("/rollouts/:policy", s.handleGetRollout)
+	api.GET("/reports", s.handleQueryReports)
+	api.GET("/summary", s.handleSummary)
+	api.GET("/recommendations", s.handleRecommendations)
+	api.GET("/stream", s.handleStream)
+	api.GET("/alerts", s.handleListAlerts)
+	api.GET("/silences", s.handleListSilences)
+	api.GET("/policies", s.handleListPolicies)
+	api.GET("/policies/:name", s.handleGetPolicy)
+
+	// Changing alerting or the served policies, or reading the policies'
+	// ingest keys, always needs a key, even when API_AUTH_DISABLED opens the
+	// read endpoints
+	admin := s.adminAuthMiddleware()
+	api.POST("/silences", admin, s.handleCreateSilence)
+	api.DELETE("/silences/:fingerprint", admin, s.handleDeleteSilence)
+	api.DELETE("/policies/:name", admin, s.handleDeletePolicy)
+	api.POST("/policies/:name/versions", admin, s.handleAddPolicyVersion)
+	api.PUT("/policies/:name/active", admin, s.handleActivatePolicy)
+	api.GET("/policies/:name/headers", admin, s.handlePolicyHeaders)
+
+	if s.config.Dashboard {
+		s.registerDashboard(router)
+	}
+
 	s.server = &http.Server{
 		Addr:         ":" + strconv.Itoa(s.config.Port),
 		Handler:      router,
@@ -69,6 +147,30 @@ func (s *Server) Shutdown(ctx context.Context) error {
 }
 
 func (s *Server) handleCSPReport(c *gin.Context) {
+	s.receiveReports(c, nil)
+}
+
+// handleProjectReport accepts reports for a single project, authenticated by
+// the project key in the URL and subject to the project's own limits.
+func (s *Server) handleProjectReport(c *gin.Context) {
+	p, ok := s.projects[c.Param("project")]
+	if !ok || !p.validKey(c.Param("key")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid project or key"})
+		return
+	}
+
+	if !p.allow() {
+		s.logger.WithField("project", p.config.ID).Warn("Project rate limit exceeded")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Project rate limit exceeded"})
+		return
+	}
+
+	s.receiveReports(c, p)
+}
+
+// receiveReports parses and submits the reports in the request body. When p
+// is set, reports count against its quota and are stamped with its ID.
+func (s *Server) receiveReports(c *gin.Context, p *project) {
 	body, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		s.logger.WithError(err).Error("Failed to read request body")
@@ -98,10 +200,33 @@ func (s *Server) handleCSPReport(c *gin.Context) {
 		return
 	}
 
+	// Reports from other origins are rejected before the project quota is
+	// charged, so posts with forged origins cannot use it up
+	allowed := make([]*models.CSPReport, 0, len(reports))
+	for _, report := range reports {
+		if s.checkOrigin(report) {
+			allowed = append(allowed, report)
+		}
+	}
+	rejectedCount := len(reports) - len(allowed)
+
+	if p != nil && len(allowed) > 0 {
+		if !p.reserve(len(allowed), time.Now()) {
+			s.logger.WithField("project", p.config.ID).Warn("Project quota exceeded")
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Project quota exceeded"})
+			return
+		}
+		for _, report := range allowed {
+			report.Project = p.config.ID
+		}
+	}
+
 	// Submit all reports for processing
 	successCount := 0
 	errorCount := 0
-	for _, report := range reports {
+	for _, report := range allowed {
+		s.linkPolicy(report)
+
 		if err := s.processor.Submit(report); err != nil {
 			s.logger.WithError(err).Error("Failed to submit report for processing")
 			errorCount++
@@ -111,6 +236,11 @@ func (s *Server) handleCSPReport(c *gin.Context) {
 	}
 
 	// Return appropriate response
+	if rejectedCount == len(reports) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Document origin not allowed"})
+		return
+	}
+
 	if errorCount > 0 && successCount == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process reports"})
 		return
@@ -123,6 +253,9 @@ func (s *Server) handleCSPReport(c *gin.Context) {
 	if errorCount > 0 {
 		response["errors"] = errorCount
 	}
+	if rejectedCount > 0 {
+		response["rejected"] = rejectedCount
+	}
 
 	// Chrome expects 204 No Content for batch reports
 	if contentType == "application/reports+json" && len(reports) > 1 {
@@ -132,6 +265,27 @@ func (s *Server) handleCSPReport(c *gin.Context) {
 	}
 }
 
+// checkOrigin applies the document origin allowlist. Reports from other
+// origins are either rejected or quarantined: stored tagged "quarantine" and
+// kept out of issue tracking.
+func (s *Server) checkOrigin(report *models.CSPReport) bool {
+	host, ok := s.allowlist.Check(report)
+	if ok {
+		return true
+	}
+
+	s.originRejections.inc(host)
+
+	if s.config.OriginAction == config.OriginActionQuarantine {
+		report.Tags = append(report.Tags, "quarantine")
+		report.Downgraded = true
+		return true
+	}
+
+	s.logger.WithField("host", host).Debug("Rejected report for document origin not on the allowlist")
+	return false
+}
+
 func (s *Server) handleHealth(c *gin.Context) {
 	status := s.processor.GetStatus()
 	c.JSON(http.StatusOK, gin.H{
@@ -143,8 +297,231 @@ func (s *Server) handleHealth(c *gin.Context) {
 }
 
 func (s *Server) handleMetrics(c *gin.Context) {
-	status := s.processor.GetStatus()
-	c.JSON(http.StatusOK, status)
+	response := metricsResponse{
+		Stats:            s.processor.GetStatus(),
+		OriginRejections: s.originRejections.snapshot(),
+	}
+
+	if len(s.projects) > 0 {
+		now := time.Now()
+		response.ProjectReportsToday = make(map[string]int, len(s.projects))
+		for id, p := range s.projects {
+			response.ProjectReportsToday[id] = p.usage(now)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) handleListIssues(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	issues := s.processor.Issues().List(limit)
+	c.JSON(http.StatusOK, gin.H{
+		"total":  s.processor.Issues().Len(),
+		"issues": issues,
+	})
+}
+
+func (s *Server) handleGetIssue(c *gin.Context) {
+	issue, ok := s.processor.Issues().Get(c.Param("fingerprint"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return
+	}
+	c.JSON(http.StatusOK, issue)
+}
+
+// handleInlineHashes lists inline violations and candidate hashes per page,
+// optionally for a single page given without query string.
+func (s *Server) handleInlineHashes(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	pages := s.processor.Inline().Pages(c.Query("page"))
+	total := len(pages)
+	if limit > 0 && len(pages) > limit {
+		pages = pages[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": total,
+		"pages": pages,
+	})
+}
+
+func (s *Server) handleListRollouts(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"rollouts": s.processor.Rollouts().List()})
+}
+
+func (s *Server) handleGetRollout(c *gin.Context) {
+	rollout, ok := s.processor.Rollouts().Get(c.Param("policy"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Policy not found"})
+		return
+	}
+	c.JSON(http.StatusOK, rollout)
+}
+
+func (s *Server) handleQueryReports(c *gin.Context) {
+	if s.reports == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Storage backend does not support queries"})
+		return
+	}
+
+	query := storage.ReportQuery{
+		Project:       c.Query("project"),
+		Directive:     c.Query("directive"),
+		BlockedURI:    c.Query("blocked_uri"),
+		DocumentHost:  c.Query("document_host"),
+		DocumentRoute: c.Query("document_route"),
+		Browser:       c.Query("browser"),
+		Fingerprint:   c.Query("fingerprint"),
+		Disposition:   c.Query("disposition"),
+		Cursor:        c.Query("cursor"),
+	}
+
+	var err error
+	if query.From, err = parseTimeParam(c, "from"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from time"})
+		return
+	}
+	if query.To, err = parseTimeParam(c, "to"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to time"})
+		return
+	}
+
+	if query.Size, err = strconv.Atoi(c.DefaultQuery("size", "50")); err != nil || query.Size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), queryTimeout)
+	defer cancel()
+
+	page, err := s.reports.QueryReports(ctx, query)
+	if errors.Is(err, storage.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+		return
+	}
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to query reports")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to query reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (s *Server) handleSummary(c *gin.Context) {
+	if s.summaries == nil {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "Storage backend does not support summaries"})
+		return
+	}
+
+	query := storage.SummaryQuery{Project: c.Query("project")}
+
+	var err error
+	params := []struct {
+		name   string
+		target *time.Time
+	}{
+		{"from", &query.From},
+		{"to", &query.To},
+		{"compare_from", &query.CompareFrom},
+		{"compare_to", &query.CompareTo},
+	}
+	for _, param := range params {
+		if *param.target, err = parseTimeParam(c, param.name); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param.name + " time"})
+			return
+		}
+	}
+
+	if query.To.IsZero() {
+		query.To = time.Now().UTC()
+	}
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultSummaryWindow)
+	}
+	if query.CompareFrom.IsZero() != query.CompareTo.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "compare_from and compare_to must be set together"})
+		return
+	}
+
+	if query.Size, err = strconv.Atoi(c.DefaultQuery("size", "10")); err != nil || query.Size <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), queryTimeout)
+	defer cancel()
+
+	summary, err := s.summaries.SummarizeReports(ctx, query)
+	if err != nil {
+		s.logger.WithError(err).Error("Failed to summarize reports")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to summarize reports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// parseTimeParam reads an optional RFC 3339 time from the query string.
+func parseTimeParam(c *gin.Context, name string) (time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// apiAuthMiddleware requires one of the configured API keys, sent as a bearer
+// token or in the X-API-Key header. Without configured keys every request is
+// rejected, unless authentication is explicitly disabled.
+func (s *Server) apiAuthMiddleware() gin.HandlerFunc {
+	return s.keyAuthMiddleware(s.config.APIAuthDisabled)
+}
+
+// adminAuthMiddleware requires an API key regardless of API_AUTH_DISABLED.
+func (s *Server) adminAuthMiddleware() gin.HandlerFunc {
+	return s.keyAuthMiddleware(false)
+}
+
+func (s *Server) keyAuthMiddleware(allowUnconfigured bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(s.config.APIKeys) == 0 {
+			if allowUnconfigured {
+				c.Next()
+				return
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "API keys are not configured"})
+			c.Abort()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && key == "" {
+			key = token
+		}
+
+		for _, allowed := range s.config.APIKeys {
+			if allowed != "" && subtle.ConstantTimeCompare([]byte(key), []byte(allowed)) == 1 {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+	}
 }
 
 func (s *Server) loggingMiddleware() gin.HandlerFunc {
@@ -1,7 +1,9 @@
 package server
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +14,7 @@ import (
 	"universal-csp-report/internal/config"
 	"universal-csp-report/internal/models"
 	"universal-csp-report/internal/processor"
+	"universal-csp-report/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -46,6 +49,8 @@ func createTestServer() *Server {
 		BatchSize:     10,
 		QueueSize:     100,
 		FlushInterval: 1,
+		MaxIssues:     100,
+		MaxIssuePages: 10,
 	}
 
 	logger := logrus.New()
@@ -54,7 +59,7 @@ func createTestServer() *Server {
 	mockStore := &mockStorage{}
 	batchProcessor := processor.New(processorCfg, mockStore, logger)
 
-	return New(cfg, batchProcessor, logger)
+	return New(cfg, batchProcessor, mockStore, logger)
 }
 
 func TestHandleCSPReport_ValidReports(t *testing.T) {
@@ -258,6 +263,11 @@ func TestHandleMetrics(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	server := createTestServer()
 
+	reports, _ := models.ParseCSPReports([]byte(`{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`), "", "")
+	if err := server.processor.Submit(reports[0]); err != nil {
+		t.Fatalf("Failed to submit report: %v", err)
+	}
+
 	req := httptest.NewRequest("GET", "/metrics", nil)
 	w := httptest.NewRecorder()
 
@@ -275,12 +285,17 @@ func TestHandleMetrics(t *testing.T) {
 	}
 
 	// Check that all expected metrics are present
-	expectedFields := []string{"queue_size", "processed_total", "errors_total", "batches_total"}
+	expectedFields := []string{"queue_size", "received_total", "processed_total", "errors_total", "batches_total"}
 	for _, field := range expectedFields {
 		if _, exists := response[field]; !exists {
 			t.Errorf("Missing expected field '%s' in metrics response", field)
 		}
 	}
+
+	// Reports count as received before any batch is stored
+	if response["received_total"] != float64(1) || response["processed_total"] != float64(0) {
+		t.Errorf("Expected 1 received and 0 processed reports, got %v and %v", response["received_total"], response["processed_total"])
+	}
 }
 
 func TestAlternativeEndpoints(t *testing.T) {
@@ -460,3 +475,1016 @@ func TestSpecialCharactersAndEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestIssueEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	router := gin.New()
+	router.POST("/csp-report", server.handleCSPReport)
+	router.GET("/api/issues", server.handleListIssues)
+	router.GET("/api/issues/:fingerprint", server.handleGetIssue)
+
+	payloads := []string{
+		`{"csp-report": {"document-uri": "https://example.com/orders/1", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`,
+		`{"csp-report": {"document-uri": "https://example.com/orders/2", "violated-directive": "script-src", "blocked-uri": "https://evil.com/b.js"}}`,
+		`{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "img-src", "blocked-uri": "https://tracker.com/p.gif"}}`,
+	}
+	for _, payload := range payloads {
+		req := httptest.NewRequest("POST", "/csp-report", strings.NewReader(payload))
+		req.Header.Set("User-Agent", "Mozilla/5.0 Firefox/120.0")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/issues", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Total  int               `json:"total"`
+		Issues []processor.Issue `json:"issues"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response JSON: %v", err)
+	}
+
+	if response.Total != 2 || len(response.Issues) != 2 {
+		t.Fatalf("Expected 2 issues, got total=%d len=%d", response.Total, len(response.Issues))
+	}
+
+	top := response.Issues[0]
+	if top.Count != 2 || len(top.AffectedPages) != 2 || top.Browsers["firefox"] != 2 {
+		t.Errorf("Expected grouped issue with 2 reports and pages, got %+v", top)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/issues/"+top.Fingerprint, nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status %d for issue lookup, got %d", http.StatusOK, w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/issues/unknown", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown issue, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestOriginAllowlist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name             string
+		action           string
+		payload          string
+		expectedStatus   int
+		expectedAccepted float64
+	}{
+		{
+			name:             "allowed origin",
+			action:           config.OriginActionReject,
+			payload:          `{"csp-report": {"document-uri": "https://shop.example.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`,
+			expectedStatus:   http.StatusOK,
+			expectedAccepted: 1,
+		},
+		{
+			name:           "rejected origin",
+			action:         config.OriginActionReject,
+			payload:        `{"csp-report": {"document-uri": "https://attacker.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "rejected envelope url",
+			action:         config.OriginActionReject,
+			payload:        `[{"type": "csp-violation", "url": "https://attacker.com/", "body": {"documentURL": "https://shop.example.com/", "effectiveDirective": "script-src", "blockedURL": "https://evil.com/a.js"}}]`,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:             "quarantined origin",
+			action:           config.OriginActionQuarantine,
+			payload:          `{"csp-report": {"document-uri": "https://attacker.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`,
+			expectedStatus:   http.StatusOK,
+			expectedAccepted: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.config.OriginAction = tt.action
+			server.allowlist = models.NewOriginAllowlist([]string{"*.example.com"})
+
+			router := gin.New()
+			router.POST("/csp-report", server.handleCSPReport)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", "/csp-report", strings.NewReader(tt.payload)))
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+
+			if tt.expectedStatus == http.StatusOK {
+				var response map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to parse response JSON: %v", err)
+				}
+				if response["accepted"] != tt.expectedAccepted {
+					t.Errorf("Expected %v accepted, got %v", tt.expectedAccepted, response["accepted"])
+				}
+			}
+		})
+	}
+}
+
+func TestOriginRejectionMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+	server.allowlist = models.NewOriginAllowlist([]string{"example.com"})
+
+	router := gin.New()
+	router.POST("/csp-report", server.handleCSPReport)
+	router.GET("/metrics", server.handleMetrics)
+
+	payload := `{"csp-report": {"document-uri": "https://attacker.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`
+	for i := 0; i < 2; i++ {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/csp-report", strings.NewReader(payload)))
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	var response struct {
+		QueueSize        int64            `json:"queue_size"`
+		OriginRejections map[string]int64 `json:"origin_rejections"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response JSON: %v", err)
+	}
+	if response.OriginRejections["attacker.com"] != 2 {
+		t.Errorf("Expected 2 rejections for attacker.com, got %v", response.OriginRejections)
+	}
+}
+
+func TestProjectEndpoints(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	payload := `{"csp-report": {"document-uri": "https://shop.example.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`
+
+	tests := []struct {
+		name           string
+		project        config.ProjectConfig
+		path           string
+		requests       int
+		expectedStatus int
+	}{
+		{
+			name:           "valid key",
+			project:        config.ProjectConfig{ID: "shop", Key: "secret"},
+			path:           "/report/shop/secret",
+			requests:       1,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "csp alias",
+			project:        config.ProjectConfig{ID: "shop", Key: "secret"},
+			path:           "/csp/shop/secret",
+			requests:       1,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "wrong key",
+			project:        config.ProjectConfig{ID: "shop", Key: "secret"},
+			path:           "/report/shop/guess",
+			requests:       1,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "unknown project",
+			project:        config.ProjectConfig{ID: "shop", Key: "secret"},
+			path:           "/report/blog/secret",
+			requests:       1,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "rate limited",
+			project:        config.ProjectConfig{ID: "shop", Key: "secret", RateLimit: 1, RateBurst: 1},
+			path:           "/report/shop/secret",
+			requests:       2,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:           "quota exceeded",
+			project:        config.ProjectConfig{ID: "shop", Key: "secret", DailyQuota: 2},
+			path:           "/report/shop/secret",
+			requests:       3,
+			expectedStatus: http.StatusTooManyRequests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.projects = newProjects([]config.ProjectConfig{tt.project})
+
+			router := gin.New()
+			router.POST("/report/:project/:key", server.handleProjectReport)
+			router.POST("/csp/:project/:key", server.handleProjectReport)
+
+			var w *httptest.ResponseRecorder
+			for i := 0; i < tt.requests; i++ {
+				w = httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, strings.NewReader(payload)))
+			}
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestProjectQuota_IgnoresRejectedOrigins(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := createTestServer()
+	server.projects = newProjects([]config.ProjectConfig{{ID: "shop", Key: "secret", DailyQuota: 1}})
+	server.allowlist = models.NewOriginAllowlist([]string{"shop.example.com"})
+
+	router := gin.New()
+	router.POST("/report/:project/:key", server.handleProjectReport)
+
+	post := func(documentURI string) int {
+		payload := `{"csp-report": {"document-uri": "` + documentURI + `", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/report/shop/secret", strings.NewReader(payload)))
+		return w.Code
+	}
+
+	for i := 0; i < 3; i++ {
+		if code := post("https://forged.example.org/"); code != http.StatusForbidden {
+			t.Fatalf("Expected forged origin to be rejected with %d, got %d", http.StatusForbidden, code)
+		}
+	}
+	if code := post("https://shop.example.com/"); code != http.StatusOK {
+		t.Errorf("Expected the quota to be left for allowed reports, got status %d", code)
+	}
+	if code := post("https://shop.example.com/"); code != http.StatusTooManyRequests {
+		t.Errorf("Expected the quota to be used up, got status %d", code)
+	}
+}
+
+func TestProjectStampedOnReports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+
+	store := &mockStorage{}
+	batchProcessor := processor.New(config.BatchProcessorConfig{
+		WorkerCount:   1,
+		BatchSize:     1,
+		QueueSize:     10,
+		FlushInterval: 1,
+	}, store, logger)
+	batchProcessor.Start()
+
+	server := New(config.ServerConfig{
+		RateLimit: 1000,
+		RateBurst: 1000,
+		Projects:  []config.ProjectConfig{{ID: "shop", Key: "secret"}},
+	}, batchProcessor, store, logger)
+
+	router := gin.New()
+	router.POST("/report/:project/:key", server.handleProjectReport)
+
+	payload := `{"csp-report": {"document-uri": "https://shop.example.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/a.js"}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/report/shop/secret", strings.NewReader(payload)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for batchProcessor.GetStatus().ProcessedTotal == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	batchProcessor.Stop()
+
+	if len(store.reports) != 1 || store.reports[0][0].Project != "shop" {
+		t.Fatalf("Expected one stored report for project shop, got %v", store.reports)
+	}
+}
+
+type mockQueryStorage struct {
+	mockStorage
+	query storage.ReportQuery
+}
+
+func (m *mockQueryStorage) QueryReports(ctx context.Context, query storage.ReportQuery) (*storage.ReportPage, error) {
+	m.query = query
+	if query.Cursor == "bad" {
+		return nil, storage.ErrInvalidCursor
+	}
+	return &storage.ReportPage{
+		Reports: []*models.CSPReport{{ID: "r1"}},
+		Next:    "next-cursor",
+	}, nil
+}
+
+func TestAPIAuth_FailsClosedWithoutKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		disabled       bool
+		expectedStatus int
+	}{
+		{"unconfigured", false, http.StatusUnauthorized},
+		{"explicitly disabled", true, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.reports = &mockQueryStorage{}
+			server.config.APIAuthDisabled = tt.disabled
+
+			router := gin.New()
+			api := router.Group("/api")
+			api.Use(server.apiAuthMiddleware())
+			api.GET("/reports", server.handleQueryReports)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", "/api/reports", nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestAdminAuth_RequiresKeyWhenAuthDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		keys           []string
+		key            string
+		expectedStatus int
+	}{
+		{"auth disabled without keys", nil, "", http.StatusUnauthorized},
+		{"missing key", []string{"k1"}, "", http.StatusUnauthorized},
+		{"valid key", []string{"k1"}, "k1", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := createTestServer()
+			server.config.APIAuthDisabled = true
+			server.config.APIKeys = tt.keys
+
+			router := gin.New()
+			api := router.Group("/api")
+			api.Use(server.apiAuthMiddleware())
+			api.DELETE("/policies/:name", server.adminAuthMiddleware(), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("DELETE", "/api/policies/main", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestQueryReports(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &mockQueryStorage{}
+	server := createTestServer()
+	server.reports = store
+	server.config.APIKeys = []string{"k1", "k2"}
+
+	router := gin.New()
+	api := router.Group("/api")
+	api.Use(server.apiAuthMiddleware())
+	api.GET("/reports", server.handleQueryReports)
+
+	tests := []struct {
+		name           string
+		url            string
+		headers        map[string]string
+		expectedStatus int
+	}{
+		{"missing key", "/api/reports", nil, http.StatusUnauthorized},
+		{"wrong key", "/api/reports", map[string]string{"X-API-Key": "nope"}, http.StatusUnauthorized},
+		{"header key", "/api/reports", map[string]string{"X-API-Key": "k2"}, http.StatusOK},
+		{"bearer key", "/api/reports", map[string]string{"Authorization": "Bearer k1"}, http.StatusOK},
+		{"invalid time", "/api/reports?from=yesterday", map[string]string{"X-API-Key": "k1"}, http.StatusBadRequest},
+		{"invalid size", "/api/reports?size=-1", map[string]string{"X-API-Key": "k1"}, http.StatusBadRequest},
+		{"invalid cursor", "/api/reports?cursor=bad", map[string]string{"X-API-Key": "k1"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	req := httptest.NewRequest("GET", "/api/reports?from=2024-01-01T00:00:00Z&directive=script-src&blocked_uri=https://evil.com/&document_host=example.com&browser=chrome&fingerprint=f1&size=10&cursor=c1", nil)
+	req.Header.Set("X-API-Key", "k1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	expected := storage.ReportQuery{
+		From:         time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Directive:    "script-src",
+		BlockedURI:   "https://evil.com/",
+		DocumentHost: "example.com",
+		Browser:      "chrome",
+		Fingerprint:  "f1",
+		Size:         10,
+		Cursor:       "c1",
+	}
+	if !store.query.From.Equal(expected.From) {
+		t.Errorf("Expected from %v, got %v", expected.From, store.query.From)
+	}
+	store.query.From = expected.From
+	if store.query != expected {
+		t.Errorf("Expected query %+v, got %+v", expected, store.query)
+	}
+
+	var page storage.ReportPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to parse response JSON: %v", err)
+	}
+	if len(page.Reports) != 1 || page.Next != "next-cursor" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+}
+
+func TestQueryReports_Unsupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	router := gin.New()
+	router.GET("/api/reports", server.handleQueryReports)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/reports", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
+
+type mockSummaryStorage struct {
+	mockStorage
+	query storage.SummaryQuery
+}
+
+func (m *mockSummaryStorage) SummarizeReports(ctx context.Context, query storage.SummaryQuery) (*storage.Summary, error) {
+	m.query = query
+	return &storage.Summary{
+		From:  query.From,
+		To:    query.To,
+		Total: 3,
+		Top:   map[string][]storage.SummaryBucket{"directives": {{Key: "script-src", Count: 3}}},
+	}, nil
+}
+
+func TestHandleSummary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	store := &mockSummaryStorage{}
+	server := createTestServer()
+	server.summaries = store
+
+	router := gin.New()
+	router.GET("/api/summary", server.handleSummary)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{"default window", "/api/summary", http.StatusOK},
+		{"compare windows", "/api/summary?from=2024-01-02T00:00:00Z&to=2024-01-03T00:00:00Z&compare_from=2024-01-01T00:00:00Z&compare_to=2024-01-02T00:00:00Z", http.StatusOK},
+		{"half a comparison", "/api/summary?compare_from=2024-01-01T00:00:00Z", http.StatusBadRequest},
+		{"invalid time", "/api/summary?to=tomorrow", http.StatusBadRequest},
+		{"invalid size", "/api/summary?size=0", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/summary?size=5", nil))
+
+	if window := store.query.To.Sub(store.query.From); window != defaultSummaryWindow {
+		t.Errorf("Expected default window of %v, got %v", defaultSummaryWindow, window)
+	}
+	if store.query.Size != 5 || !store.query.CompareFrom.IsZero() {
+		t.Errorf("Unexpected query: %+v", store.query)
+	}
+
+	var summary storage.Summary
+	if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("Failed to parse response JSON: %v", err)
+	}
+	if summary.Total != 3 || summary.Top["directives"][0].Key != "script-src" {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+}
+
+func TestDashboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	router := gin.New()
+	server.registerDashboard(router)
+
+	tests := []struct {
+		path           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"/", http.StatusFound, ""},
+		{"/dashboard/", http.StatusOK, "<title>CSP Reports</title>"},
+		{"/dashboard/app.js", http.StatusOK, "/api/summary"},
+		{"/dashboard/style.css", http.StatusOK, ".card"},
+		{"/dashboard/missing.js", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.expectedBody) {
+				t.Errorf("Expected body to contain %q", tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestHandleStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	router := gin.New()
+	router.GET("/api/stream", server.handleStream)
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"/api/stream?directive=script-src", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Stream request failed: %v", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	for _, directive := range []string{"img-src", "script-src"} {
+		reports, err := models.ParseCSPReports([]byte(`{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "`+directive+`", "blocked-uri": "https://evil.com/a.js"}}`), "test", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Failed to parse report: %v", err)
+		}
+		server.processor.Submit(reports[0])
+	}
+
+	scanner := bufio.NewScanner(res.Body)
+	var event, data string
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "event: ") {
+			event = strings.TrimPrefix(line, "event: ")
+		}
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(line, "data: ")
+			break
+		}
+	}
+
+	if event != "report" {
+		t.Fatalf("Expected report event, got %q", event)
+	}
+	var report models.CSPReport
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		t.Fatalf("Invalid event data: %v", err)
+	}
+	if report.ParsedReport.ViolatedDirective != "script-src" {
+		t.Errorf("Expected only the script-src report, got %s", report.ParsedReport.ViolatedDirective)
+	}
+}
+
+func TestHandleStream_TooManySubscribers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	batchProcessor := processor.New(config.BatchProcessorConfig{
+		WorkerCount: 1,
+		BatchSize:   1,
+		QueueSize:   10,
+		Stream:      config.StreamConfig{MaxSubscribers: 1},
+	}, &mockStorage{}, logger)
+	server := New(config.ServerConfig{RateLimit: 1000, RateBurst: 1000}, batchProcessor, &mockStorage{}, logger)
+
+	if _, err := batchProcessor.Broker().Subscribe(processor.StreamFilter{}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/api/stream", server.handleStream)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/stream", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, w.Code)
+	}
+}
+
+func TestSilences(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	batchProcessor := processor.New(config.BatchProcessorConfig{
+		WorkerCount: 1,
+		BatchSize:   1,
+		QueueSize:   10,
+		Alerting: config.AlertingConfig{
+			Enabled:     true,
+			NewIssues:   true,
+			DedupWindow: 3600,
+		},
+	}, &mockStorage{}, logger)
+	server := New(config.ServerConfig{RateLimit: 1000, RateBurst: 1000}, batchProcessor, &mockStorage{}, logger)
+
+	router := gin.New()
+	router.GET("/api/alerts", server.handleListAlerts)
+	router.GET("/api/silences", server.handleListSilences)
+	router.POST("/api/silences", server.handleCreateSilence)
+	router.DELETE("/api/silences/:fingerprint", server.handleDeleteSilence)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"missing duration", "POST", "/api/silences", `{"fingerprint":"abc"}`, http.StatusBadRequest},
+		{"invalid duration", "POST", "/api/silences", `{"fingerprint":"abc","duration":"soon"}`, http.StatusBadRequest},
+		{"create", "POST", "/api/silences", `{"fingerprint":"abc","duration":"2h"}`, http.StatusCreated},
+		{"delete", "DELETE", "/api/silences/abc", "", http.StatusNoContent},
+		{"delete missing", "DELETE", "/api/silences/abc", "", http.StatusNotFound},
+		{"alerts", "GET", "/api/alerts", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	batchProcessor.Alerter().Silence("abc", time.Now().Add(time.Hour))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/silences", nil))
+
+	var response struct {
+		Silences []struct {
+			Fingerprint string `json:"fingerprint"`
+		} `json:"silences"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(response.Silences) != 1 || response.Silences[0].Fingerprint != "abc" {
+		t.Errorf("Expected silence for abc, got %+v", response.Silences)
+	}
+}
+
+func TestSilences_AlertingDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	router := gin.New()
+	router.GET("/api/alerts", server.handleListAlerts)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/alerts", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
+
+type mockReportPages struct {
+	mockStorage
+	query   storage.ReportQuery
+	reports []*models.CSPReport
+}
+
+func (m *mockReportPages) QueryReports(ctx context.Context, query storage.ReportQuery) (*storage.ReportPage, error) {
+	m.query = query
+	return &storage.ReportPage{Reports: m.reports}, nil
+}
+
+func TestHandleRecommendations(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	report := &models.CSPReport{ParsedReport: &models.ParsedCSPReport{
+		DocumentURI:       "https://shop.example.com/cart",
+		ViolatedDirective: "script-src",
+		BlockedURI:        "https://cdn.example.net/lib.js",
+		OriginalPolicy:    "default-src 'self'",
+	}}
+	store := &mockReportPages{reports: []*models.CSPReport{report, report}}
+	server := createTestServer()
+	server.reports = store
+
+	router := gin.New()
+	router.GET("/api/recommendations", server.handleRecommendations)
+
+	tests := []struct {
+		name           string
+		url            string
+		expectedStatus int
+	}{
+		{"default", "/api/recommendations?document_host=shop.example.com", http.StatusOK},
+		{"invalid from", "/api/recommendations?from=last-week", http.StatusBadRequest},
+		{"invalid min evidence", "/api/recommendations?min_evidence=0", http.StatusBadRequest},
+		{"invalid max reports", "/api/recommendations?max_reports=-1", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.url, nil))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/recommendations?document_host=shop.example.com&min_evidence=2", nil))
+
+	var response struct {
+		Truncated       bool `json:"truncated"`
+		Recommendations []struct {
+			DocumentHost string `json:"document_host"`
+			Policy       string `json:"policy"`
+		} `json:"recommendations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if store.query.DocumentHost != "shop.example.com" || store.query.From.IsZero() {
+		t.Errorf("Expected host filter and default window, got %+v", store.query)
+	}
+	if len(response.Recommendations) != 1 {
+		t.Fatalf("Expected 1 recommendation, got %d", len(response.Recommendations))
+	}
+	expected := "default-src 'self'; script-src 'self' https://cdn.example.net"
+	if response.Recommendations[0].Policy != expected {
+		t.Errorf("Expected policy %q, got %q", expected, response.Recommendations[0].Policy)
+	}
+}
+
+func TestHandleRecommendations_Unsupported(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	router := gin.New()
+	router.GET("/api/recommendations", server.handleRecommendations)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/recommendations", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
+
+func TestHandleInlineHashes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	for _, page := range []string{"https://shop.example.com/cart?id=1", "https://shop.example.com/"} {
+		report := &models.CSPReport{ParsedReport: &models.ParsedCSPReport{
+			DocumentURI:        page,
+			EffectiveDirective: "script-src-elem",
+			BlockedURI:         "inline",
+			SHA256:             "sha256-abc=",
+		}}
+		server.processor.Submit(report)
+	}
+
+	router := gin.New()
+	router.GET("/api/inline", server.handleInlineHashes)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/inline?page=https://shop.example.com/cart", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var response struct {
+		Total int                    `json:"total"`
+		Pages []processor.InlinePage `json:"pages"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Total != 1 || len(response.Pages) != 1 {
+		t.Fatalf("Expected 1 page, got %d", response.Total)
+	}
+	if hashes := response.Pages[0].Hashes; len(hashes) != 1 || hashes[0].Hash != "'sha256-abc='" {
+		t.Errorf("Expected the reported hash, got %+v", hashes)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/inline?limit=x", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestHandleRollouts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	policy := "default-src 'self'"
+	report := &models.CSPReport{
+		Timestamp: time.Now().UTC(),
+		ParsedReport: &models.ParsedCSPReport{
+			DocumentURI:       "https://example.com/",
+			ViolatedDirective: "script-src",
+			BlockedURI:        "https://evil.com/x.js",
+			OriginalPolicy:    policy,
+			Disposition:       "report",
+		},
+		PolicyFingerprint: models.PolicyFingerprint(policy),
+	}
+	report.Fingerprint = models.ComputeFingerprint(report.ParsedReport)
+	server.processor.Submit(report)
+
+	router := gin.New()
+	router.GET("/api/rollouts", server.handleListRollouts)
+	router.GET("/api/rollouts/:policy", server.handleGetRollout)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/rollouts", nil))
+
+	var list struct {
+		Rollouts []processor.PolicyRollout `json:"rollouts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(list.Rollouts) != 1 || list.Rollouts[0].ReportOnly != 1 {
+		t.Fatalf("Expected 1 rollout with 1 report-only violation, got %+v", list.Rollouts)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/rollouts/"+report.PolicyFingerprint, nil))
+	var detail processor.PolicyRollout
+	if err := json.Unmarshal(w.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(detail.Violations) != 1 || detail.Violations[0].Fingerprint != report.Fingerprint {
+		t.Errorf("Expected the violation to be listed, got %+v", detail.Violations)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/rollouts/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandlePolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+	server.config.PublicURL = "https://csp.example.com"
+	server.projects = newProjects([]config.ProjectConfig{{ID: "shop", Key: "secret"}})
+	server.registry = newRegistryFor(t.TempDir()+"/policies.json", server.logger)
+
+	router := gin.New()
+	router.GET("/api/policies", server.handleListPolicies)
+	router.POST("/api/policies/:name/versions", server.handleAddPolicyVersion)
+	router.PUT("/api/policies/:name/active", server.handleActivatePolicy)
+	router.GET("/api/policies/:name/headers", server.handlePolicyHeaders)
+	router.DELETE("/api/policies/:name", server.handleDeletePolicy)
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"add first version", "POST", "/api/policies/shop/versions", `{"site":"shop.example.com","project":"shop","policy":"default-src 'self'"}`, http.StatusCreated},
+		{"add second version", "POST", "/api/policies/shop/versions", `{"policy":"default-src 'self'; img-src *","report_only":true}`, http.StatusCreated},
+		{"unknown project", "POST", "/api/policies/blog/versions", `{"site":"blog.example.com","project":"blog","policy":"default-src 'self'"}`, http.StatusBadRequest},
+		{"empty policy", "POST", "/api/policies/blog/versions", `{"site":"blog.example.com","policy":"report-uri /x"}`, http.StatusBadRequest},
+		{"activate missing version", "PUT", "/api/policies/shop/active", `{"version":5}`, http.StatusNotFound},
+		{"activate", "PUT", "/api/policies/shop/active", `{"version":2}`, http.StatusOK},
+		{"headers of missing policy", "GET", "/api/policies/blog/headers", "", http.StatusNotFound},
+		{"list", "GET", "/api/policies?site=shop.example.com", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/policies/shop/headers", nil))
+
+	var response struct {
+		Version int               `json:"version"`
+		Headers map[string]string `json:"headers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	reportURL := "https://csp.example.com/report/shop/secret"
+	if response.Version != 2 || response.Headers["Reporting-Endpoints"] != `csp-endpoint="`+reportURL+`"` {
+		t.Errorf("Expected active version 2 reporting to the project endpoint, got %+v", response)
+	}
+
+	emitted := response.Headers["Content-Security-Policy-Report-Only"]
+	report := &models.CSPReport{PolicyFingerprint: models.PolicyFingerprint(emitted)}
+	server.linkPolicy(report)
+	if report.PolicyName != "shop" || report.PolicyVersion != 2 {
+		t.Errorf("Expected report linked to shop v2, got %q v%d", report.PolicyName, report.PolicyVersion)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("DELETE", "/api/policies/shop", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+	}
+}
+
+func TestHandlePolicies_Disabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := createTestServer()
+
+	router := gin.New()
+	router.GET("/api/policies", server.handleListPolicies)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/policies", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("Expected status %d, got %d", http.StatusNotImplemented, w.Code)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"universal-csp-report/internal/processor"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat keeps idle streams open through proxies.
+const streamHeartbeat = 15 * time.Second
+
+// handleStream tails incoming reports as Server-Sent Events. Each report is
+// sent as a "report" event; a "dropped" event reports how many reports this
+// client missed because it fell behind.
+func (s *Server) handleStream(c *gin.Context) {
+	sub, err := s.processor.Broker().Subscribe(processor.StreamFilter{
+		Project:   c.Query("project"),
+		Directive: c.Query("directive"),
+		Host:      c.Query("host"),
+	})
+	if errors.Is(err, processor.ErrTooManySubscribers) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many stream subscribers"})
+		return
+	}
+	defer s.processor.Broker().Unsubscribe(sub)
+
+	// Streams outlive the server's write timeout
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		s.logger.WithError(err).Debug("Failed to clear write deadline for stream")
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	var reportedDrops int64
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case report, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+
+			if dropped := sub.Dropped(); dropped > reportedDrops {
+				if _, err := fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped-reportedDrops); err != nil {
+					return false
+				}
+				reportedDrops = dropped
+			}
+
+			data, err := json.Marshal(report)
+			if err != nil {
+				s.logger.WithError(err).Error("Failed to marshal streamed report")
+				return true
+			}
+			_, err = fmt.Fprintf(w, "event: report\ndata: %s\n\n", data)
+			return err == nil
+		}
+	})
+}
@@ -0,0 +1,429 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+const (
+	chConnectionTimeout = 10 * time.Second
+	chInsertTimeout     = 30 * time.Second
+	chTimestampFormat   = "2006-01-02 15:04:05.000"
+)
+
+// chRollupKey is the sorting key of the rollup table, made of every
+// dimension so rows are only summed within the same combination.
+const chRollupKey = "(interval, bucket_start, directive, blocked_host, document_host, browser_type, disposition, fingerprint, document_route)"
+
+// chAddedColumns lists columns introduced after the initial schema. They are
+// added to existing tables on startup; append new columns here as well as to
+// the CREATE TABLE statement.
+var chAddedColumns = []string{
+	"fingerprint String",
+	"occurrences UInt32 DEFAULT 1",
+	"first_occurrence Nullable(DateTime64(3, 'UTC'))",
+	"last_occurrence Nullable(DateTime64(3, 'UTC'))",
+	"sample_rate Float64 DEFAULT 1",
+	"tags Array(LowCardinality(String))",
+	"downgraded Bool DEFAULT false",
+	"project LowCardinality(String)",
+	"policy_fingerprint LowCardinality(String)",
+	"policy_name LowCardinality(String)",
+	"policy_version UInt32 DEFAULT 0",
+	"governing_directive LowCardinality(String)",
+	"blocked_scheme LowCardinality(String)",
+	"blocked_domain LowCardinality(String)",
+	"blocked_port UInt16 DEFAULT 0",
+	"blocked_path String",
+	"blocked_categories Array(LowCardinality(String))",
+	"document_route String",
+	"redactions Nested(field LowCardinality(String), rule LowCardinality(String), count UInt32)",
+}
+
+type ClickHouseStorage struct {
+	client *http.Client
+	config config.ClickHouseConfig
+}
+
+// clickHouseRow is the flattened, columnar-friendly representation of a
+// CSPReport written with the JSONEachRow input format.
+type clickHouseRow struct {
+	ID                 string   `json:"id"`
+	Project            string   `json:"project"`
+	Timestamp          string   `json:"timestamp"`
+	UserAgent          string   `json:"user_agent"`
+	RemoteAddr         string   `json:"remote_addr"`
+	Browser            string   `json:"browser"`
+	DocumentURI        string   `json:"document_uri"`
+	DocumentHost       string   `json:"document_host"`
+	DocumentRoute      string   `json:"document_route"`
+	Referrer           string   `json:"referrer"`
+	Directive          string   `json:"directive"`
+	GoverningDirective string   `json:"governing_directive"`
+	ViolatedDirective  string   `json:"violated_directive"`
+	EffectiveDirective string   `json:"effective_directive"`
+	BlockedURI         string   `json:"blocked_uri"`
+	BlockedHost        string   `json:"blocked_host"`
+	BlockedScheme      string   `json:"blocked_scheme"`
+	BlockedDomain      string   `json:"blocked_domain"`
+	BlockedPort        int      `json:"blocked_port"`
+	BlockedPath        string   `json:"blocked_path"`
+	BlockedCategories  []string `json:"blocked_categories"`
+	OriginalPolicy     string   `json:"original_policy"`
+	Disposition        string   `json:"disposition"`
+	StatusCode         *int     `json:"status_code"`
+	ScriptSample       string   `json:"script_sample"`
+	SourceFile         string   `json:"source_file"`
+	LineNumber         *int     `json:"line_number"`
+	ColumnNumber       *int     `json:"column_number"`
+	SHA256             string   `json:"sha256"`
+	HumanReadable      string   `json:"human_readable"`
+	Fingerprint        string   `json:"fingerprint"`
+	PolicyFingerprint  string   `json:"policy_fingerprint"`
+	PolicyName         string   `json:"policy_name"`
+	PolicyVersion      int      `json:"policy_version"`
+	Occurrences        int      `json:"occurrences"`
+	FirstOccurrence    *string  `json:"first_occurrence"`
+	LastOccurrence     *string  `json:"last_occurrence"`
+	SampleRate         float64  `json:"sample_rate"`
+	Tags               []string `json:"tags"`
+	Downgraded         bool     `json:"downgraded"`
+	ProcessingErrors   []string `json:"processing_errors"`
+	RedactionFields    []string `json:"redactions.field"`
+	RedactionRules     []string `json:"redactions.rule"`
+	RedactionCounts    []int    `json:"redactions.count"`
+	RawReport          string   `json:"raw_report"`
+}
+
+func NewClickHouseClient(cfg config.ClickHouseConfig) (Storage, error) {
+	storage := &ClickHouseStorage{
+		client: &http.Client{},
+		config: cfg,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chConnectionTimeout)
+	defer cancel()
+
+	if err := storage.exec(ctx, "SELECT 1", nil); err != nil {
+		return nil, fmt.Errorf("failed to connect to ClickHouse: %w", err)
+	}
+
+	if err := storage.ensureTable(); err != nil {
+		return nil, fmt.Errorf("failed to ensure table: %w", err)
+	}
+
+	return storage, nil
+}
+
+func (ch *ClickHouseStorage) StoreBatch(reports []*models.CSPReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	for _, report := range reports {
+		rowBytes, err := json.Marshal(flattenReport(report))
+		if err != nil {
+			return fmt.Errorf("failed to marshal row: %w", err)
+		}
+
+		buf.Write(rowBytes)
+		buf.WriteByte('\n')
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chInsertTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", ch.tableName())
+	if err := ch.exec(ctx, query, &buf); err != nil {
+		return fmt.Errorf("insert request failed: %w", err)
+	}
+
+	return nil
+}
+
+// StoreRollups inserts aggregates into the rollup table. Its
+// SummingMergeTree engine adds up counts for the same bucket and dimensions.
+func (ch *ClickHouseStorage) StoreRollups(rollups []*models.Rollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	for _, rollup := range rollups {
+		row := map[string]interface{}{
+			"bucket_start": rollup.BucketStart.UTC().Format(chTimestampFormat),
+			"interval":     rollup.Interval,
+			"count":        rollup.Count,
+		}
+		for _, dimension := range models.RollupDimensions {
+			row[dimension] = rollup.Dimensions[dimension]
+		}
+
+		rowBytes, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rollup row: %w", err)
+		}
+
+		buf.Write(rowBytes)
+		buf.WriteByte('\n')
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), chInsertTimeout)
+	defer cancel()
+
+	query := fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", ch.rollupTableName())
+	if err := ch.exec(ctx, query, &buf); err != nil {
+		return fmt.Errorf("rollup insert request failed: %w", err)
+	}
+
+	return nil
+}
+
+func (ch *ClickHouseStorage) Close() error {
+	ch.client.CloseIdleConnections()
+	return nil
+}
+
+func (ch *ClickHouseStorage) tableName() string {
+	return fmt.Sprintf("`%s`.`%s`", ch.config.Database, ch.config.Table)
+}
+
+func (ch *ClickHouseStorage) rollupTableName() string {
+	return fmt.Sprintf("`%s`.`%s_rollups`", ch.config.Database, ch.config.Table)
+}
+
+func (ch *ClickHouseStorage) ensureTable() error {
+	ctx, cancel := context.WithTimeout(context.Background(), chConnectionTimeout)
+	defer cancel()
+
+	if err := ch.exec(ctx, fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", ch.config.Database), nil); err != nil {
+		return fmt.Errorf("database creation error: %w", err)
+	}
+
+	ddl := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id String,
+	project LowCardinality(String),
+	timestamp DateTime64(3, 'UTC'),
+	user_agent String,
+	remote_addr String,
+	browser LowCardinality(String),
+	document_uri String,
+	document_host LowCardinality(String),
+	document_route String,
+	referrer String,
+	directive LowCardinality(String),
+	governing_directive LowCardinality(String),
+	violated_directive String,
+	effective_directive LowCardinality(String),
+	blocked_uri String,
+	blocked_host LowCardinality(String),
+	blocked_scheme LowCardinality(String),
+	blocked_domain LowCardinality(String),
+	blocked_port UInt16 DEFAULT 0,
+	blocked_path String,
+	blocked_categories Array(LowCardinality(String)),
+	original_policy String,
+	disposition LowCardinality(String),
+	status_code Nullable(Int32),
+	script_sample String,
+	source_file String,
+	line_number Nullable(Int32),
+	column_number Nullable(Int32),
+	sha256 String,
+	human_readable String,
+	fingerprint String,
+	policy_fingerprint LowCardinality(String),
+	policy_name LowCardinality(String),
+	policy_version UInt32 DEFAULT 0,
+	occurrences UInt32 DEFAULT 1,
+	first_occurrence Nullable(DateTime64(3, 'UTC')),
+	last_occurrence Nullable(DateTime64(3, 'UTC')),
+	sample_rate Float64 DEFAULT 1,
+	tags Array(LowCardinality(String)),
+	downgraded Bool DEFAULT false,
+	processing_errors Array(String),
+	redactions Nested(field LowCardinality(String), rule LowCardinality(String), count UInt32),
+	raw_report String
+) ENGINE = MergeTree
+PARTITION BY toYYYYMMDD(timestamp)
+ORDER BY (directive, blocked_host, document_host, timestamp)`, ch.tableName())
+
+	if err := ch.exec(ctx, ddl, nil); err != nil {
+		return fmt.Errorf("table creation error: %w", err)
+	}
+
+	for _, column := range chAddedColumns {
+		alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", ch.tableName(), column)
+		if err := ch.exec(ctx, alter, nil); err != nil {
+			return fmt.Errorf("column migration error: %w", err)
+		}
+	}
+
+	rollupDDL := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	bucket_start DateTime64(3, 'UTC'),
+	interval LowCardinality(String),
+	directive LowCardinality(String),
+	blocked_host LowCardinality(String),
+	document_host LowCardinality(String),
+	browser_type LowCardinality(String),
+	disposition LowCardinality(String),
+	fingerprint String,
+	document_route String,
+	count UInt64
+) ENGINE = SummingMergeTree(count)
+PARTITION BY toYYYYMM(bucket_start)
+ORDER BY %s`, ch.rollupTableName(), chRollupKey)
+
+	if err := ch.exec(ctx, rollupDDL, nil); err != nil {
+		return fmt.Errorf("rollup table creation error: %w", err)
+	}
+
+	// SummingMergeTree only keeps dimensions apart that are in the sorting
+	// key, so the route column joins it in the same statement
+	alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS document_route String, MODIFY ORDER BY %s", ch.rollupTableName(), chRollupKey)
+	if err := ch.exec(ctx, alter, nil); err != nil {
+		return fmt.Errorf("rollup column migration error: %w", err)
+	}
+
+	return nil
+}
+
+// exec runs a single statement against the ClickHouse HTTP interface. When
+// body is non-nil it is sent as the statement's input data.
+func (ch *ClickHouseStorage) exec(ctx context.Context, query string, body io.Reader) error {
+	endpoint, err := url.Parse(ch.config.URL)
+	if err != nil {
+		return fmt.Errorf("invalid ClickHouse URL: %w", err)
+	}
+
+	params := endpoint.Query()
+	params.Set("query", query)
+	endpoint.RawQuery = params.Encode()
+
+	if body == nil {
+		body = http.NoBody
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if ch.config.Username != "" {
+		req.Header.Set("X-ClickHouse-User", ch.config.Username)
+		req.Header.Set("X-ClickHouse-Key", ch.config.Password)
+	}
+
+	res, err := ch.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("clickhouse error: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+func flattenReport(report *models.CSPReport) clickHouseRow {
+	row := clickHouseRow{
+		ID:                report.ID,
+		Project:           report.Project,
+		Timestamp:         report.Timestamp.UTC().Format(chTimestampFormat),
+		UserAgent:         report.UserAgent,
+		RemoteAddr:        report.RemoteAddr,
+		Browser:           report.BrowserType,
+		HumanReadable:     report.HumanReadable,
+		Fingerprint:       report.Fingerprint,
+		DocumentRoute:     report.DocumentRoute,
+		PolicyFingerprint: report.PolicyFingerprint,
+		PolicyName:        report.PolicyName,
+		PolicyVersion:     report.PolicyVersion,
+		Occurrences:       report.Occurrences,
+		FirstOccurrence:   formatOptionalTime(report.FirstOccurrence),
+		LastOccurrence:    formatOptionalTime(report.LastOccurrence),
+		SampleRate:        report.SampleRate,
+		Tags:              report.Tags,
+		Downgraded:        report.Downgraded,
+		ProcessingErrors:  report.ProcessingErrors,
+	}
+
+	for _, redaction := range report.Redactions {
+		row.RedactionFields = append(row.RedactionFields, redaction.Field)
+		row.RedactionRules = append(row.RedactionRules, redaction.Rule)
+		row.RedactionCounts = append(row.RedactionCounts, redaction.Count)
+	}
+
+	if row.Occurrences == 0 {
+		row.Occurrences = 1
+	}
+
+	if row.SampleRate == 0 {
+		row.SampleRate = 1
+	}
+
+	if row.Tags == nil {
+		row.Tags = []string{}
+	}
+
+	if row.ProcessingErrors == nil {
+		row.ProcessingErrors = []string{}
+	}
+
+	if report.RawReport != nil {
+		if rawBytes, err := json.Marshal(report.RawReport); err == nil {
+			row.RawReport = string(rawBytes)
+		}
+	}
+
+	if parsed := report.ParsedReport; parsed != nil {
+		row.DocumentURI = parsed.DocumentURI
+		row.DocumentHost = models.URLHost(parsed.DocumentURI)
+		row.Referrer = parsed.Referrer
+		row.ViolatedDirective = parsed.ViolatedDirective
+		row.EffectiveDirective = parsed.EffectiveDirective
+		row.Directive = parsed.CanonicalDirective()
+		row.GoverningDirective = parsed.GoverningDirective()
+		row.BlockedURI = parsed.BlockedURI
+		row.BlockedHost = models.URLHost(parsed.BlockedURI)
+		blocked := parsed.BlockedResource()
+		row.BlockedScheme = blocked.Scheme
+		row.BlockedDomain = blocked.Domain
+		row.BlockedPort = blocked.Port
+		row.BlockedPath = blocked.Path
+		row.BlockedCategories = blocked.Categories
+		row.OriginalPolicy = parsed.OriginalPolicy
+		row.Disposition = parsed.DispositionName()
+		row.StatusCode = parsed.StatusCode
+		row.ScriptSample = parsed.ScriptSample
+		row.SourceFile = parsed.SourceFile
+		row.LineNumber = parsed.LineNumber
+		row.ColumnNumber = parsed.ColumnNumber
+		row.SHA256 = parsed.SHA256
+	}
+
+	return row
+}
+
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.UTC().Format(chTimestampFormat)
+	return &formatted
+}
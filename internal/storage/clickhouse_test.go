@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+type clickHouseStub struct {
+	mu      sync.Mutex
+	queries []string
+	bodies  []string
+	user    string
+}
+
+func (s *clickHouseStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.queries = append(s.queries, r.URL.Query().Get("query"))
+	s.bodies = append(s.bodies, string(body))
+	s.user = r.Header.Get("X-ClickHouse-User")
+	s.mu.Unlock()
+
+	if query := r.URL.Query().Get("query"); strings.HasPrefix(query, "INSERT") && strings.Contains(query, "broken") {
+		http.Error(w, "Code: 60. DB::Exception: Table does not exist", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func newClickHouseTestStorage(t *testing.T, table string) (*ClickHouseStorage, *clickHouseStub) {
+	t.Helper()
+
+	stub := &clickHouseStub{}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	store, err := NewClickHouseClient(config.ClickHouseConfig{
+		URL:      srv.URL,
+		Database: "csp",
+		Table:    table,
+		Username: "writer",
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create ClickHouse storage: %v", err)
+	}
+
+	return store.(*ClickHouseStorage), stub
+}
+
+func TestClickHouseStorage_EnsuresTableOnStartup(t *testing.T) {
+	_, stub := newClickHouseTestStorage(t, "reports")
+
+	if len(stub.queries) != 5+len(chAddedColumns) {
+		t.Fatalf("Expected %d startup queries, got %d: %v", 5+len(chAddedColumns), len(stub.queries), stub.queries)
+	}
+	if !strings.HasPrefix(stub.queries[1], "CREATE DATABASE IF NOT EXISTS `csp`") {
+		t.Errorf("Expected database creation, got %q", stub.queries[1])
+	}
+	if !strings.Contains(stub.queries[2], "CREATE TABLE IF NOT EXISTS `csp`.`reports`") {
+		t.Errorf("Expected table creation, got %q", stub.queries[2])
+	}
+	for _, column := range []string{"directive LowCardinality(String)", "blocked_host LowCardinality(String)", "document_host LowCardinality(String)", "browser LowCardinality(String)"} {
+		if !strings.Contains(stub.queries[2], column) {
+			t.Errorf("Expected table DDL to contain %q", column)
+		}
+	}
+	for i, column := range chAddedColumns {
+		expected := "ALTER TABLE `csp`.`reports` ADD COLUMN IF NOT EXISTS " + column
+		if stub.queries[3+i] != expected {
+			t.Errorf("Expected column migration %q, got %q", expected, stub.queries[3+i])
+		}
+	}
+	if rollup := stub.queries[len(stub.queries)-2]; !strings.Contains(rollup, "CREATE TABLE IF NOT EXISTS `csp`.`reports_rollups`") || !strings.Contains(rollup, "SummingMergeTree(count)") {
+		t.Errorf("Expected rollup table creation, got %q", rollup)
+	}
+	expected := "ALTER TABLE `csp`.`reports_rollups` ADD COLUMN IF NOT EXISTS document_route String, MODIFY ORDER BY " + chRollupKey
+	if migration := stub.queries[len(stub.queries)-1]; migration != expected {
+		t.Errorf("Expected rollup route migration %q, got %q", expected, migration)
+	}
+	if stub.user != "writer" {
+		t.Errorf("Expected X-ClickHouse-User 'writer', got %q", stub.user)
+	}
+}
+
+func TestClickHouseStorage_StoreBatch(t *testing.T) {
+	store, stub := newClickHouseTestStorage(t, "reports")
+
+	reports, err := models.ParseCSPReports([]byte(`[
+		{"type": "csp-violation", "body": {"documentURL": "https://shop.example.com/cart", "effectiveDirective": "script-src-elem", "blockedURL": "https://cdn.evil.com/x.js"}},
+		{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "img-src 'self'", "blocked-uri": "data"}}
+	]`), "Mozilla/5.0 Firefox/120.0", "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Failed to parse reports: %v", err)
+	}
+	reports[0].Timestamp = time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+
+	if err := store.StoreBatch(reports); err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	last := len(stub.queries) - 1
+	if stub.queries[last] != "INSERT INTO `csp`.`reports` FORMAT JSONEachRow" {
+		t.Errorf("Unexpected insert query %q", stub.queries[last])
+	}
+
+	var rows []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(stub.bodies[last]))
+	for scanner.Scan() {
+		var row map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("Invalid JSONEachRow line %q: %v", scanner.Text(), err)
+		}
+		rows = append(rows, row)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+
+	expected := map[string]interface{}{
+		"timestamp":           "2024-01-02 03:04:05.006",
+		"browser":             "firefox",
+		"directive":           "script-src",
+		"governing_directive": "script-src-elem",
+		"effective_directive": "script-src-elem",
+		"document_host":       "shop.example.com",
+		"blocked_host":        "cdn.evil.com",
+		"blocked_scheme":      "https",
+		"blocked_domain":      "evil.com",
+		"blocked_port":        float64(443),
+		"blocked_path":        "/x.js",
+	}
+	for key, want := range expected {
+		if rows[0][key] != want {
+			t.Errorf("%s: expected %v, got %v", key, want, rows[0][key])
+		}
+	}
+
+	if categories, _ := rows[0]["blocked_categories"].([]interface{}); len(categories) != 1 || categories[0] != "third-party" {
+		t.Errorf("Expected blocked_categories [third-party], got %v", rows[0]["blocked_categories"])
+	}
+
+	if rows[1]["directive"] != "img-src" {
+		t.Errorf("Expected directive name stripped of sources, got %v", rows[1]["directive"])
+	}
+	if rows[1]["blocked_host"] != "" {
+		t.Errorf("Expected empty blocked_host for keyword URI, got %v", rows[1]["blocked_host"])
+	}
+	if raw, _ := rows[1]["raw_report"].(string); !strings.Contains(raw, "csp-report") {
+		t.Errorf("Expected raw_report to contain original JSON, got %q", raw)
+	}
+}
+
+func TestClickHouseStorage_StoreRollups(t *testing.T) {
+	store, stub := newClickHouseTestStorage(t, "reports")
+
+	err := store.StoreRollups([]*models.Rollup{{
+		BucketStart: time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC),
+		Interval:    "1m",
+		Dimensions:  map[string]string{"directive": "script-src", "blocked_host": "evil.com"},
+		Count:       42,
+	}})
+	if err != nil {
+		t.Fatalf("StoreRollups failed: %v", err)
+	}
+
+	last := len(stub.queries) - 1
+	if stub.queries[last] != "INSERT INTO `csp`.`reports_rollups` FORMAT JSONEachRow" {
+		t.Errorf("Unexpected insert query %q", stub.queries[last])
+	}
+
+	var row map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stub.bodies[last])), &row); err != nil {
+		t.Fatalf("Invalid rollup row: %v", err)
+	}
+	if row["count"] != float64(42) || row["directive"] != "script-src" || row["document_host"] != "" {
+		t.Errorf("Unexpected rollup row %v", row)
+	}
+}
+
+func TestClickHouseStorage_StoreBatchError(t *testing.T) {
+	store, _ := newClickHouseTestStorage(t, "broken")
+
+	reports, _ := models.ParseCSPReports([]byte(`{"csp-report": {"document-uri": "https://example.com/"}}`), "", "")
+	err := store.StoreBatch(reports)
+	if err == nil || !strings.Contains(err.Error(), "Table does not exist") {
+		t.Errorf("Expected ClickHouse error to be surfaced, got %v", err)
+	}
+}
@@ -5,6 +5,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -26,10 +29,41 @@ type ElasticsearchStorage struct {
 }
 
 func NewElasticsearchClient(cfg config.ElasticsearchConfig) (Storage, error) {
-	esCfg := elasticsearch.Config{
-		Addresses: cfg.Addresses,
-		Username:  cfg.Username,
-		Password:  cfg.Password,
+	storage, err := connectElasticsearch(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.ILM.Enabled {
+		if err := storage.ensureILMPolicy(); err != nil {
+			return nil, fmt.Errorf("failed to ensure ILM policy: %w", err)
+		}
+	}
+
+	if err := storage.ensureIndexTemplate(); err != nil {
+		return nil, fmt.Errorf("failed to ensure index template: %w", err)
+	}
+
+	if err := storage.ensureRollupTemplate(); err != nil {
+		return nil, fmt.Errorf("failed to ensure rollup template: %w", err)
+	}
+
+	return storage, nil
+}
+
+// NewElasticsearchReader connects for reading stored reports only. Unlike
+// NewElasticsearchClient it installs no templates or lifecycle policies and
+// migrates no mappings, so one-off tools never write to the cluster.
+func NewElasticsearchReader(cfg config.ElasticsearchConfig) (Storage, error) {
+	return connectElasticsearch(cfg)
+}
+
+// connectElasticsearch creates the client and checks that the cluster is
+// reachable.
+func connectElasticsearch(cfg config.ElasticsearchConfig) (*ElasticsearchStorage, error) {
+	esCfg, err := newElasticsearchConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	client, err := elasticsearch.NewClient(esCfg)
@@ -50,16 +84,69 @@ func NewElasticsearchClient(cfg config.ElasticsearchConfig) (Storage, error) {
 		return nil, fmt.Errorf("elasticsearch connection error: %s", res.Status())
 	}
 
-	storage := &ElasticsearchStorage{
+	return &ElasticsearchStorage{
 		client: client,
 		config: cfg,
+	}, nil
+}
+
+// newElasticsearchConfig translates the service configuration into client
+// options, resolving credentials stored in files and building the TLS setup.
+func newElasticsearchConfig(cfg config.ElasticsearchConfig) (elasticsearch.Config, error) {
+	password, err := readSecret(cfg.Password, cfg.PasswordFile)
+	if err != nil {
+		return elasticsearch.Config{}, fmt.Errorf("failed to read Elasticsearch password: %w", err)
 	}
 
-	if err := storage.ensureIndexTemplate(); err != nil {
-		return nil, fmt.Errorf("failed to ensure index template: %w", err)
+	apiKey, err := readSecret(cfg.APIKey, cfg.APIKeyFile)
+	if err != nil {
+		return elasticsearch.Config{}, fmt.Errorf("failed to read Elasticsearch API key: %w", err)
 	}
 
-	return storage, nil
+	serviceToken, err := readSecret(cfg.ServiceToken, cfg.ServiceTokenFile)
+	if err != nil {
+		return elasticsearch.Config{}, fmt.Errorf("failed to read Elasticsearch service token: %w", err)
+	}
+
+	esCfg := elasticsearch.Config{
+		Addresses:    cfg.Addresses,
+		Username:     cfg.Username,
+		Password:     password,
+		APIKey:       apiKey,
+		ServiceToken: serviceToken,
+	}
+
+	// The client rejects Cloud ID combined with explicit addresses
+	if cfg.CloudID != "" {
+		esCfg.Addresses = nil
+		esCfg.CloudID = cfg.CloudID
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.CAFile, cfg.CertFile, cfg.KeyFile, cfg.InsecureSkipVerify)
+	if err != nil {
+		return elasticsearch.Config{}, fmt.Errorf("failed to configure Elasticsearch TLS: %w", err)
+	}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		esCfg.Transport = transport
+	}
+
+	return esCfg, nil
+}
+
+// readSecret returns the contents of file when set, otherwise value. This
+// lets credentials be mounted from Kubernetes secrets instead of env vars.
+func readSecret(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+
+	secret, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
 }
 
 func (es *ElasticsearchStorage) StoreBatch(reports []*models.CSPReport) error {
@@ -69,11 +156,17 @@ func (es *ElasticsearchStorage) StoreBatch(reports []*models.CSPReport) error {
 
 	var buf bytes.Buffer
 
+	// Data streams are append-only and only accept the create action
+	action := "index"
+	if es.config.DataStream {
+		action = "create"
+	}
+
 	for _, report := range reports {
-		indexName := es.getIndexName(report.Timestamp)
+		indexName := es.getIndexName(report.Project, report.Timestamp)
 
 		meta := map[string]interface{}{
-			"index": map[string]interface{}{
+			action: map[string]interface{}{
 				"_index": indexName,
 				"_id":    report.ID,
 			},
@@ -84,7 +177,7 @@ func (es *ElasticsearchStorage) StoreBatch(reports []*models.CSPReport) error {
 			return fmt.Errorf("failed to marshal metadata: %w", err)
 		}
 
-		docBytes, err := json.Marshal(report)
+		docBytes, err := json.Marshal(es.newDocument(report))
 		if err != nil {
 			return fmt.Errorf("failed to marshal document: %w", err)
 		}
@@ -95,6 +188,45 @@ func (es *ElasticsearchStorage) StoreBatch(reports []*models.CSPReport) error {
 		buf.WriteByte('\n')
 	}
 
+	return es.bulk(&buf)
+}
+
+// StoreRollups writes aggregates into monthly rollup indices.
+func (es *ElasticsearchStorage) StoreRollups(rollups []*models.Rollup) error {
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+
+	for _, rollup := range rollups {
+		meta := map[string]interface{}{
+			"index": map[string]interface{}{
+				"_index": es.rollupIndexName(rollup.BucketStart),
+				"_id":    rollup.ID,
+			},
+		}
+
+		metaBytes, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("failed to marshal metadata: %w", err)
+		}
+
+		docBytes, err := json.Marshal(rollup)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rollup: %w", err)
+		}
+
+		buf.Write(metaBytes)
+		buf.WriteByte('\n')
+		buf.Write(docBytes)
+		buf.WriteByte('\n')
+	}
+
+	return es.bulk(&buf)
+}
+
+func (es *ElasticsearchStorage) bulk(buf *bytes.Buffer) error {
 	ctx, cancel := context.WithTimeout(context.Background(), esBulkTimeout)
 	defer cancel()
 
@@ -133,97 +265,289 @@ func (es *ElasticsearchStorage) Close() error {
 	return nil
 }
 
-func (es *ElasticsearchStorage) getIndexName(timestamp time.Time) string {
-	return fmt.Sprintf("%s-%s", es.config.IndexPrefix, timestamp.Format("2006.01.02"))
+// esDocument is the stored form of a report. It adds the derived fields used
+// to filter and aggregate and, for data streams, the required @timestamp
+// field.
+type esDocument struct {
+	*models.CSPReport
+	Timestamp          *time.Time `json:"@timestamp,omitempty"`
+	Directive          string     `json:"directive,omitempty"`
+	GoverningDirective string     `json:"governing_directive,omitempty"`
+	DocumentHost       string     `json:"document_host,omitempty"`
+	BlockedHost        string     `json:"blocked_host,omitempty"`
+	BlockedOrigin      string     `json:"blocked_origin,omitempty"`
+	BlockedScheme      string     `json:"blocked_scheme,omitempty"`
+	BlockedDomain      string     `json:"blocked_domain,omitempty"`
+	BlockedPort        int        `json:"blocked_port,omitempty"`
+	BlockedPath        string     `json:"blocked_path,omitempty"`
+	BlockedCategories  []string   `json:"blocked_categories,omitempty"`
+	Disposition        string     `json:"disposition,omitempty"`
+}
+
+func (es *ElasticsearchStorage) newDocument(report *models.CSPReport) esDocument {
+	doc := esDocument{CSPReport: report}
+	if es.config.DataStream {
+		timestamp := report.Timestamp
+		doc.Timestamp = &timestamp
+	}
+	if parsed := report.ParsedReport; parsed != nil {
+		doc.Directive = parsed.CanonicalDirective()
+		doc.GoverningDirective = parsed.GoverningDirective()
+		doc.DocumentHost = models.URLHost(parsed.DocumentURI)
+		doc.BlockedHost = models.URLHost(parsed.BlockedURI)
+		doc.BlockedOrigin = models.BlockedOrigin(parsed.BlockedURI)
+		blocked := parsed.BlockedResource()
+		doc.BlockedScheme = blocked.Scheme
+		doc.BlockedDomain = blocked.Domain
+		doc.BlockedPort = blocked.Port
+		doc.BlockedPath = blocked.Path
+		doc.BlockedCategories = blocked.Categories
+		doc.Disposition = parsed.DispositionName()
+	}
+	return doc
+}
+
+// getIndexName routes project reports to their own indices or data stream
+// below the index prefix, so templates and lifecycle policies apply to them.
+func (es *ElasticsearchStorage) getIndexName(project string, timestamp time.Time) string {
+	name := es.config.IndexPrefix
+	if project != "" {
+		name += "-" + project
+	}
+
+	if es.config.DataStream {
+		return name
+	}
+	return fmt.Sprintf("%s-%s", name, timestamp.Format("2006.01.02"))
+}
+
+func (es *ElasticsearchStorage) rollupIndexName(bucketStart time.Time) string {
+	return fmt.Sprintf("%s-rollups-%s", es.config.IndexPrefix, bucketStart.Format("2006.01"))
+}
+
+func (es *ElasticsearchStorage) rollupIndexPattern() string {
+	return es.config.IndexPrefix + "-rollups-*"
+}
+
+func (es *ElasticsearchStorage) ilmPolicyName() string {
+	if es.config.ILM.PolicyName != "" {
+		return es.config.ILM.PolicyName
+	}
+	return es.config.IndexPrefix + "-policy"
+}
+
+// ensureILMPolicy creates or updates the lifecycle policy. Rollover is only
+// configured for data streams since daily indices are never rolled over.
+func (es *ElasticsearchStorage) ensureILMPolicy() error {
+	ilm := es.config.ILM
+	phases := map[string]interface{}{}
+
+	hotActions := map[string]interface{}{}
+	if es.config.DataStream {
+		rollover := map[string]interface{}{}
+		if ilm.RolloverMaxSize != "" {
+			rollover["max_primary_shard_size"] = ilm.RolloverMaxSize
+		}
+		if ilm.RolloverMaxAge != "" {
+			rollover["max_age"] = ilm.RolloverMaxAge
+		}
+		if len(rollover) > 0 {
+			hotActions["rollover"] = rollover
+		}
+	}
+	phases["hot"] = map[string]interface{}{
+		"min_age": "0ms",
+		"actions": hotActions,
+	}
+
+	if ilmPhaseEnabled(ilm.WarmAfter) {
+		phases["warm"] = map[string]interface{}{
+			"min_age": ilm.WarmAfter,
+			"actions": map[string]interface{}{
+				"set_priority": map[string]interface{}{"priority": 50},
+				"forcemerge":   map[string]interface{}{"max_num_segments": 1},
+			},
+		}
+	}
+
+	if ilmPhaseEnabled(ilm.DeleteAfter) {
+		phases["delete"] = map[string]interface{}{
+			"min_age": ilm.DeleteAfter,
+			"actions": map[string]interface{}{
+				"delete": map[string]interface{}{},
+			},
+		}
+	}
+
+	policyBytes, err := json.Marshal(map[string]interface{}{
+		"policy": map[string]interface{}{"phases": phases},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal ILM policy: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), esConnectionTimeout)
+	defer cancel()
+
+	req := esapi.ILMPutLifecycleRequest{
+		Policy: es.ilmPolicyName(),
+		Body:   bytes.NewReader(policyBytes),
+	}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("failed to put ILM policy: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("ILM policy creation error: %s", res.Status())
+	}
+
+	return nil
+}
+
+// ilmPhaseEnabled reports whether a phase age is set; "none" disables a phase
+// since empty environment variables fall back to the defaults.
+func ilmPhaseEnabled(minAge string) bool {
+	return minAge != "" && !strings.EqualFold(minAge, "none")
+}
+
+func (es *ElasticsearchStorage) settingsTemplateName() string {
+	return es.config.IndexPrefix + "-settings"
+}
+
+func (es *ElasticsearchStorage) mappingsTemplateName() string {
+	return es.config.IndexPrefix + "-mappings"
 }
 
+// indexPatterns matches the report indices or data streams of all projects.
+func (es *ElasticsearchStorage) indexPatterns() []string {
+	if es.config.DataStream {
+		return []string{es.config.IndexPrefix, es.config.IndexPrefix + "-*"}
+	}
+	return []string{es.config.IndexPrefix + "-*"}
+}
+
+// ensureIndexTemplate installs the component and index templates. It refuses
+// to replace a template written by a newer version of the service, and when
+// upgrading from an older version it migrates the mappings of existing indices
+// so new fields are searchable without waiting for the next index.
 func (es *ElasticsearchStorage) ensureIndexTemplate() error {
 	templateName := es.config.IndexPrefix + "-template"
 
+	installed, err := es.installedTemplateVersion(templateName)
+	if err != nil {
+		return err
+	}
+	if installed > esTemplateVersion {
+		return fmt.Errorf("index template %s has version %d, newer than supported version %d; refusing to downgrade",
+			templateName, installed, esTemplateVersion)
+	}
+
+	settings := map[string]interface{}{
+		"number_of_shards":   es.config.Shards,
+		"number_of_replicas": es.config.Replicas,
+		"refresh_interval":   es.config.RefreshInterval,
+	}
+	if es.config.ILM.Enabled {
+		settings["index.lifecycle.name"] = es.ilmPolicyName()
+	}
+
+	meta := map[string]interface{}{
+		"version":    esTemplateVersion,
+		"managed_by": "universal-csp-report",
+	}
+
+	if err := es.putComponentTemplate(es.settingsTemplateName(), map[string]interface{}{
+		"template": map[string]interface{}{"settings": settings},
+		"_meta":    meta,
+	}); err != nil {
+		return err
+	}
+
+	if err := es.putComponentTemplate(es.mappingsTemplateName(), map[string]interface{}{
+		"template": map[string]interface{}{"mappings": reportMappings()},
+		"_meta":    meta,
+	}); err != nil {
+		return err
+	}
+
 	template := map[string]interface{}{
-		"index_patterns": []string{es.config.IndexPrefix + "-*"},
+		"index_patterns": es.indexPatterns(),
+		"composed_of":    []string{es.settingsTemplateName(), es.mappingsTemplateName()},
+		"version":        esTemplateVersion,
+		"_meta":          meta,
+	}
+
+	if es.config.DataStream {
+		template["data_stream"] = map[string]interface{}{}
+		template["priority"] = 200
+	}
+
+	templateBytes, err := json.Marshal(template)
+	if err != nil {
+		return fmt.Errorf("failed to marshal template: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), esConnectionTimeout)
+	defer cancel()
+
+	req := esapi.IndicesPutIndexTemplateRequest{
+		Name: templateName,
+		Body: bytes.NewReader(templateBytes),
+	}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("failed to create index template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("index template creation error: %s", res.Status())
+	}
+
+	if installed > 0 && installed < esTemplateVersion {
+		if err := es.migrateMappings(); err != nil {
+			return fmt.Errorf("failed to migrate mappings from version %d: %w", installed, err)
+		}
+	}
+
+	return nil
+}
+
+// ensureRollupTemplate installs the template for rollup indices. Its priority
+// is above the report template, whose daily pattern also matches them. When
+// upgrading, dimensions added since are mapped on existing rollup indices.
+func (es *ElasticsearchStorage) ensureRollupTemplate() error {
+	templateName := es.config.IndexPrefix + "-rollups-template"
+
+	installed, err := es.installedTemplateVersion(templateName)
+	if err != nil {
+		return err
+	}
+
+	template := map[string]interface{}{
+		"index_patterns": []string{es.rollupIndexPattern()},
+		"priority":       300,
+		"version":        esTemplateVersion,
+		"_meta": map[string]interface{}{
+			"version":    esTemplateVersion,
+			"managed_by": "universal-csp-report",
+		},
 		"template": map[string]interface{}{
 			"settings": map[string]interface{}{
 				"number_of_shards":   1,
-				"number_of_replicas": 0,
-				"refresh_interval":   "30s",
-			},
-			"mappings": map[string]interface{}{
-				"properties": map[string]interface{}{
-					"id": map[string]interface{}{
-						"type": "keyword",
-					},
-					"timestamp": map[string]interface{}{
-						"type": "date",
-					},
-					"user_agent": map[string]interface{}{
-						"type": "text",
-						"fields": map[string]interface{}{
-							"keyword": map[string]interface{}{
-								"type": "keyword",
-							},
-						},
-					},
-					"remote_addr": map[string]interface{}{
-						"type": "ip",
-					},
-					"browser_type": map[string]interface{}{
-						"type": "keyword",
-					},
-					"parsed_report": map[string]interface{}{
-						"properties": map[string]interface{}{
-							"document_uri": map[string]interface{}{
-								"type": "keyword",
-							},
-							"referrer": map[string]interface{}{
-								"type": "keyword",
-							},
-							"violated_directive": map[string]interface{}{
-								"type": "keyword",
-							},
-							"original_policy": map[string]interface{}{
-								"type": "text",
-							},
-							"blocked_uri": map[string]interface{}{
-								"type": "keyword",
-							},
-							"status_code": map[string]interface{}{
-								"type": "integer",
-							},
-							"script_sample": map[string]interface{}{
-								"type": "text",
-							},
-							"line_number": map[string]interface{}{
-								"type": "integer",
-							},
-							"column_number": map[string]interface{}{
-								"type": "integer",
-							},
-							"source_file": map[string]interface{}{
-								"type": "keyword",
-							},
-							"disposition": map[string]interface{}{
-								"type": "keyword",
-							},
-							"effective_directive": map[string]interface{}{
-								"type": "keyword",
-							},
-						},
-					},
-					"human_readable": map[string]interface{}{
-						"type": "text",
-					},
-					"processing_errors": map[string]interface{}{
-						"type": "keyword",
-					},
-				},
+				"number_of_replicas": es.config.Replicas,
 			},
+			"mappings": rollupMappings(),
 		},
 	}
 
 	templateBytes, err := json.Marshal(template)
 	if err != nil {
-		return fmt.Errorf("failed to marshal template: %w", err)
+		return fmt.Errorf("failed to marshal rollup template: %w", err)
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), esConnectionTimeout)
@@ -236,13 +560,220 @@ func (es *ElasticsearchStorage) ensureIndexTemplate() error {
 
 	res, err := req.Do(ctx, es.client)
 	if err != nil {
-		return fmt.Errorf("failed to create index template: %w", err)
+		return fmt.Errorf("failed to create rollup template: %w", err)
 	}
 	defer res.Body.Close()
 
 	if res.IsError() {
-		return fmt.Errorf("index template creation error: %s", res.Status())
+		return fmt.Errorf("rollup template creation error: %s", res.Status())
+	}
+
+	if installed > 0 && installed < esTemplateVersion {
+		// Dimensions are keywords only ever added, so mapping all of them
+		// again is safe on every rollup index
+		mappings := rollupMappings()["properties"].(map[string]interface{})
+		if err := es.putMapping(es.rollupIndexPattern(), map[string]interface{}{
+			"properties": map[string]interface{}{"dimensions": mappings["dimensions"]},
+		}); err != nil {
+			return fmt.Errorf("failed to migrate rollup mappings from version %d: %w", installed, err)
+		}
 	}
 
 	return nil
 }
+
+// installedTemplateVersion returns the _meta.version of the existing index
+// template, or 0 when there is none. Templates installed before versioning
+// have no _meta and count as version 1.
+func (es *ElasticsearchStorage) installedTemplateVersion(name string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), esConnectionTimeout)
+	defer cancel()
+
+	req := esapi.IndicesGetIndexTemplateRequest{Name: name}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get index template: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return 0, nil
+	}
+	if res.IsError() {
+		return 0, fmt.Errorf("index template lookup error: %s", res.Status())
+	}
+
+	var response struct {
+		IndexTemplates []struct {
+			IndexTemplate struct {
+				Meta struct {
+					Version *int `json:"version"`
+				} `json:"_meta"`
+			} `json:"index_template"`
+		} `json:"index_templates"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return 0, fmt.Errorf("failed to decode index template: %w", err)
+	}
+
+	if len(response.IndexTemplates) == 0 {
+		return 0, nil
+	}
+	if version := response.IndexTemplates[0].IndexTemplate.Meta.Version; version != nil {
+		return *version, nil
+	}
+	return 1, nil
+}
+
+func (es *ElasticsearchStorage) putComponentTemplate(name string, body map[string]interface{}) error {
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal component template: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), esConnectionTimeout)
+	defer cancel()
+
+	req := esapi.ClusterPutComponentTemplateRequest{
+		Name: name,
+		Body: bytes.NewReader(bodyBytes),
+	}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("failed to create component template %s: %w", name, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("component template %s creation error: %s", name, res.Status())
+	}
+
+	return nil
+}
+
+// migrateMappings adds new fields to indices created from an older template.
+// Fields an index already maps are left out: mapping updates cannot change
+// them, and indices from before versioning mapped fields such as raw_report
+// dynamically, which would conflict with the explicit mapping.
+func (es *ElasticsearchStorage) migrateMappings() error {
+	existing, err := es.indexMappings()
+	if err != nil {
+		return err
+	}
+
+	indices := make([]string, 0, len(existing))
+	for index := range existing {
+		indices = append(indices, index)
+	}
+	sort.Strings(indices)
+
+	mappings := reportMappings()
+	for _, index := range indices {
+		properties := missingMappings(mappings["properties"].(map[string]interface{}), existing[index])
+		if len(properties) == 0 {
+			continue
+		}
+		if err := es.putMapping(index, map[string]interface{}{
+			"dynamic":    mappings["dynamic"],
+			"properties": properties,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// indexMappings returns the mapped properties of every report index.
+func (es *ElasticsearchStorage) indexMappings() (map[string]map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), esConnectionTimeout)
+	defer cancel()
+
+	// Rollup indices share the prefix but have their own mappings
+	req := esapi.IndicesGetMappingRequest{
+		Index: append(es.indexPatterns(), "-"+es.rollupIndexPattern()),
+	}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return nil, fmt.Errorf("get mapping request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	// No existing indices or data stream yet means nothing to migrate
+	if res.StatusCode == 404 {
+		return nil, nil
+	}
+	if res.IsError() {
+		return nil, fmt.Errorf("get mapping error: %s", res.Status())
+	}
+
+	var response map[string]struct {
+		Mappings struct {
+			Properties map[string]interface{} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode mappings: %w", err)
+	}
+
+	mappings := make(map[string]map[string]interface{}, len(response))
+	for index, mapping := range response {
+		mappings[index] = mapping.Mappings.Properties
+	}
+	return mappings, nil
+}
+
+func (es *ElasticsearchStorage) putMapping(index string, mapping map[string]interface{}) error {
+	mappingBytes, err := json.Marshal(mapping)
+	if err != nil {
+		return fmt.Errorf("failed to marshal mappings: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), esConnectionTimeout)
+	defer cancel()
+
+	req := esapi.IndicesPutMappingRequest{
+		Index:          []string{index},
+		Body:           bytes.NewReader(mappingBytes),
+		AllowNoIndices: esapi.BoolPtr(true),
+	}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("put mapping request for %s failed: %w", index, err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("put mapping error for %s: %s", index, res.Status())
+	}
+
+	return nil
+}
+
+// missingMappings returns the properties of want that have is missing,
+// descending into object fields both of them map.
+func missingMappings(want, have map[string]interface{}) map[string]interface{} {
+	missing := make(map[string]interface{})
+	for field, mapping := range want {
+		existing, ok := have[field].(map[string]interface{})
+		if !ok {
+			missing[field] = mapping
+			continue
+		}
+
+		wantProperties, _ := mapping.(map[string]interface{})["properties"].(map[string]interface{})
+		haveProperties, _ := existing["properties"].(map[string]interface{})
+		if wantProperties == nil || haveProperties == nil {
+			continue
+		}
+		if nested := missingMappings(wantProperties, haveProperties); len(nested) > 0 {
+			missing[field] = map[string]interface{}{"properties": nested}
+		}
+	}
+	return missing
+}
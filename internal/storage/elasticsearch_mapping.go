@@ -0,0 +1,214 @@
+package storage
+
+import "universal-csp-report/internal/models"
+
+// esTemplateVersion is stored in the templates' _meta.version. Bump it
+// whenever reportMappings or rollupMappings changes so running clusters get
+// migrated on the next startup.
+const esTemplateVersion = 16
+
+// reportMappings returns the explicit mapping for stored CSPReport documents.
+// Dynamic mapping is disabled so unmapped fields are kept in _source without
+// producing inconsistent field types across indices.
+func reportMappings() map[string]interface{} {
+	return map[string]interface{}{
+		"dynamic": false,
+		"properties": map[string]interface{}{
+			"@timestamp": map[string]interface{}{
+				"type": "date",
+			},
+			"id": map[string]interface{}{
+				"type": "keyword",
+			},
+			"project": map[string]interface{}{
+				"type": "keyword",
+			},
+			"timestamp": map[string]interface{}{
+				"type": "date",
+			},
+			"user_agent": map[string]interface{}{
+				"type": "text",
+				"fields": map[string]interface{}{
+					"keyword": map[string]interface{}{
+						"type": "keyword",
+					},
+				},
+			},
+			"remote_addr": map[string]interface{}{
+				"type": "ip",
+			},
+			"browser_type": map[string]interface{}{
+				"type": "keyword",
+			},
+			"directive": map[string]interface{}{
+				"type": "keyword",
+			},
+			"governing_directive": map[string]interface{}{
+				"type": "keyword",
+			},
+			"document_host": map[string]interface{}{
+				"type": "keyword",
+			},
+			"blocked_host": map[string]interface{}{
+				"type": "keyword",
+			},
+			"blocked_origin": map[string]interface{}{
+				"type": "keyword",
+			},
+			"blocked_scheme": map[string]interface{}{
+				"type": "keyword",
+			},
+			"blocked_domain": map[string]interface{}{
+				"type": "keyword",
+			},
+			"blocked_port": map[string]interface{}{
+				"type": "integer",
+			},
+			"blocked_path": map[string]interface{}{
+				"type":         "keyword",
+				"ignore_above": 1024,
+			},
+			"blocked_categories": map[string]interface{}{
+				"type": "keyword",
+			},
+			"disposition": map[string]interface{}{
+				"type": "keyword",
+			},
+			"parsed_report": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"document_uri": map[string]interface{}{
+						"type": "keyword",
+					},
+					"referrer": map[string]interface{}{
+						"type": "keyword",
+					},
+					"violated_directive": map[string]interface{}{
+						"type": "keyword",
+					},
+					"original_policy": map[string]interface{}{
+						"type": "text",
+					},
+					"blocked_uri": map[string]interface{}{
+						"type": "keyword",
+					},
+					"status_code": map[string]interface{}{
+						"type": "integer",
+					},
+					"script_sample": map[string]interface{}{
+						"type": "text",
+					},
+					"line_number": map[string]interface{}{
+						"type": "integer",
+					},
+					"column_number": map[string]interface{}{
+						"type": "integer",
+					},
+					"source_file": map[string]interface{}{
+						"type": "keyword",
+					},
+					"disposition": map[string]interface{}{
+						"type": "keyword",
+					},
+					"effective_directive": map[string]interface{}{
+						"type": "keyword",
+					},
+					"sha256": map[string]interface{}{
+						"type": "keyword",
+					},
+					"errors": map[string]interface{}{
+						"type": "keyword",
+					},
+				},
+			},
+			"raw_report": map[string]interface{}{
+				"type":    "object",
+				"enabled": false,
+			},
+			"human_readable": map[string]interface{}{
+				"type": "text",
+			},
+			"fingerprint": map[string]interface{}{
+				"type": "keyword",
+			},
+			"document_route": map[string]interface{}{
+				"type":         "keyword",
+				"ignore_above": 1024,
+			},
+			"redactions": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"field": map[string]interface{}{
+						"type": "keyword",
+					},
+					"rule": map[string]interface{}{
+						"type": "keyword",
+					},
+					"count": map[string]interface{}{
+						"type": "integer",
+					},
+				},
+			},
+			"policy_fingerprint": map[string]interface{}{
+				"type": "keyword",
+			},
+			"policy_name": map[string]interface{}{
+				"type": "keyword",
+			},
+			"policy_version": map[string]interface{}{
+				"type": "integer",
+			},
+			"occurrences": map[string]interface{}{
+				"type": "integer",
+			},
+			"first_occurrence": map[string]interface{}{
+				"type": "date",
+			},
+			"last_occurrence": map[string]interface{}{
+				"type": "date",
+			},
+			"sample_rate": map[string]interface{}{
+				"type": "float",
+			},
+			"tags": map[string]interface{}{
+				"type": "keyword",
+			},
+			"downgraded": map[string]interface{}{
+				"type": "boolean",
+			},
+			"processing_errors": map[string]interface{}{
+				"type": "keyword",
+			},
+		},
+	}
+}
+
+// rollupMappings returns the mapping for rollup documents. Every supported
+// dimension is a keyword so counts can be summed per dimension value.
+func rollupMappings() map[string]interface{} {
+	dimensions := make(map[string]interface{}, len(models.RollupDimensions))
+	for _, dimension := range models.RollupDimensions {
+		dimensions[dimension] = map[string]interface{}{
+			"type": "keyword",
+		}
+	}
+
+	return map[string]interface{}{
+		"dynamic": false,
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type": "keyword",
+			},
+			"bucket_start": map[string]interface{}{
+				"type": "date",
+			},
+			"interval": map[string]interface{}{
+				"type": "keyword",
+			},
+			"dimensions": map[string]interface{}{
+				"properties": dimensions,
+			},
+			"count": map[string]interface{}{
+				"type": "long",
+			},
+		},
+	}
+}
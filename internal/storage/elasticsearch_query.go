@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"universal-csp-report/internal/models"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+const (
+	esDefaultQuerySize = 50
+	esMaxQuerySize     = 500
+)
+
+// QueryReports searches report indices newest first, paging with
+// search_after on (timestamp, id).
+func (es *ElasticsearchStorage) QueryReports(ctx context.Context, query ReportQuery) (*ReportPage, error) {
+	size := query.Size
+	if size <= 0 {
+		size = esDefaultQuerySize
+	}
+	if size > esMaxQuerySize {
+		size = esMaxQuerySize
+	}
+
+	search := map[string]interface{}{
+		"size":  size,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": reportFilters(query)}},
+		"sort": []interface{}{
+			map[string]interface{}{"timestamp": "desc"},
+			map[string]interface{}{"id": "desc"},
+		},
+	}
+
+	if query.Cursor != "" {
+		searchAfter, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		search["search_after"] = searchAfter
+	}
+
+	var result struct {
+		Hits struct {
+			Hits []struct {
+				Source models.CSPReport `json:"_source"`
+				Sort   []interface{}    `json:"sort"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := es.search(ctx, search, &result); err != nil {
+		return nil, err
+	}
+
+	page := &ReportPage{Reports: make([]*models.CSPReport, 0, len(result.Hits.Hits))}
+	for i := range result.Hits.Hits {
+		page.Reports = append(page.Reports, &result.Hits.Hits[i].Source)
+	}
+
+	if hits := result.Hits.Hits; len(hits) == size {
+		next, err := encodeCursor(hits[len(hits)-1].Sort)
+		if err != nil {
+			return nil, err
+		}
+		page.Next = next
+	}
+
+	return page, nil
+}
+
+// search runs a search over all report indices and decodes the response
+// into result.
+func (es *ElasticsearchStorage) search(ctx context.Context, search map[string]interface{}, result interface{}) error {
+	body, err := json.Marshal(search)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+
+	req := esapi.SearchRequest{
+		Index:             append(es.indexPatterns(), "-"+es.rollupIndexPattern()),
+		Body:              bytes.NewReader(body),
+		AllowNoIndices:    esapi.BoolPtr(true),
+		IgnoreUnavailable: esapi.BoolPtr(true),
+	}
+
+	res, err := req.Do(ctx, es.client)
+	if err != nil {
+		return fmt.Errorf("search request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.IsError() {
+		return fmt.Errorf("search error: %s", res.Status())
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return nil
+}
+
+func reportFilters(query ReportQuery) []interface{} {
+	filters := []interface{}{}
+
+	if !query.From.IsZero() || !query.To.IsZero() {
+		timeRange := map[string]interface{}{}
+		if !query.From.IsZero() {
+			timeRange["gte"] = query.From.UTC().Format(time.RFC3339Nano)
+		}
+		if !query.To.IsZero() {
+			timeRange["lt"] = query.To.UTC().Format(time.RFC3339Nano)
+		}
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{"timestamp": timeRange},
+		})
+	}
+
+	terms := []struct{ field, value string }{
+		{"project", query.Project},
+		{"document_host", strings.ToLower(query.DocumentHost)},
+		{"document_route", query.DocumentRoute},
+		{"browser_type", query.Browser},
+		{"fingerprint", query.Fingerprint},
+		{"disposition", query.Disposition},
+	}
+	for _, term := range terms {
+		if term.value != "" {
+			filters = append(filters, map[string]interface{}{
+				"term": map[string]interface{}{term.field: term.value},
+			})
+		}
+	}
+
+	if query.BlockedURI != "" {
+		filters = append(filters, map[string]interface{}{
+			"prefix": map[string]interface{}{"parsed_report.blocked_uri": query.BlockedURI},
+		})
+	}
+
+	// The top-level directive holds the directive family, so script-src also
+	// matches script-src-elem and script-src-attr. Reports stored before
+	// template version 12 lack it and are matched on the raw fields, where
+	// the violated directive may carry the source list, e.g. "script-src 'self'"
+	if directive := strings.ToLower(query.Directive); directive != "" {
+		filters = append(filters, map[string]interface{}{
+			"bool": map[string]interface{}{
+				"should": []interface{}{
+					map[string]interface{}{"term": map[string]interface{}{"directive": models.CanonicalDirective(directive)}},
+					map[string]interface{}{"term": map[string]interface{}{"parsed_report.effective_directive": directive}},
+					map[string]interface{}{"term": map[string]interface{}{"parsed_report.violated_directive": directive}},
+					map[string]interface{}{"prefix": map[string]interface{}{"parsed_report.violated_directive": directive + " "}},
+				},
+				"minimum_should_match": 1,
+			},
+		})
+	}
+
+	return filters
+}
+
+func encodeCursor(sort []interface{}) (string, error) {
+	data, err := json.Marshal(sort)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(cursor string) ([]interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var sort []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&sort); err != nil || len(sort) != 2 {
+		return nil, ErrInvalidCursor
+	}
+	return sort, nil
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+const (
+	esDefaultSummarySize = 10
+	esMaxSummarySize     = 100
+)
+
+// esReportWeightScript is the number of reports a stored document stands for:
+// its occurrences when deduplicated, scaled up by its sample rate. Documents
+// from before either feature count once.
+const esReportWeightScript = `double occurrences = doc['occurrences'].size() == 0 ? 1 : doc['occurrences'].value;
+double rate = doc['sample_rate'].size() == 0 || doc['sample_rate'].value <= 0 ? 1 : doc['sample_rate'].value;
+return occurrences / rate;`
+
+// summaryDimensions maps summary dimension names to the aggregated fields.
+var summaryDimensions = []struct {
+	name  string
+	field string
+}{
+	{"blocked_origins", "blocked_origin"},
+	{"blocked_domains", "blocked_domain"},
+	{"blocked_categories", "blocked_categories"},
+	{"directives", "directive"},
+	{"pages", "document_route"},
+	{"browsers", "browser_type"},
+	{"dispositions", "disposition"},
+}
+
+// esAggregation is the result of a terms aggregation, whose buckets carry a
+// weight sub-aggregation, or of a metric aggregation in Value.
+type esAggregation struct {
+	Value   float64 `json:"value"`
+	Buckets []struct {
+		Key    string `json:"key"`
+		Weight struct {
+			Value float64 `json:"value"`
+		} `json:"weight"`
+	} `json:"buckets"`
+}
+
+type esSummaryResponse struct {
+	Aggregations map[string]esAggregation `json:"aggregations"`
+}
+
+// esWeightSum sums the report weight of the documents in a bucket.
+func esWeightSum() map[string]interface{} {
+	return map[string]interface{}{
+		"sum": map[string]interface{}{
+			"script": map[string]interface{}{"source": esReportWeightScript},
+		},
+	}
+}
+
+// SummarizeReports returns the top values per dimension using terms
+// aggregations. With a baseline window it also looks up which top values are
+// missing from the other window, so new and disappearing violations are
+// exact rather than an artifact of the top-N cut-off.
+func (es *ElasticsearchStorage) SummarizeReports(ctx context.Context, query SummaryQuery) (*Summary, error) {
+	size := query.Size
+	if size <= 0 {
+		size = esDefaultSummarySize
+	}
+	if size > esMaxSummarySize {
+		size = esMaxSummarySize
+	}
+
+	window := ReportQuery{From: query.From, To: query.To, Project: query.Project}
+	summary, err := es.summarizeWindow(ctx, window, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if query.CompareFrom.IsZero() && query.CompareTo.IsZero() {
+		return summary, nil
+	}
+
+	baselineWindow := ReportQuery{From: query.CompareFrom, To: query.CompareTo, Project: query.Project}
+	baseline, err := es.summarizeWindow(ctx, baselineWindow, size)
+	if err != nil {
+		return nil, err
+	}
+
+	newKeys, err := es.missingKeys(ctx, baselineWindow, summary.Top)
+	if err != nil {
+		return nil, err
+	}
+	goneKeys, err := es.missingKeys(ctx, window, baseline.Top)
+	if err != nil {
+		return nil, err
+	}
+
+	summary.Comparison = &SummaryComparison{
+		Baseline: baseline,
+		New:      newKeys,
+		Gone:     goneKeys,
+	}
+	return summary, nil
+}
+
+// summarizeWindow counts reports rather than stored documents, weighting each
+// document by esReportWeightScript, and ranks the values of each dimension by
+// that weighted count.
+func (es *ElasticsearchStorage) summarizeWindow(ctx context.Context, window ReportQuery, size int) (*Summary, error) {
+	aggs := make(map[string]interface{}, len(summaryDimensions)+1)
+	aggs["total"] = esWeightSum()
+	for _, dimension := range summaryDimensions {
+		aggs[dimension.name] = map[string]interface{}{
+			"terms": map[string]interface{}{
+				"field": dimension.field,
+				"size":  size,
+				"order": map[string]interface{}{"weight": "desc"},
+			},
+			"aggs": map[string]interface{}{"weight": esWeightSum()},
+		}
+	}
+
+	search := map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": reportFilters(window)}},
+		"aggs":  aggs,
+	}
+
+	var result esSummaryResponse
+	if err := es.search(ctx, search, &result); err != nil {
+		return nil, fmt.Errorf("summary failed: %w", err)
+	}
+
+	summary := &Summary{
+		From:  window.From,
+		To:    window.To,
+		Total: int64(math.Round(result.Aggregations["total"].Value)),
+		Top:   make(map[string][]SummaryBucket, len(summaryDimensions)),
+	}
+	for _, dimension := range summaryDimensions {
+		buckets := []SummaryBucket{}
+		for _, bucket := range result.Aggregations[dimension.name].Buckets {
+			buckets = append(buckets, SummaryBucket{Key: bucket.Key, Count: int64(math.Round(bucket.Weight.Value))})
+		}
+		summary.Top[dimension.name] = buckets
+	}
+	return summary, nil
+}
+
+// missingKeys returns, per dimension, the buckets whose key does not occur at
+// all within window.
+func (es *ElasticsearchStorage) missingKeys(ctx context.Context, window ReportQuery, top map[string][]SummaryBucket) (map[string][]SummaryBucket, error) {
+	aggs := make(map[string]interface{}, len(summaryDimensions))
+	for _, dimension := range summaryDimensions {
+		keys := make([]string, 0, len(top[dimension.name]))
+		for _, bucket := range top[dimension.name] {
+			keys = append(keys, bucket.Key)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		aggs[dimension.name] = map[string]interface{}{
+			"terms": map[string]interface{}{"field": dimension.field, "size": len(keys), "include": keys},
+		}
+	}
+
+	missing := make(map[string][]SummaryBucket, len(summaryDimensions))
+	for _, dimension := range summaryDimensions {
+		missing[dimension.name] = []SummaryBucket{}
+	}
+	if len(aggs) == 0 {
+		return missing, nil
+	}
+
+	search := map[string]interface{}{
+		"size":  0,
+		"query": map[string]interface{}{"bool": map[string]interface{}{"filter": reportFilters(window)}},
+		"aggs":  aggs,
+	}
+
+	var result esSummaryResponse
+	if err := es.search(ctx, search, &result); err != nil {
+		return nil, fmt.Errorf("summary comparison failed: %w", err)
+	}
+
+	for _, dimension := range summaryDimensions {
+		present := make(map[string]bool)
+		for _, bucket := range result.Aggregations[dimension.name].Buckets {
+			present[bucket.Key] = true
+		}
+		for _, bucket := range top[dimension.name] {
+			if !present[bucket.Key] {
+				missing[dimension.name] = append(missing[dimension.name], bucket)
+			}
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,735 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+// elasticsearchStub records requests and answers them like a minimal
+// Elasticsearch 8 cluster.
+type elasticsearchStub struct {
+	mu     sync.Mutex
+	bodies map[string]string
+	auth   string
+
+	// installedVersion is reported as the existing template's _meta.version,
+	// 0 means no template is installed. legacyTemplate reports a template
+	// without _meta instead.
+	installedVersion int
+	legacyTemplate   bool
+
+	// mappings is returned for GET _mapping requests, no indices when empty.
+	mappings string
+
+	// searchResponses are returned for successive _search requests, falling
+	// back to searchResponse once used up. Request bodies go to searches.
+	searchResponse  string
+	searchResponses []string
+	searches        []string
+}
+
+func (s *elasticsearchStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	s.bodies[r.Method+" "+r.URL.Path] = string(body)
+	s.auth = r.Header.Get("Authorization")
+	s.mu.Unlock()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case r.URL.Path == "/":
+		w.Write([]byte(`{"version": {"number": "8.11.1"}}`))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/_index_template/") && s.legacyTemplate:
+		w.Write([]byte(`{"index_templates": [{"name": "csp-reports-template", "index_template": {"index_patterns": ["csp-reports-*"]}}]}`))
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/_index_template/"):
+		if s.installedVersion == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "index_template_missing_exception"}`))
+			return
+		}
+		fmt.Fprintf(w, `{"index_templates": [{"name": "csp-reports-template", "index_template": {"_meta": {"version": %d}}}]}`, s.installedVersion)
+	case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/_mapping"):
+		if s.mappings == "" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "index_not_found_exception"}`))
+			return
+		}
+		w.Write([]byte(s.mappings))
+	case r.URL.Path == "/_bulk":
+		w.Write([]byte(`{"errors": false, "items": []}`))
+	case strings.HasSuffix(r.URL.Path, "/_search"):
+		s.mu.Lock()
+		response := s.searchResponse
+		if len(s.searchResponses) > 0 {
+			response, s.searchResponses = s.searchResponses[0], s.searchResponses[1:]
+		}
+		s.searches = append(s.searches, string(body))
+		s.mu.Unlock()
+		w.Write([]byte(response))
+	default:
+		w.Write([]byte(`{"acknowledged": true}`))
+	}
+}
+
+func (s *elasticsearchStub) body(t *testing.T, key string) map[string]interface{} {
+	t.Helper()
+
+	s.mu.Lock()
+	raw, ok := s.bodies[key]
+	s.mu.Unlock()
+	if !ok {
+		t.Fatalf("Expected request %q, got %v", key, s.keys())
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		t.Fatalf("Invalid JSON body for %q: %v", key, err)
+	}
+	return decoded
+}
+
+// putMappings returns the number of indices whose mapping was updated.
+func (s *elasticsearchStub) putMappings() int {
+	count := 0
+	for _, key := range s.keys() {
+		if strings.HasPrefix(key, "PUT ") && strings.HasSuffix(key, "/_mapping") {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *elasticsearchStub) keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.bodies {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func newElasticsearchTestStorage(t *testing.T, cfg config.ElasticsearchConfig) (*ElasticsearchStorage, *elasticsearchStub) {
+	t.Helper()
+
+	stub := &elasticsearchStub{bodies: make(map[string]string)}
+	store, err := newElasticsearchTestStorageWithStub(t, stub, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create Elasticsearch storage: %v", err)
+	}
+	return store, stub
+}
+
+func newElasticsearchTestStorageWithStub(t *testing.T, stub *elasticsearchStub, cfg config.ElasticsearchConfig) (*ElasticsearchStorage, error) {
+	t.Helper()
+
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	cfg.Addresses = []string{srv.URL}
+	if cfg.IndexPrefix == "" {
+		cfg.IndexPrefix = "csp-reports"
+	}
+	if cfg.RefreshInterval == "" {
+		cfg.RefreshInterval = config.DefaultESRefreshInterval
+	}
+
+	store, err := NewElasticsearchClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return store.(*ElasticsearchStorage), nil
+}
+
+func TestNewElasticsearchReader_WritesNothing(t *testing.T) {
+	stub := &elasticsearchStub{bodies: make(map[string]string), installedVersion: 1}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	store, err := NewElasticsearchReader(config.ElasticsearchConfig{
+		Addresses:   []string{srv.URL},
+		IndexPrefix: "csp-reports",
+		ILM:         config.ILMConfig{Enabled: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create reader: %v", err)
+	}
+	defer store.Close()
+
+	for _, key := range stub.keys() {
+		if !strings.HasPrefix(key, "GET ") && !strings.HasPrefix(key, "HEAD ") {
+			t.Errorf("Expected the reader to send no writes, got %q", key)
+		}
+	}
+}
+
+func TestElasticsearchStorage_DailyIndices(t *testing.T) {
+	store, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{Shards: 3, Replicas: 2})
+
+	template := stub.body(t, "PUT /_index_template/csp-reports-template")
+	if _, ok := template["data_stream"]; ok {
+		t.Error("Expected no data_stream in daily index template")
+	}
+	if patterns := template["index_patterns"].([]interface{}); patterns[0] != "csp-reports-*" {
+		t.Errorf("Expected daily index pattern, got %v", patterns)
+	}
+	if composed := template["composed_of"].([]interface{}); len(composed) != 2 {
+		t.Errorf("Expected settings and mappings component templates, got %v", composed)
+	}
+
+	settings := componentTemplate(t, stub, "csp-reports-settings")["settings"].(map[string]interface{})
+	if settings["number_of_shards"] != float64(3) || settings["number_of_replicas"] != float64(2) {
+		t.Errorf("Expected configured shards and replicas, got %v", settings)
+	}
+	if _, ok := settings["index.lifecycle.name"]; ok {
+		t.Error("Expected no lifecycle setting when ILM is disabled")
+	}
+
+	if err := store.StoreBatch(testReports(t, 1)); err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	lines := bulkLines(t, stub)
+	index, ok := lines[0]["index"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected index action, got %v", lines[0])
+	}
+	if name := index["_index"].(string); !strings.HasPrefix(name, "csp-reports-") {
+		t.Errorf("Expected daily index name, got %s", name)
+	}
+}
+
+func TestElasticsearchStorage_DataStreamWithILM(t *testing.T) {
+	store, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{
+		DataStream:      true,
+		Shards:          1,
+		RefreshInterval: "5s",
+		ILM: config.ILMConfig{
+			Enabled:         true,
+			RolloverMaxSize: "10gb",
+			RolloverMaxAge:  "12h",
+			WarmAfter:       "none",
+			DeleteAfter:     "90d",
+		},
+	})
+
+	policy := stub.body(t, "PUT /_ilm/policy/csp-reports-policy")
+	phases := policy["policy"].(map[string]interface{})["phases"].(map[string]interface{})
+
+	rollover := phases["hot"].(map[string]interface{})["actions"].(map[string]interface{})["rollover"].(map[string]interface{})
+	if rollover["max_primary_shard_size"] != "10gb" || rollover["max_age"] != "12h" {
+		t.Errorf("Unexpected rollover action: %v", rollover)
+	}
+	if _, ok := phases["warm"]; ok {
+		t.Error("Expected warm phase to be disabled")
+	}
+	if phases["delete"].(map[string]interface{})["min_age"] != "90d" {
+		t.Errorf("Unexpected delete phase: %v", phases["delete"])
+	}
+
+	template := stub.body(t, "PUT /_index_template/csp-reports-template")
+	if _, ok := template["data_stream"]; !ok {
+		t.Error("Expected data_stream in template")
+	}
+	settings := componentTemplate(t, stub, "csp-reports-settings")["settings"].(map[string]interface{})
+	if settings["index.lifecycle.name"] != "csp-reports-policy" {
+		t.Errorf("Expected lifecycle policy setting, got %v", settings)
+	}
+	if settings["refresh_interval"] != "5s" {
+		t.Errorf("Expected configured refresh interval, got %v", settings["refresh_interval"])
+	}
+
+	if err := store.StoreBatch(testReports(t, 1)); err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	lines := bulkLines(t, stub)
+	create, ok := lines[0]["create"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected create action for data stream, got %v", lines[0])
+	}
+	if create["_index"] != "csp-reports" {
+		t.Errorf("Expected data stream name, got %v", create["_index"])
+	}
+	if _, ok := lines[1]["@timestamp"]; !ok {
+		t.Error("Expected @timestamp on data stream document")
+	}
+	if _, ok := lines[1]["parsed_report"]; !ok {
+		t.Error("Expected report fields on data stream document")
+	}
+}
+
+func TestElasticsearchStorage_ProjectRouting(t *testing.T) {
+	tests := []struct {
+		name       string
+		dataStream bool
+		expected   string
+	}{
+		{"daily indices", false, "csp-reports-shop-"},
+		{"data stream", true, "csp-reports-shop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{DataStream: tt.dataStream})
+
+			reports := testReports(t, 2)
+			reports[0].Project = "shop"
+			if err := store.StoreBatch(reports); err != nil {
+				t.Fatalf("StoreBatch failed: %v", err)
+			}
+
+			lines := bulkLines(t, stub)
+			var projectIndex, defaultIndex string
+			for _, action := range lines[0] {
+				projectIndex = action.(map[string]interface{})["_index"].(string)
+			}
+			for _, action := range lines[2] {
+				defaultIndex = action.(map[string]interface{})["_index"].(string)
+			}
+
+			if !strings.HasPrefix(projectIndex, tt.expected) || (tt.dataStream && projectIndex != tt.expected) {
+				t.Errorf("Expected project index %s, got %s", tt.expected, projectIndex)
+			}
+			if strings.HasPrefix(defaultIndex, "csp-reports-shop") {
+				t.Errorf("Expected report without project in the default index, got %s", defaultIndex)
+			}
+
+			template := stub.body(t, "PUT /_index_template/csp-reports-template")
+			patterns := template["index_patterns"].([]interface{})
+			matched := false
+			for _, pattern := range patterns {
+				if pattern == "csp-reports-*" {
+					matched = true
+				}
+			}
+			if !matched {
+				t.Errorf("Expected template to cover project indices, got %v", patterns)
+			}
+		})
+	}
+}
+
+func TestElasticsearchStorage_CredentialsFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "api-key")
+	if err := os.WriteFile(keyFile, []byte("c2VjcmV0LWFwaS1rZXk=\n"), 0o600); err != nil {
+		t.Fatalf("Failed to write API key file: %v", err)
+	}
+
+	_, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{
+		APIKey:     "ignored-env-value",
+		APIKeyFile: keyFile,
+	})
+
+	if stub.auth != "APIKey c2VjcmV0LWFwaS1rZXk=" {
+		t.Errorf("Expected API key from file, got Authorization %q", stub.auth)
+	}
+}
+
+func TestElasticsearchStorage_PrivateCA(t *testing.T) {
+	stub := &elasticsearchStub{bodies: make(map[string]string)}
+	srv := httptest.NewTLSServer(stub)
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	cfg := config.ElasticsearchConfig{
+		Addresses:       []string{srv.URL},
+		IndexPrefix:     "csp-reports",
+		RefreshInterval: config.DefaultESRefreshInterval,
+		ServiceToken:    "AAEAAWVsYXN0aWM",
+	}
+
+	if _, err := NewElasticsearchClient(cfg); err == nil {
+		t.Fatal("Expected certificate verification to fail without the CA bundle")
+	}
+
+	cfg.CAFile = caFile
+	if _, err := NewElasticsearchClient(cfg); err != nil {
+		t.Fatalf("Expected connection with CA bundle to succeed: %v", err)
+	}
+	if stub.auth != "Bearer AAEAAWVsYXN0aWM" {
+		t.Errorf("Expected service token auth, got %q", stub.auth)
+	}
+}
+
+func TestNewElasticsearchConfig(t *testing.T) {
+	esCfg, err := newElasticsearchConfig(config.ElasticsearchConfig{
+		Addresses: []string{"http://localhost:9200"},
+		CloudID:   "deployment:dXMtZWFzdC0xLmF3cy5mb3VuZC5pbyRjZWM2ZjI2MWE3NGJmMjRjZTMzYmI4ODExYjg0Mjk0ZiQ=",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if esCfg.Addresses != nil || esCfg.CloudID == "" {
+		t.Errorf("Expected Cloud ID to replace addresses, got %+v", esCfg)
+	}
+
+	if _, err := newElasticsearchConfig(config.ElasticsearchConfig{PasswordFile: "/nonexistent/password"}); err == nil {
+		t.Error("Expected error for missing password file")
+	}
+}
+
+func TestElasticsearchStorage_TemplateVersioning(t *testing.T) {
+	t.Run("Fresh install does not migrate", func(t *testing.T) {
+		_, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{})
+
+		template := stub.body(t, "PUT /_index_template/csp-reports-template")
+		meta := template["_meta"].(map[string]interface{})
+		if meta["version"] != float64(esTemplateVersion) {
+			t.Errorf("Expected _meta.version %d, got %v", esTemplateVersion, meta["version"])
+		}
+		if stub.putMappings() > 0 {
+			t.Error("Expected no mapping migration on fresh install")
+		}
+	})
+
+	t.Run("Older template migrates existing indices", func(t *testing.T) {
+		stub := &elasticsearchStub{
+			bodies:           make(map[string]string),
+			installedVersion: esTemplateVersion - 1,
+			mappings:         `{"csp-reports-2024.01.01": {"mappings": {"properties": {"parsed_report": {"properties": {"document_uri": {"type": "keyword"}}}}}}}`,
+		}
+		if _, err := newElasticsearchTestStorageWithStub(t, stub, config.ElasticsearchConfig{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		mapping := stub.body(t, "PUT /csp-reports-2024.01.01/_mapping")
+		parsed := mapping["properties"].(map[string]interface{})["parsed_report"].(map[string]interface{})["properties"].(map[string]interface{})
+		for _, field := range []string{"sha256", "errors"} {
+			if _, ok := parsed[field]; !ok {
+				t.Errorf("Expected migrated mapping to contain parsed_report.%s", field)
+			}
+		}
+		if _, ok := parsed["document_uri"]; ok {
+			t.Error("Expected already mapped parsed_report.document_uri to be left out")
+		}
+	})
+
+	t.Run("Unversioned template migrates dynamically mapped indices", func(t *testing.T) {
+		stub := &elasticsearchStub{
+			bodies:         make(map[string]string),
+			legacyTemplate: true,
+			mappings: `{
+				"csp-reports-2024.01.01": {"mappings": {"properties": {
+					"id": {"type": "keyword"},
+					"raw_report": {"properties": {"csp-report": {"properties": {"document-uri": {"type": "text"}}}}},
+					"parsed_report": {"properties": {"sha256": {"type": "text", "fields": {"keyword": {"type": "keyword"}}}}}
+				}}},
+				"csp-reports-2024.01.02": {"mappings": {"properties": {"id": {"type": "keyword"}}}}
+			}`,
+		}
+		if _, err := newElasticsearchTestStorageWithStub(t, stub, config.ElasticsearchConfig{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		mapping := stub.body(t, "PUT /csp-reports-2024.01.01/_mapping")
+		properties := mapping["properties"].(map[string]interface{})
+		if _, ok := properties["raw_report"]; ok {
+			t.Error("Expected dynamically mapped raw_report to be left out")
+		}
+		if _, ok := properties["fingerprint"]; !ok {
+			t.Error("Expected migrated mapping to contain fingerprint")
+		}
+		parsed := properties["parsed_report"].(map[string]interface{})["properties"].(map[string]interface{})
+		if _, ok := parsed["sha256"]; ok {
+			t.Error("Expected dynamically mapped parsed_report.sha256 to be left out")
+		}
+
+		other := stub.body(t, "PUT /csp-reports-2024.01.02/_mapping")
+		if _, ok := other["properties"].(map[string]interface{})["raw_report"]; !ok {
+			t.Error("Expected raw_report to be mapped on indices without it")
+		}
+
+		rollups := stub.body(t, "PUT /csp-reports-rollups-*/_mapping")
+		dimensions := rollups["properties"].(map[string]interface{})["dimensions"].(map[string]interface{})["properties"].(map[string]interface{})
+		if _, ok := dimensions["document_route"]; !ok {
+			t.Error("Expected rollup indices to get the document_route dimension")
+		}
+	})
+
+	t.Run("Same version does not migrate", func(t *testing.T) {
+		stub := &elasticsearchStub{
+			bodies:           make(map[string]string),
+			installedVersion: esTemplateVersion,
+			mappings:         `{"csp-reports-2024.01.01": {"mappings": {"properties": {}}}}`,
+		}
+		if _, err := newElasticsearchTestStorageWithStub(t, stub, config.ElasticsearchConfig{}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if stub.putMappings() > 0 {
+			t.Error("Expected no mapping migration for the current version")
+		}
+	})
+
+	t.Run("Newer template refuses to downgrade", func(t *testing.T) {
+		stub := &elasticsearchStub{bodies: make(map[string]string), installedVersion: esTemplateVersion + 1}
+		_, err := newElasticsearchTestStorageWithStub(t, stub, config.ElasticsearchConfig{})
+		if err == nil || !strings.Contains(err.Error(), "refusing to downgrade") {
+			t.Fatalf("Expected downgrade refusal, got %v", err)
+		}
+		if _, ok := stub.bodies["PUT /_index_template/csp-reports-template"]; ok {
+			t.Error("Expected newer template to be left untouched")
+		}
+	})
+}
+
+func TestReportMappings(t *testing.T) {
+	mappings := reportMappings()
+	if mappings["dynamic"] != false {
+		t.Error("Expected dynamic mapping to be disabled")
+	}
+
+	properties := mappings["properties"].(map[string]interface{})
+	raw := properties["raw_report"].(map[string]interface{})
+	if raw["enabled"] != false {
+		t.Error("Expected raw_report to be stored but not indexed")
+	}
+}
+
+func componentTemplate(t *testing.T, stub *elasticsearchStub, name string) map[string]interface{} {
+	t.Helper()
+	return stub.body(t, "PUT /_component_template/"+name)["template"].(map[string]interface{})
+}
+
+func TestElasticsearchStorage_StoreRollups(t *testing.T) {
+	store, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{})
+
+	template := stub.body(t, "PUT /_index_template/csp-reports-rollups-template")
+	if patterns := template["index_patterns"].([]interface{}); patterns[0] != "csp-reports-rollups-*" {
+		t.Errorf("Expected rollup index pattern, got %v", patterns)
+	}
+
+	rollups := []*models.Rollup{{
+		ID:          "abc",
+		BucketStart: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC),
+		Interval:    "1m",
+		Dimensions:  map[string]string{"directive": "script-src"},
+		Count:       7,
+	}}
+	if err := store.StoreRollups(rollups); err != nil {
+		t.Fatalf("StoreRollups failed: %v", err)
+	}
+
+	lines := bulkLines(t, stub)
+	index := lines[0]["index"].(map[string]interface{})
+	if index["_index"] != "csp-reports-rollups-2024.03" || index["_id"] != "abc" {
+		t.Errorf("Expected monthly rollup index with rollup ID, got %v", index)
+	}
+	if lines[1]["count"] != float64(7) {
+		t.Errorf("Expected count 7, got %v", lines[1]["count"])
+	}
+}
+
+func TestElasticsearchStorage_QueryReports(t *testing.T) {
+	store, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{})
+	stub.searchResponse = `{"hits": {"hits": [
+		{"_source": {"id": "b", "fingerprint": "f1", "parsed_report": {"blocked_uri": "https://evil.com/a.js"}}, "sort": [1704110400000, "b"]},
+		{"_source": {"id": "a", "fingerprint": "f1", "parsed_report": {"blocked_uri": "https://evil.com/b.js"}}, "sort": [1704110300000, "a"]}
+	]}}`
+
+	page, err := store.QueryReports(context.Background(), ReportQuery{
+		From:         time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Directive:    "Script-Src",
+		BlockedURI:   "https://evil.com/",
+		DocumentHost: "Example.com",
+		Fingerprint:  "f1",
+		Disposition:  "report",
+		Size:         2,
+	})
+	if err != nil {
+		t.Fatalf("QueryReports failed: %v", err)
+	}
+	if len(page.Reports) != 2 || page.Reports[0].ID != "b" {
+		t.Fatalf("Expected 2 reports newest first, got %+v", page.Reports)
+	}
+	if page.Next == "" {
+		t.Fatal("Expected a cursor for a full page")
+	}
+
+	search := stub.body(t, "POST /csp-reports-*,-csp-reports-rollups-*/_search")
+	filters := search["query"].(map[string]interface{})["bool"].(map[string]interface{})["filter"].([]interface{})
+	if len(filters) != 6 {
+		t.Errorf("Expected 6 filters, got %d: %v", len(filters), filters)
+	}
+	encoded, _ := json.Marshal(filters)
+	for _, expected := range []string{`"document_host":"example.com"`, `"disposition":"report"`, `"parsed_report.blocked_uri":"https://evil.com/"`, `"parsed_report.effective_directive":"script-src"`, `"gte":"2024-01-01T00:00:00Z"`} {
+		if !strings.Contains(string(encoded), expected) {
+			t.Errorf("Expected filters to contain %s, got %s", expected, encoded)
+		}
+	}
+
+	stub.searchResponse = `{"hits": {"hits": []}}`
+	page, err = store.QueryReports(context.Background(), ReportQuery{Size: 2, Cursor: page.Next})
+	if err != nil {
+		t.Fatalf("QueryReports with cursor failed: %v", err)
+	}
+	if page.Next != "" || len(page.Reports) != 0 {
+		t.Errorf("Expected empty last page, got %+v", page)
+	}
+
+	search = stub.body(t, "POST /csp-reports-*,-csp-reports-rollups-*/_search")
+	after := search["search_after"].([]interface{})
+	if len(after) != 2 || after[0] != float64(1704110300000) || after[1] != "a" {
+		t.Errorf("Expected search_after from the last hit, got %v", after)
+	}
+
+	if _, err := store.QueryReports(context.Background(), ReportQuery{Cursor: "not a cursor"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+func TestElasticsearchStorage_QueryReportsMatchesDirectiveFamily(t *testing.T) {
+	store, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{})
+	stub.searchResponse = `{"hits": {"hits": [
+		{"_source": {"id": "a", "directive": "script-src", "parsed_report": {"effective_directive": "script-src-elem"}}, "sort": [1704110400000, "a"]}
+	]}}`
+
+	tests := []struct {
+		directive string
+		expected  string
+	}{
+		{"script-src", `{"term":{"directive":"script-src"}}`},
+		{"Script-Src-Elem", `{"term":{"directive":"script-src"}}`},
+	}
+
+	for _, tt := range tests {
+		page, err := store.QueryReports(context.Background(), ReportQuery{Directive: tt.directive, Size: 10})
+		if err != nil {
+			t.Fatalf("QueryReports failed: %v", err)
+		}
+		if len(page.Reports) != 1 || page.Reports[0].ParsedReport.EffectiveDirective != "script-src-elem" {
+			t.Errorf("Expected the script-src-elem report, got %+v", page.Reports)
+		}
+
+		search := stub.body(t, "POST /csp-reports-*,-csp-reports-rollups-*/_search")
+		encoded, _ := json.Marshal(search["query"])
+		for _, expected := range []string{tt.expected, `"parsed_report.violated_directive"`} {
+			if !strings.Contains(string(encoded), expected) {
+				t.Errorf("Expected %s filter to contain %s, got %s", tt.directive, expected, encoded)
+			}
+		}
+	}
+}
+
+func TestElasticsearchStorage_SummarizeReports(t *testing.T) {
+	store, stub := newElasticsearchTestStorage(t, config.ElasticsearchConfig{})
+	stub.searchResponses = []string{
+		// Current window, with deduplicated and sampled reports weighted
+		`{"hits": {"total": {"value": 3}}, "aggregations": {
+			"total": {"value": 30.0},
+			"blocked_origins": {"buckets": [{"key": "https://new.com", "doc_count": 1, "weight": {"value": 20.0}}, {"key": "https://old.com", "doc_count": 2, "weight": {"value": 10.0}}]},
+			"directives": {"buckets": [{"key": "script-src", "doc_count": 3, "weight": {"value": 30.0}}]},
+			"pages": {"buckets": []},
+			"browsers": {"buckets": [{"key": "chrome", "doc_count": 3, "weight": {"value": 30.0}}]}
+		}}`,
+		// Baseline window
+		`{"hits": {"total": {"value": 12}}, "aggregations": {
+			"total": {"value": 12.0},
+			"blocked_origins": {"buckets": [{"key": "https://old.com", "doc_count": 8, "weight": {"value": 8.0}}, {"key": "https://gone.com", "doc_count": 4, "weight": {"value": 4.0}}]},
+			"directives": {"buckets": [{"key": "script-src", "doc_count": 12, "weight": {"value": 12.0}}]},
+			"pages": {"buckets": []},
+			"browsers": {"buckets": [{"key": "chrome", "doc_count": 12, "weight": {"value": 12.0}}]}
+		}}`,
+		// Current top values present in the baseline
+		`{"hits": {"total": {"value": 12}}, "aggregations": {
+			"blocked_origins": {"buckets": [{"key": "https://old.com", "doc_count": 8}]},
+			"directives": {"buckets": [{"key": "script-src", "doc_count": 12}]},
+			"browsers": {"buckets": [{"key": "chrome", "doc_count": 12}]}
+		}}`,
+		// Baseline top values present in the current window
+		`{"hits": {"total": {"value": 30}}, "aggregations": {
+			"blocked_origins": {"buckets": [{"key": "https://old.com", "doc_count": 10}]},
+			"directives": {"buckets": [{"key": "script-src", "doc_count": 30}]},
+			"browsers": {"buckets": [{"key": "chrome", "doc_count": 30}]}
+		}}`,
+	}
+
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	summary, err := store.SummarizeReports(context.Background(), SummaryQuery{
+		From:        now.Add(-time.Hour),
+		To:          now,
+		Size:        5,
+		CompareFrom: now.Add(-2 * time.Hour),
+		CompareTo:   now.Add(-time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("SummarizeReports failed: %v", err)
+	}
+
+	if summary.Total != 30 || len(summary.Top["blocked_origins"]) != 2 || summary.Top["directives"][0].Key != "script-src" {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+	if summary.Comparison == nil || summary.Comparison.Baseline.Total != 12 {
+		t.Fatalf("Expected comparison with baseline, got %+v", summary.Comparison)
+	}
+
+	newOrigins := summary.Comparison.New["blocked_origins"]
+	if len(newOrigins) != 1 || newOrigins[0].Key != "https://new.com" || newOrigins[0].Count != 20 {
+		t.Errorf("Expected https://new.com to be new, got %v", newOrigins)
+	}
+	goneOrigins := summary.Comparison.Gone["blocked_origins"]
+	if len(goneOrigins) != 1 || goneOrigins[0].Key != "https://gone.com" || goneOrigins[0].Count != 4 {
+		t.Errorf("Expected https://gone.com to be gone, got %v", goneOrigins)
+	}
+	if len(summary.Comparison.New["directives"]) != 0 {
+		t.Errorf("Expected no new directives, got %v", summary.Comparison.New["directives"])
+	}
+
+	if len(stub.searches) != 4 {
+		t.Fatalf("Expected 4 searches, got %d", len(stub.searches))
+	}
+	if !strings.Contains(stub.searches[2], `"include":["https://new.com","https://old.com"]`) {
+		t.Errorf("Expected baseline lookup of current top values, got %s", stub.searches[2])
+	}
+	for _, expected := range []string{`"order":{"weight":"desc"}`, `doc['occurrences']`, `doc['sample_rate']`, `"field":"document_route"`} {
+		if !strings.Contains(stub.searches[0], expected) {
+			t.Errorf("Expected the summary search to contain %s, got %s", expected, stub.searches[0])
+		}
+	}
+}
+
+func bulkLines(t *testing.T, stub *elasticsearchStub) []map[string]interface{} {
+	t.Helper()
+
+	stub.mu.Lock()
+	raw := stub.bodies["POST /_bulk"]
+	stub.mu.Unlock()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var line map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("Invalid bulk line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -1,8 +1,112 @@
 package storage
 
-import "universal-csp-report/internal/models"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"universal-csp-report/internal/models"
+)
 
 type Storage interface {
 	StoreBatch(reports []*models.CSPReport) error
 	Close() error
 }
+
+// RollupStorage is implemented by backends that can persist time-bucketed
+// aggregates alongside raw reports.
+type RollupStorage interface {
+	StoreRollups(rollups []*models.Rollup) error
+}
+
+// ErrInvalidCursor is returned by QueryReports for a malformed cursor.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PartialBatchError is returned by StoreBatch when only some of the reports
+// could not be stored. Err is the first failure.
+type PartialBatchError struct {
+	Failed int
+	Total  int
+	Err    error
+}
+
+func (e *PartialBatchError) Error() string {
+	return fmt.Sprintf("failed to store %d of %d reports: %v", e.Failed, e.Total, e.Err)
+}
+
+func (e *PartialBatchError) Unwrap() error {
+	return e.Err
+}
+
+// ReportQuery filters stored reports. Empty fields are not filtered on.
+// BlockedURI matches as a prefix, the other fields exactly.
+type ReportQuery struct {
+	From          time.Time
+	To            time.Time
+	Project       string
+	Directive     string
+	BlockedURI    string
+	DocumentHost  string
+	DocumentRoute string
+	Browser       string
+	Fingerprint   string
+	Disposition   string
+	Size          int
+	Cursor        string
+}
+
+// ReportPage is one page of reports, newest first. Next is the cursor for the
+// following page and is empty on the last page.
+type ReportPage struct {
+	Reports []*models.CSPReport `json:"reports"`
+	Next    string              `json:"next,omitempty"`
+}
+
+// QueryStorage is implemented by backends that can search stored reports.
+type QueryStorage interface {
+	QueryReports(ctx context.Context, query ReportQuery) (*ReportPage, error)
+}
+
+// SummaryQuery selects the window to summarize. When CompareFrom and
+// CompareTo are set, the summary is compared against that baseline window.
+type SummaryQuery struct {
+	From        time.Time
+	To          time.Time
+	Project     string
+	Size        int
+	CompareFrom time.Time
+	CompareTo   time.Time
+}
+
+// SummaryBucket is a single value of a summary dimension and its count.
+type SummaryBucket struct {
+	Key   string `json:"key"`
+	Count int64  `json:"count"`
+}
+
+// Summary holds the top values per dimension within a time window, keyed by
+// dimension name (blocked_origins, blocked_domains, blocked_categories,
+// directives, pages, routes, browsers, dispositions).
+type Summary struct {
+	From       time.Time                  `json:"from"`
+	To         time.Time                  `json:"to"`
+	Total      int64                      `json:"total"`
+	Top        map[string][]SummaryBucket `json:"top"`
+	Comparison *SummaryComparison         `json:"comparison,omitempty"`
+}
+
+// SummaryComparison lists, per dimension, the top values of the window that
+// never occurred in the baseline (New) and the top values of the baseline
+// that no longer occur (Gone).
+type SummaryComparison struct {
+	Baseline *Summary                   `json:"baseline"`
+	New      map[string][]SummaryBucket `json:"new"`
+	Gone     map[string][]SummaryBucket `json:"gone"`
+}
+
+// SummaryStorage is implemented by backends that can aggregate stored
+// reports.
+type SummaryStorage interface {
+	SummarizeReports(ctx context.Context, query SummaryQuery) (*Summary, error)
+}
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+// WebhookStorage forwards report batches to an arbitrary HTTP endpoint such
+// as a SIEM collector, a Splunk HEC-style receiver or the Loki push API.
+type WebhookStorage struct {
+	client *http.Client
+	config config.WebhookConfig
+}
+
+func NewWebhookClient(cfg config.WebhookConfig) (Storage, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+
+	switch cfg.Format {
+	case config.WebhookFormatNDJSON, config.WebhookFormatJSONArray, config.WebhookFormatPerReport:
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", cfg.Format)
+	}
+
+	if cfg.Method == "" {
+		cfg.Method = http.MethodPost
+	}
+
+	tlsConfig, err := newTLSConfig(cfg.CAFile, cfg.CertFile, cfg.KeyFile, cfg.InsecureSkipVerify)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &WebhookStorage{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   time.Duration(cfg.Timeout) * time.Second,
+		},
+		config: cfg,
+	}, nil
+}
+
+func (wh *WebhookStorage) StoreBatch(reports []*models.CSPReport) error {
+	if len(reports) == 0 {
+		return nil
+	}
+
+	chunkSize := wh.config.BatchSize
+	if wh.config.Format == config.WebhookFormatPerReport {
+		chunkSize = 1
+	}
+	if chunkSize <= 0 {
+		chunkSize = len(reports)
+	}
+
+	// A failed chunk does not stop the others from being delivered
+	failed := 0
+	var firstErr error
+	for start := 0; start < len(reports); start += chunkSize {
+		end := start + chunkSize
+		if end > len(reports) {
+			end = len(reports)
+		}
+
+		body, err := wh.encode(reports[start:end])
+		if err == nil {
+			err = wh.send(body)
+		}
+		if err != nil {
+			failed += end - start
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	if failed > 0 {
+		return &PartialBatchError{Failed: failed, Total: len(reports), Err: firstErr}
+	}
+	return nil
+}
+
+func (wh *WebhookStorage) Close() error {
+	wh.client.CloseIdleConnections()
+	return nil
+}
+
+func (wh *WebhookStorage) encode(reports []*models.CSPReport) ([]byte, error) {
+	switch wh.config.Format {
+	case config.WebhookFormatNDJSON:
+		var buf bytes.Buffer
+		for _, report := range reports {
+			docBytes, err := json.Marshal(report)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal document: %w", err)
+			}
+			buf.Write(docBytes)
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), nil
+
+	case config.WebhookFormatPerReport:
+		docBytes, err := json.Marshal(reports[0])
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal document: %w", err)
+		}
+		return docBytes, nil
+
+	default:
+		docBytes, err := json.Marshal(reports)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal documents: %w", err)
+		}
+		return docBytes, nil
+	}
+}
+
+func (wh *WebhookStorage) contentType() string {
+	if wh.config.Format == config.WebhookFormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "application/json"
+}
+
+// send delivers a single request body, retrying transport errors, 429 and 5xx
+// responses with exponential backoff.
+func (wh *WebhookStorage) send(body []byte) error {
+	backoff := time.Duration(wh.config.RetryBackoff) * time.Millisecond
+
+	var lastErr error
+	for attempt := 0; attempt <= wh.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		retryable, err := wh.do(body)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if !retryable {
+			break
+		}
+	}
+
+	return fmt.Errorf("webhook delivery failed: %w", lastErr)
+}
+
+func (wh *WebhookStorage) do(body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(context.Background(), wh.config.Method, wh.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", wh.contentType())
+	for name, value := range wh.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	switch {
+	case wh.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+wh.config.BearerToken)
+	case wh.config.Username != "":
+		req.SetBasicAuth(wh.config.Username, wh.config.Password)
+	}
+
+	res, err := wh.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode >= 200 && res.StatusCode < 300 {
+		io.Copy(io.Discard, res.Body)
+		return false, nil
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+	err = fmt.Errorf("webhook error: %s: %s", res.Status, strings.TrimSpace(string(msg)))
+
+	retryable := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+	return retryable, err
+}
@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/models"
+)
+
+type webhookStub struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+	statuses []int
+}
+
+func (s *webhookStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = append(s.requests, r)
+	s.bodies = append(s.bodies, string(body))
+
+	status := http.StatusNoContent
+	if len(s.statuses) > 0 {
+		status = s.statuses[0]
+		s.statuses = s.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newWebhookTestStorage(t *testing.T, stub *webhookStub, cfg config.WebhookConfig) Storage {
+	t.Helper()
+
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+
+	cfg.URL = srv.URL
+	cfg.RetryBackoff = 1
+	store, err := NewWebhookClient(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create webhook storage: %v", err)
+	}
+	return store
+}
+
+func testReports(t *testing.T, count int) []*models.CSPReport {
+	t.Helper()
+
+	var reports []*models.CSPReport
+	for i := 0; i < count; i++ {
+		parsed, err := models.ParseCSPReports([]byte(`{"csp-report": {"document-uri": "https://example.com/", "violated-directive": "script-src", "blocked-uri": "https://evil.com/x.js"}}`), "Mozilla/5.0 Chrome/120.0", "10.0.0.1")
+		if err != nil {
+			t.Fatalf("Failed to parse report: %v", err)
+		}
+		reports = append(reports, parsed...)
+	}
+	return reports
+}
+
+func TestWebhookStorage_Formats(t *testing.T) {
+	tests := []struct {
+		name             string
+		format           string
+		batchSize        int
+		expectedRequests int
+		expectedType     string
+		validate         func(t *testing.T, body string)
+	}{
+		{
+			name:             "NDJSON",
+			format:           config.WebhookFormatNDJSON,
+			expectedRequests: 1,
+			expectedType:     "application/x-ndjson",
+			validate: func(t *testing.T, body string) {
+				lines := strings.Split(strings.TrimSpace(body), "\n")
+				if len(lines) != 3 {
+					t.Errorf("Expected 3 NDJSON lines, got %d", len(lines))
+				}
+			},
+		},
+		{
+			name:             "JSON array with batching",
+			format:           config.WebhookFormatJSONArray,
+			batchSize:        2,
+			expectedRequests: 2,
+			expectedType:     "application/json",
+			validate: func(t *testing.T, body string) {
+				var docs []map[string]interface{}
+				if err := json.Unmarshal([]byte(body), &docs); err != nil {
+					t.Errorf("Expected JSON array body: %v", err)
+				}
+			},
+		},
+		{
+			name:             "Per report",
+			format:           config.WebhookFormatPerReport,
+			batchSize:        10,
+			expectedRequests: 3,
+			expectedType:     "application/json",
+			validate: func(t *testing.T, body string) {
+				var doc map[string]interface{}
+				if err := json.Unmarshal([]byte(body), &doc); err != nil {
+					t.Errorf("Expected single JSON object body: %v", err)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &webhookStub{}
+			store := newWebhookTestStorage(t, stub, config.WebhookConfig{
+				Format:    tt.format,
+				BatchSize: tt.batchSize,
+			})
+
+			if err := store.StoreBatch(testReports(t, 3)); err != nil {
+				t.Fatalf("StoreBatch failed: %v", err)
+			}
+
+			if len(stub.requests) != tt.expectedRequests {
+				t.Fatalf("Expected %d requests, got %d", tt.expectedRequests, len(stub.requests))
+			}
+			if got := stub.requests[0].Header.Get("Content-Type"); got != tt.expectedType {
+				t.Errorf("Expected Content-Type %s, got %s", tt.expectedType, got)
+			}
+			tt.validate(t, stub.bodies[0])
+		})
+	}
+}
+
+func TestWebhookStorage_HeadersAndAuth(t *testing.T) {
+	stub := &webhookStub{}
+	store := newWebhookTestStorage(t, stub, config.WebhookConfig{
+		Method:      http.MethodPut,
+		Format:      config.WebhookFormatNDJSON,
+		Headers:     map[string]string{"X-Scope-OrgID": "security"},
+		BearerToken: "token123",
+	})
+
+	if err := store.StoreBatch(testReports(t, 1)); err != nil {
+		t.Fatalf("StoreBatch failed: %v", err)
+	}
+
+	req := stub.requests[0]
+	if req.Method != http.MethodPut {
+		t.Errorf("Expected PUT, got %s", req.Method)
+	}
+	if got := req.Header.Get("X-Scope-OrgID"); got != "security" {
+		t.Errorf("Expected custom header, got %q", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer token123" {
+		t.Errorf("Expected bearer auth, got %q", got)
+	}
+}
+
+func TestWebhookStorage_Retries(t *testing.T) {
+	tests := []struct {
+		name             string
+		statuses         []int
+		maxRetries       int
+		expectError      bool
+		expectedRequests int
+	}{
+		{
+			name:             "Recovers after server errors",
+			statuses:         []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			maxRetries:       3,
+			expectedRequests: 3,
+		},
+		{
+			name:             "Gives up after max retries",
+			statuses:         []int{500, 500, 500},
+			maxRetries:       2,
+			expectError:      true,
+			expectedRequests: 3,
+		},
+		{
+			name:             "Does not retry client errors",
+			statuses:         []int{http.StatusBadRequest},
+			maxRetries:       3,
+			expectError:      true,
+			expectedRequests: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &webhookStub{statuses: tt.statuses}
+			store := newWebhookTestStorage(t, stub, config.WebhookConfig{
+				Format:     config.WebhookFormatNDJSON,
+				MaxRetries: tt.maxRetries,
+			})
+
+			err := store.StoreBatch(testReports(t, 1))
+			if tt.expectError && err == nil {
+				t.Error("Expected error, got nil")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if len(stub.requests) != tt.expectedRequests {
+				t.Errorf("Expected %d requests, got %d", tt.expectedRequests, len(stub.requests))
+			}
+		})
+	}
+}
+
+func TestWebhookStorage_ContinuesAfterFailedChunk(t *testing.T) {
+	stub := &webhookStub{statuses: []int{http.StatusNoContent, http.StatusBadRequest, http.StatusNoContent}}
+	store := newWebhookTestStorage(t, stub, config.WebhookConfig{
+		Format:     config.WebhookFormatPerReport,
+		MaxRetries: 3,
+	})
+
+	err := store.StoreBatch(testReports(t, 3))
+
+	var partial *PartialBatchError
+	if !errors.As(err, &partial) || partial.Failed != 1 || partial.Total != 3 {
+		t.Fatalf("Expected 1 of 3 reports to fail, got %v", err)
+	}
+	if len(stub.requests) != 3 {
+		t.Errorf("Expected the remaining report to be sent after the failure, got %d requests", len(stub.requests))
+	}
+}
+
+func TestWebhookStorage_TLS(t *testing.T) {
+	stub := &webhookStub{}
+	srv := httptest.NewTLSServer(stub)
+	defer srv.Close()
+
+	store, err := NewWebhookClient(config.WebhookConfig{
+		URL:                srv.URL,
+		Format:             config.WebhookFormatJSONArray,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create webhook storage: %v", err)
+	}
+
+	if err := store.StoreBatch(testReports(t, 1)); err != nil {
+		t.Fatalf("StoreBatch over TLS failed: %v", err)
+	}
+}
+
+func TestNewWebhookClient_InvalidConfig(t *testing.T) {
+	if _, err := NewWebhookClient(config.WebhookConfig{Format: config.WebhookFormatNDJSON}); err == nil {
+		t.Error("Expected error for missing URL")
+	}
+	if _, err := NewWebhookClient(config.WebhookConfig{URL: "http://localhost", Format: "xml"}); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+	if _, err := NewWebhookClient(config.WebhookConfig{URL: "http://localhost", Format: config.WebhookFormatNDJSON, CAFile: "/nonexistent/ca.pem"}); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+}
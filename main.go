@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"math"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
 	"universal-csp-report/internal/config"
+	"universal-csp-report/internal/policy"
 	"universal-csp-report/internal/processor"
 	"universal-csp-report/internal/server"
 	"universal-csp-report/internal/storage"
@@ -25,6 +28,10 @@ var (
 
 func main() {
 	var showVersion = flag.Bool("version", false, "Show version information")
+	var recommend = flag.Bool("recommend", false, "Print policy recommendations from stored reports and exit")
+	var recommendHost = flag.String("host", "", "Document host to recommend a policy for (default: all hosts)")
+	var recommendSince = flag.Duration("since", 7*24*time.Hour, "Window of stored reports to recommend from")
+	var minEvidence = flag.Int64("min-evidence", 1, "Minimum reports supporting a recommended source")
 	flag.Parse()
 
 	if *showVersion {
@@ -45,15 +52,27 @@ func main() {
 	}
 	logger.SetFormatter(&logrus.JSONFormatter{})
 
-	esClient, err := storage.NewElasticsearchClient(cfg.Elasticsearch)
+	if err := cfg.LoadProjects(); err != nil {
+		logger.Fatalf("Failed to load projects: %v", err)
+	}
+
+	if *recommend {
+		if err := printRecommendations(cfg, *recommendHost, *recommendSince, *minEvidence); err != nil {
+			logger.Fatalf("Failed to recommend policies: %v", err)
+		}
+		return
+	}
+
+	store, err := newStorage(cfg)
 	if err != nil {
-		logger.Fatalf("Failed to create Elasticsearch client: %v", err)
+		logger.Fatalf("Failed to create storage backend: %v", err)
 	}
+	defer store.Close()
 
-	batchProcessor := processor.New(cfg.BatchProcessor, esClient, logger)
+	batchProcessor := processor.New(cfg.BatchProcessor, store, logger)
 	batchProcessor.Start()
 
-	httpServer := server.New(cfg.Server, batchProcessor, logger)
+	httpServer := server.New(cfg.Server, batchProcessor, store, logger)
 
 	go func() {
 		if err := httpServer.Start(); err != nil {
@@ -80,3 +99,58 @@ func main() {
 
 	logger.Info("Server exited")
 }
+
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case config.StorageBackendElasticsearch:
+		return storage.NewElasticsearchClient(cfg.Elasticsearch)
+	case config.StorageBackendClickHouse:
+		return storage.NewClickHouseClient(cfg.ClickHouse)
+	case config.StorageBackendWebhook:
+		return storage.NewWebhookClient(cfg.Webhook)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// newQueryStorage connects to the storage backend for reading stored reports,
+// without the schema setup and migrations newStorage runs.
+func newQueryStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.StorageBackend {
+	case config.StorageBackendElasticsearch:
+		return storage.NewElasticsearchReader(cfg.Elasticsearch)
+	default:
+		return nil, fmt.Errorf("storage backend %q does not support queries", cfg.StorageBackend)
+	}
+}
+
+// printRecommendations writes the policy recommendations for the stored
+// reports of the last window to stdout as JSON.
+func printRecommendations(cfg *config.Config, host string, window time.Duration, minEvidence int64) error {
+	store, err := newQueryStorage(cfg)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	reports, ok := store.(storage.QueryStorage)
+	if !ok {
+		return fmt.Errorf("storage backend does not support queries")
+	}
+
+	now := time.Now().UTC()
+	query := storage.ReportQuery{
+		From:         now.Add(-window),
+		To:           now,
+		DocumentHost: host,
+	}
+
+	recommender := policy.NewRecommender()
+	if _, err := recommender.Collect(context.Background(), reports, query, math.MaxInt); err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(recommender.Recommend(minEvidence))
+}